@@ -1,23 +1,36 @@
-// Package main is the entry point for the Drift portfolio simulation server.
+// Package main is the entry point for Drift: the portfolio simulation server
+// and the command-line tool that drives the same services without HTTP. With
+// no command it serves; "drift help" lists the commands.
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/gjcourt/drift/internal/adapters/cli"
 	httpAdapter "github.com/gjcourt/drift/internal/adapters/http"
 	"github.com/gjcourt/drift/internal/adapters/ingestion"
+	"github.com/gjcourt/drift/internal/adapters/storage/memory"
 	"github.com/gjcourt/drift/internal/adapters/storage/sqlite"
 	"github.com/gjcourt/drift/internal/app"
+	"github.com/gjcourt/drift/internal/ports/outbound"
 )
 
 func main() {
 	// Configuration from env with sensible defaults.
 	addr := envOr("DRIFT_ADDR", ":8080")
 	dbPath := envOr("DRIFT_DB", "drift.db")
+	maxRuns := envIntOr("DRIFT_MAX_CONCURRENT_RUNS", 1)
 
 	// Resolve template and static directories relative to this source file at build time.
 	// In production, override with DRIFT_TMPL_DIR and DRIFT_STATIC_DIR.
@@ -28,31 +41,137 @@ func main() {
 	tmplDir := envOr("DRIFT_TMPL_DIR", defaultTmplDir)
 	staticDir := envOr("DRIFT_STATIC_DIR", defaultStaticDir)
 
-	// Open SQLite store (implements all three repository interfaces).
-	store, err := sqlite.New(dbPath)
-	if err != nil {
-		slog.Error("open database", "err", err)
-		os.Exit(1)
+	// Open the SQLite store (implements all repository interfaces), or for
+	// headless commands an in-memory store that is gone when they exit.
+	args := os.Args[1:]
+	var store repositories = memory.New()
+	if !cli.Headless(args) {
+		db, err := sqlite.New(dbPath)
+		if err != nil {
+			slog.Error("open database", "err", err)
+			os.Exit(1)
+		}
+		store = db
 	}
 
 	// Wire services.
 	ingestionSvc := app.NewIngestionService(ingestion.Parser{}, store)
-	resultsSvc := app.NewResultsService(store, store)
-	simSvc := app.NewSimulationService(store, store, store)
+	resultsSvc := app.NewResultsService(store, store, ingestion.Parser{})
+	simSvc := app.NewSimulationService(store, store, store, store)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serve := func(ctx context.Context) error {
+		handler := httpAdapter.New(ingestionSvc, resultsSvc, simSvc, tmplDir, staticDir,
+			httpAdapter.WithExperimentConfigType(ingestion.ExperimentConfig{}))
+		runner := app.NewRunner(simSvc, app.WithConcurrency(maxRuns))
+		slog.Info("Drift starting", "addr", addr, "db", dbPath, "max_concurrent_runs", maxRuns)
+		return serveHTTP(ctx, addr, handler, runner)
+	}
+	// "drift run --wait" and "drift simulate" execute their one run in this
+	// process. A server sharing the database keeps executing the rest.
+	execute := func(ctx context.Context, runID string) error {
+		quiet := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		return app.NewRunner(simSvc, app.WithLogger(quiet)).ExecuteRun(ctx, runID)
+	}
+	c := cli.New(ingestionSvc, resultsSvc, simSvc, cli.WithServe(serve), cli.WithExecutor(execute))
+	code := c.Run(ctx, args)
+	stop()
+	os.Exit(code)
+}
+
+// repositories is every outbound repository port; both stores implement all
+// of them.
+type repositories interface {
+	outbound.AssetRepository
+	outbound.ExperimentRepository
+	outbound.SimulationRepository
+	outbound.PathRepository
+}
+
+// serveHTTP serves handler on addr and executes queued runs with runner until
+// ctx is done, then shuts both down.
+func serveHTTP(ctx context.Context, addr string, handler http.Handler, runner *app.Runner) error {
+	// The runner owns simulation execution; it outlives any single request.
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	runnerDone := make(chan struct{})
+	go func() {
+		defer close(runnerDone)
+		if err := runner.Start(ctx); err != nil {
+			slog.Error("runner", "err", err)
+			stop()
+		}
+	}()
+
+	// Request contexts are cancelled when shutdown begins so long-lived
+	// event streams end instead of holding Shutdown open.
+	reqCtx, cancelReqs := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return reqCtx },
+	}
+	srv.RegisterOnShutdown(cancelReqs)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
 
-	// Build HTTP handler.
-	handler := httpAdapter.New(ingestionSvc, resultsSvc, simSvc, tmplDir, staticDir)
+	var err error
+	select {
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		stop()
+	case <-ctx.Done():
+	}
 
-	slog.Info("Drift starting", "addr", addr, "db", dbPath)
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		slog.Error("server error", "err", err)
-		os.Exit(1)
+	// Stop accepting requests first so nothing new is queued, then give
+	// in-flight runs time to finish before interrupting them. Any run that
+	// still outlives the process is failed by the reaper on the next start.
+	slog.Info("Drift shutting down")
+	shutCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		slog.Error("server shutdown", "err", err)
 	}
+	select {
+	case <-runnerDone:
+	case <-shutCtx.Done():
+		slog.Warn("runs still in flight at shutdown; interrupting them")
+		runner.Interrupt()
+		select {
+		case <-runnerDone:
+		case <-time.After(5 * time.Second):
+			slog.Warn("runs did not stop; they will be marked interrupted on restart")
+		}
+	}
+	return err
 }
 
+// drainTimeout bounds how long shutdown waits for requests and runs to finish.
+const drainTimeout = 30 * time.Second
+
 func envOr(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return def
 }
+
+func envIntOr(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("ignoring non-integer env var", "key", key, "value", v)
+		return def
+	}
+	return n
+}
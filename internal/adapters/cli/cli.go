@@ -0,0 +1,259 @@
+// Package cli is the command-line driving adapter. It parses drift's
+// subcommands and calls the inbound services, printing text for people or,
+// with --json, JSON for scripts. Every command exits non-zero on failure.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK      = 0
+	ExitFailure = 1 // the command ran and failed, including runs that fail
+	ExitUsage   = 2 // the command line could not be parsed
+)
+
+const usage = `Usage: drift <command> [arguments]
+
+Commands:
+  serve                              start the web server (the default with no command)
+  ingest FILE...                     load CSV price files
+  experiment create --from FILE      create an experiment from a JSON experiment config ("-" reads stdin)
+  run EXP_ID [--wait]                queue a run; --wait executes it here and waits for it to finish
+  runs list [--experiment EXP_ID]    list runs, newest first
+  export RUN_ID [--format json|csv] [--paths]
+                                     write a run's results JSON, with stored paths when --paths
+                                     is set, or its stored paths as CSV
+  simulate --config FILE --prices DIR [--out FILE]
+                                     run an experiment config once over the CSV files in DIR
+                                     without a database; --out also writes the results JSON
+
+ingest, experiment, run, runs and simulate take --json to print JSON instead of text.
+Configuration comes from the same DRIFT_* environment variables as the server.
+`
+
+// CLI runs drift subcommands against the inbound services.
+type CLI struct {
+	ingest  inbound.DataIngestionService
+	results inbound.ResultsService
+	sim     inbound.SimulationService
+
+	stdout, stderr io.Writer
+	serve          func(context.Context) error
+	execute        func(ctx context.Context, runID string) error
+	pollInterval   time.Duration
+}
+
+// Option configures a CLI.
+type Option func(*CLI)
+
+// WithOutput sets where output and errors are written. The defaults are
+// os.Stdout and os.Stderr.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(c *CLI) { c.stdout, c.stderr = stdout, stderr }
+}
+
+// WithServe sets what "drift serve" runs; it should block until ctx is done.
+// Without it, serve is an error.
+func WithServe(serve func(ctx context.Context) error) Option {
+	return func(c *CLI) { c.serve = serve }
+}
+
+// WithExecutor sets how "drift run --wait" gets its run executed: execute is
+// started in the background and should execute runID, and no other run, if
+// it is still queued, returning once it has finished. Without it, --wait
+// relies on a server sharing the database to pick the run up.
+func WithExecutor(execute func(ctx context.Context, runID string) error) Option {
+	return func(c *CLI) { c.execute = execute }
+}
+
+// WithPollInterval sets how often "drift run --wait" checks on its run. The
+// default is 250ms.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *CLI) {
+		if d > 0 {
+			c.pollInterval = d
+		}
+	}
+}
+
+// New constructs a CLI over the given services.
+func New(ingest inbound.DataIngestionService, results inbound.ResultsService, sim inbound.SimulationService, opts ...Option) *CLI {
+	c := &CLI{
+		ingest:       ingest,
+		results:      results,
+		sim:          sim,
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+		pollInterval: 250 * time.Millisecond,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Run executes the command in args (os.Args[1:]) and returns the process
+// exit code.
+func (c *CLI) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "serve":
+		return c.runServe(ctx, rest)
+	case "ingest":
+		return c.runIngest(ctx, rest)
+	case "experiment":
+		if len(rest) == 0 || rest[0] != "create" {
+			return c.usageError("experiment: want the create subcommand")
+		}
+		return c.runExperimentCreate(ctx, rest[1:])
+	case "run":
+		return c.runRun(ctx, rest)
+	case "runs":
+		if len(rest) == 0 || rest[0] != "list" {
+			return c.usageError("runs: want the list subcommand")
+		}
+		return c.runRunsList(ctx, rest[1:])
+	case "export":
+		return c.runExport(ctx, rest)
+	case "simulate":
+		return c.runSimulate(ctx, rest)
+	case "help", "-h", "-help", "--help":
+		_, _ = io.WriteString(c.stdout, usage)
+		return ExitOK
+	}
+	return c.usageError(fmt.Sprintf("unknown command %q", cmd))
+}
+
+// Headless reports whether args name a command that keeps nothing: it should
+// run against services over an in-memory store, and never open the database.
+func Headless(args []string) bool {
+	return len(args) > 0 && args[0] == "simulate"
+}
+
+// usageError reports a malformed command line.
+func (c *CLI) usageError(msg string) int {
+	fmt.Fprintf(c.stderr, "drift: %s\n\n%s", msg, usage)
+	return ExitUsage
+}
+
+// flags returns a flag set for the named command that reports errors to
+// stderr rather than exiting.
+func (c *CLI) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("drift "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses args with fs, allowing flags after positional arguments as in
+// "drift run EXP_ID --wait", and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		if args[0] == "--" {
+			return append(pos, args[1:]...), nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// output prints the results of one command invocation in the chosen format.
+type output struct {
+	c    *CLI
+	json bool
+}
+
+// print writes v as JSON in JSON mode and calls text otherwise.
+func (o output) print(v any, text func(w io.Writer)) {
+	if o.json {
+		enc := json.NewEncoder(o.c.stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(v)
+		return
+	}
+	text(o.c.stdout)
+}
+
+// errorJSON mirrors the JSON API's error body so scripts can handle both
+// the same way.
+type errorJSON struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Fields  []fieldJSON `json:"fields,omitempty"`
+}
+
+type fieldJSON struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fail reports err on stderr, as JSON in JSON mode, and returns ExitFailure.
+// Invalid input is listed field by field.
+func (o output) fail(err error) int {
+	var fields domain.FieldErrors
+	errors.As(err, &fields)
+	if !o.json {
+		if len(fields) == 0 {
+			fmt.Fprintf(o.c.stderr, "drift: %v\n", err)
+			return ExitFailure
+		}
+		what := strings.TrimSuffix(strings.TrimSuffix(err.Error(), fields.Error()), ": ")
+		if what == "" {
+			what = domain.ErrInvalid.Error()
+		}
+		fmt.Fprintf(o.c.stderr, "drift: %s:\n", what)
+		for _, fe := range fields {
+			fmt.Fprintf(o.c.stderr, "  %s\n", fe)
+		}
+		return ExitFailure
+	}
+	detail := errorDetail{Code: errorCode(err), Message: err.Error()}
+	for _, fe := range fields {
+		detail.Fields = append(detail.Fields, fieldJSON{Field: fe.Field, Message: fe.Message})
+	}
+	enc := json.NewEncoder(o.c.stderr)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(errorJSON{Error: detail})
+	return ExitFailure
+}
+
+// errorCode classifies err with the JSON API's error codes.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, domain.ErrInvalid):
+		return "invalid"
+	case errors.Is(err, domain.ErrRunActive), errors.Is(err, domain.ErrRunNotActive), errors.Is(err, export.ErrNoResults):
+		return "conflict"
+	}
+	return "internal"
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// The fakes implement only what the commands under test call; anything else
+// panics through the nil embedded interface.
+
+type fakeIngest struct {
+	inbound.DataIngestionService
+}
+
+func (fakeIngest) IngestCSV(_ context.Context, r io.Reader, _ string) (int, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(b, []byte("date,")) {
+		return 0, domain.ErrInvalid
+	}
+	return bytes.Count(b, []byte("\n")) - 1, nil
+}
+
+type fakeResults struct {
+	inbound.ResultsService
+	runs []domain.Run
+}
+
+func (f *fakeResults) ImportExperiment(_ context.Context, r io.Reader) (*domain.Experiment, error) {
+	var cfg struct {
+		Experiment struct{ Name string } `json:"experiment"`
+	}
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, domain.ErrInvalid
+	}
+	if cfg.Experiment.Name == "" {
+		return nil, domain.FieldErrors{{Field: "experiment.name", Message: "is required"}}
+	}
+	return &domain.Experiment{ID: "exp_new", Name: cfg.Experiment.Name}, nil
+}
+
+func (f *fakeResults) ListExperiments(context.Context) ([]domain.Experiment, error) {
+	return []domain.Experiment{{ID: "exp_1"}, {ID: "exp_2"}}, nil
+}
+
+func (f *fakeResults) GetExperiment(_ context.Context, id string) (*domain.Experiment, error) {
+	if id != "exp_1" && id != "exp_2" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Experiment{ID: id}, nil
+}
+
+func (f *fakeResults) ListRuns(_ context.Context, experimentID string) ([]domain.Run, error) {
+	var out []domain.Run
+	for _, r := range f.runs {
+		if r.ExperimentID == experimentID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// fakeSim queues runs in memory; executing one (see execute) completes it.
+type fakeSim struct {
+	inbound.SimulationService
+	mu   sync.Mutex
+	runs map[string]domain.Run
+}
+
+func (f *fakeSim) EnqueueRun(_ context.Context, experimentID string) (*domain.Run, error) {
+	if experimentID != "exp_1" && experimentID != "exp_new" {
+		return nil, domain.ErrNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	run := domain.Run{ID: "run_new", ExperimentID: experimentID, Status: domain.StatusQueued, ProgressTotal: 4}
+	f.runs[run.ID] = run
+	return &run, nil
+}
+
+func (f *fakeSim) GetRun(_ context.Context, runID string) (*domain.Run, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	run, ok := f.runs[runID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &run, nil
+}
+
+func (f *fakeSim) GetRunPaths(_ context.Context, runID string, offset, limit int) (*domain.PathSet, error) {
+	if runID != "run_done" {
+		return nil, domain.ErrNotFound
+	}
+	set := &domain.PathSet{RunID: runID, Total: 2, Offset: offset, Days: []int{0, 1}}
+	for i := offset; i < min(offset+limit, 2); i++ {
+		set.Paths = append(set.Paths, domain.SimulatedPath{Values: []float64{100, 100 + float64(i)}})
+	}
+	return set, nil
+}
+
+func (f *fakeSim) execute(_ context.Context, runID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r := f.runs[runID]; r.Status == domain.StatusQueued {
+		r.Status, r.ProgressDone, r.Stats.P50 = domain.StatusComplete, r.ProgressTotal, 120
+		f.runs[runID] = r
+	}
+	return nil
+}
+
+var queued = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func newTestCLI() (*fakeSim, func(args ...string) (int, string, string)) {
+	done := domain.Run{ID: "run_done", ExperimentID: "exp_1", Status: domain.StatusComplete, QueuedAt: queued, ProgressDone: 2, ProgressTotal: 2, Stats: domain.ResultStats{P50: 110}}
+	failed := domain.Run{ID: "run_failed", ExperimentID: "exp_2", Status: domain.StatusFailed, Error: "boom", QueuedAt: queued.Add(time.Hour)}
+	results := &fakeResults{runs: []domain.Run{done, failed}}
+	sim := &fakeSim{runs: map[string]domain.Run{done.ID: done, failed.ID: failed}}
+	return sim, func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		c := New(fakeIngest{}, results, sim, WithOutput(&stdout, &stderr), WithExecutor(sim.execute), WithPollInterval(time.Millisecond))
+		code := c.Run(context.Background(), args)
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIngest(t *testing.T) {
+	_, drift := newTestCLI()
+	good := writeFile(t, "spy.csv", "date,symbol,adjusted_close\n2024-01-02,SPY,1\n2024-01-03,SPY,2\n")
+	bad := writeFile(t, "bad.csv", "nonsense\n")
+
+	code, stdout, _ := drift("ingest", good)
+	if code != ExitOK || !strings.Contains(stdout, "spy.csv: 2 records") {
+		t.Errorf("ingest = %d %q, want 0 and a record count", code, stdout)
+	}
+	code, stdout, stderr := drift("ingest", "--json", good, bad)
+	if code != ExitFailure {
+		t.Errorf("ingest with a bad file = %d, want %d", code, ExitFailure)
+	}
+	var res ingestJSON
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+		t.Fatalf("decode %q: %v", stdout, err)
+	}
+	if len(res.Files) != 2 || res.Files[0].Records != 2 || res.Files[1].Error == "" {
+		t.Errorf("ingest JSON = %+v, want the good file loaded and the bad one failed", res)
+	}
+	if !strings.Contains(stderr, `"code": "invalid"`) {
+		t.Errorf("stderr = %q, want a JSON invalid error", stderr)
+	}
+}
+
+func TestExperimentCreate(t *testing.T) {
+	_, drift := newTestCLI()
+	code, stdout, _ := drift("experiment", "create", "--json", "--from", writeFile(t, "exp.json", `{"experiment":{"name":"60/40"}}`))
+	if code != ExitOK || !strings.Contains(stdout, `"id": "exp_new"`) {
+		t.Errorf("create = %d %q, want the new experiment", code, stdout)
+	}
+	code, _, stderr := drift("experiment", "create", "--from", writeFile(t, "exp.json", `{"experiment":{}}`))
+	if code != ExitFailure || !strings.Contains(stderr, "  experiment.name: is required") {
+		t.Errorf("invalid create = %d %q, want the invalid field listed", code, stderr)
+	}
+	code, _, stderr = drift("experiment", "create", "--json", "--from", writeFile(t, "exp.json", `{"experiment":{}}`))
+	var e errorJSON
+	if err := json.Unmarshal([]byte(stderr), &e); err != nil || code != ExitFailure || len(e.Error.Fields) != 1 || e.Error.Fields[0].Field != "experiment.name" {
+		t.Errorf("invalid create with --json = %d %q, want the field in the error", code, stderr)
+	}
+}
+
+func TestRun(t *testing.T) {
+	sim, drift := newTestCLI()
+	code, stdout, _ := drift("run", "exp_1")
+	if code != ExitOK || stdout != "run_new queued\n" {
+		t.Errorf("run = %d %q, want the queued run", code, stdout)
+	}
+	if sim.runs["run_new"].Status != domain.StatusQueued {
+		t.Errorf("run without --wait was executed")
+	}
+
+	other := domain.Run{ID: "run_other", ExperimentID: "exp_1", Status: domain.StatusQueued}
+	sim.runs[other.ID] = other
+	code, stdout, _ = drift("run", "exp_1", "--wait", "--json")
+	var run runJSON
+	if err := json.Unmarshal([]byte(stdout), &run); err != nil {
+		t.Fatalf("decode %q: %v", stdout, err)
+	}
+	if code != ExitOK || run.Status != "complete" || run.Results == nil || run.Results.Percentiles.P50 != 120 {
+		t.Errorf("run --wait = %d %+v, want the completed run with results", code, run)
+	}
+	if sim.runs[other.ID].Status != domain.StatusQueued {
+		t.Errorf("run --wait executed another queued run")
+	}
+
+	if code, _, stderr := drift("run", "exp_missing"); code != ExitFailure || !strings.Contains(stderr, "not found") {
+		t.Errorf("run of a missing experiment = %d %q, want a not-found failure", code, stderr)
+	}
+}
+
+func TestRunsList(t *testing.T) {
+	_, drift := newTestCLI()
+	code, stdout, _ := drift("runs", "list")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != ExitOK || len(lines) != 3 || !strings.HasPrefix(lines[1], "run_failed") || !strings.Contains(lines[2], "110.00") {
+		t.Errorf("runs list = %d\n%s\nwant a header and both runs, newest first", code, stdout)
+	}
+	code, stdout, _ = drift("runs", "list", "--experiment", "exp_2", "--json")
+	var res runListJSON
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil || code != ExitOK || len(res.Runs) != 1 || res.Runs[0].Error != "boom" || res.Runs[0].Results != nil {
+		t.Errorf("runs list --experiment = %d %s", code, stdout)
+	}
+}
+
+func TestExport(t *testing.T) {
+	_, drift := newTestCLI()
+	code, stdout, _ := drift("export", "run_done", "--paths")
+	var res struct {
+		RunID string      `json:"run_id"`
+		Paths [][]float64 `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil || code != ExitOK || res.RunID != "run_done" || len(res.Paths) != 2 {
+		t.Errorf("export --paths = %d %s", code, stdout)
+	}
+	code, stdout, _ = drift("export", "run_done", "--format", "csv")
+	if code != ExitOK || stdout != "path,day_0,day_1,net_cash_flow\n0,100,100,0\n1,100,101,0\n" {
+		t.Errorf("export --format csv = %d %q", code, stdout)
+	}
+	if code, _, stderr := drift("export", "run_failed"); code != ExitFailure || !strings.Contains(stderr, "no results") {
+		t.Errorf("export of a failed run = %d %q, want a failure", code, stderr)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	_, drift := newTestCLI()
+	config := writeFile(t, "exp.json", `{"experiment":{"name":"60/40"}}`)
+	prices := filepath.Dir(writeFile(t, "spy.csv", "date,symbol,adjusted_close\n2024-01-02,SPY,1\n"))
+	results := filepath.Join(t.TempDir(), "results.json")
+
+	code, stdout, _ := drift("simulate", "--config", config, "--prices", prices, "--out", results)
+	if code != ExitOK || !strings.Contains(stdout, "run_new complete") || !strings.Contains(stdout, "p50 120.00") {
+		t.Errorf("simulate = %d %q, want the completed run's summary", code, stdout)
+	}
+	b, err := os.ReadFile(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		ExperimentID string `json:"experiment_id"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil || res.ExperimentID != "exp_new" {
+		t.Errorf("results file = %s, want the run's results JSON", b)
+	}
+
+	if code, _, stderr := drift("simulate", "--config", config, "--prices", t.TempDir()); code != ExitFailure || !strings.Contains(stderr, "no .csv files") {
+		t.Errorf("simulate without price files = %d %q, want a failure", code, stderr)
+	}
+	bad := filepath.Dir(writeFile(t, "bad.csv", "nonsense\n"))
+	if code, _, stderr := drift("simulate", "--config", config, "--prices", bad); code != ExitFailure || !strings.Contains(stderr, "bad.csv") {
+		t.Errorf("simulate with a bad price file = %d %q, want a failure naming it", code, stderr)
+	}
+	if !Headless([]string{"simulate", "--config", config}) || Headless([]string{"run", "exp_1"}) || Headless(nil) {
+		t.Error("Headless should hold for simulate only")
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	_, drift := newTestCLI()
+	for _, args := range [][]string{
+		{"frobnicate"},
+		{"ingest"},
+		{"experiment", "delete"},
+		{"experiment", "create"},
+		{"run"},
+		{"run", "exp_1", "--bogus"},
+		{"export", "run_done", "--format", "xml"},
+		{"simulate", "--config", "exp.json"},
+	} {
+		if code, _, _ := drift(args...); code != ExitUsage {
+			t.Errorf("drift %s = %d, want %d", strings.Join(args, " "), code, ExitUsage)
+		}
+	}
+	if code, stdout, _ := drift("help"); code != ExitOK || !strings.Contains(stdout, "Usage: drift") {
+		t.Errorf("help = %d %q", code, stdout)
+	}
+}
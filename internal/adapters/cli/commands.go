@@ -0,0 +1,482 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// runJSON is a run as the CLI prints it. Results is present once the run has
+// results, in the results export format.
+type runJSON struct {
+	ID           string          `json:"id"`
+	ExperimentID string          `json:"experiment_id"`
+	Status       string          `json:"status"`
+	Error        string          `json:"error,omitempty"`
+	QueuedAt     time.Time       `json:"queued_at"`
+	FinishedAt   *time.Time      `json:"finished_at,omitempty"`
+	Done         int             `json:"done"`
+	Total        int             `json:"total"`
+	Results      *export.Results `json:"results,omitempty"`
+}
+
+func newRunJSON(run domain.Run) runJSON {
+	out := runJSON{
+		ID:           run.ID,
+		ExperimentID: run.ExperimentID,
+		Status:       string(run.Status),
+		Error:        run.Error,
+		QueuedAt:     run.QueuedAt,
+		FinishedAt:   run.FinishedAt,
+		Done:         run.ProgressDone,
+		Total:        run.ProgressTotal,
+	}
+	if export.Check(run) == nil {
+		res := export.NewResults(run)
+		out.Results = &res
+	}
+	return out
+}
+
+func (c *CLI) runServe(ctx context.Context, args []string) int {
+	if len(args) > 0 {
+		return c.usageError("serve takes no arguments; configure it with DRIFT_* environment variables")
+	}
+	if c.serve == nil {
+		return output{c: c}.fail(errors.New("serve is not available"))
+	}
+	if err := c.serve(ctx); err != nil {
+		return output{c: c}.fail(err)
+	}
+	return ExitOK
+}
+
+type ingestJSON struct {
+	Files []ingestFileJSON `json:"files"`
+}
+
+type ingestFileJSON struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runIngest loads each CSV file in turn. A file that fails does not stop the
+// rest, but makes the command fail.
+func (c *CLI) runIngest(ctx context.Context, args []string) int {
+	fs := c.flags("ingest")
+	asJSON := fs.Bool("json", false, "print JSON")
+	files, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(files) == 0 {
+		return c.usageError("ingest: want at least one CSV file")
+	}
+	out := output{c: c, json: *asJSON}
+	var res ingestJSON
+	var failed error
+	for _, name := range files {
+		n, err := c.ingestFile(ctx, name)
+		f := ingestFileJSON{File: name, Records: n}
+		if err != nil {
+			f.Error = err.Error()
+			failed = errors.Join(failed, fmt.Errorf("%s: %w", name, err))
+		}
+		res.Files = append(res.Files, f)
+	}
+	out.print(res, func(w io.Writer) {
+		for _, f := range res.Files {
+			if f.Error == "" {
+				fmt.Fprintf(w, "%s: %d records\n", f.File, f.Records)
+			}
+		}
+	})
+	if failed != nil {
+		return out.fail(failed)
+	}
+	return ExitOK
+}
+
+func (c *CLI) ingestFile(ctx context.Context, name string) (int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+	return c.ingest.IngestCSV(ctx, f, filepath.Base(name))
+}
+
+type experimentJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c *CLI) runExperimentCreate(ctx context.Context, args []string) int {
+	fs := c.flags("experiment create")
+	from := fs.String("from", "", "JSON experiment config file, or - for stdin")
+	asJSON := fs.Bool("json", false, "print JSON")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if *from == "" || len(pos) > 0 {
+		return c.usageError("experiment create: want --from FILE and no arguments")
+	}
+	out := output{c: c, json: *asJSON}
+	exp, err := c.importExperiment(ctx, *from)
+	if err != nil {
+		return out.fail(err)
+	}
+	out.print(experimentJSON{ID: exp.ID, Name: exp.Name}, func(w io.Writer) {
+		fmt.Fprintf(w, "created experiment %s (%s)\n", exp.ID, exp.Name)
+	})
+	return ExitOK
+}
+
+// importExperiment creates an experiment from the JSON experiment config in
+// the named file, or on stdin if name is "-".
+func (c *CLI) importExperiment(ctx context.Context, name string) (*domain.Experiment, error) {
+	if name == "-" {
+		return c.results.ImportExperiment(ctx, os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+	return c.results.ImportExperiment(ctx, f)
+}
+
+// runRun queues a run of an experiment. With --wait it also waits for the run
+// to finish, and fails unless the run completes.
+func (c *CLI) runRun(ctx context.Context, args []string) int {
+	fs := c.flags("run")
+	wait := fs.Bool("wait", false, "execute the run and wait for it to finish")
+	asJSON := fs.Bool("json", false, "print JSON")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usageError("run: want one experiment ID")
+	}
+	out := output{c: c, json: *asJSON}
+	run, err := c.sim.EnqueueRun(ctx, pos[0])
+	if err != nil {
+		return out.fail(err)
+	}
+	if !*wait {
+		out.print(newRunJSON(*run), func(w io.Writer) {
+			fmt.Fprintf(w, "%s %s\n", run.ID, run.Status)
+		})
+		return ExitOK
+	}
+	run, err = c.wait(ctx, run.ID)
+	if err != nil {
+		return out.fail(err)
+	}
+	out.print(newRunJSON(*run), func(w io.Writer) { printRun(w, *run) })
+	if run.Status != domain.StatusComplete {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// wait polls the run until it finishes, executing it in the background
+// meanwhile if the CLI has an executor. If ctx is done first, the
+// run is cancelled and wait returns once it has stopped.
+func (c *CLI) wait(ctx context.Context, runID string) (*domain.Run, error) {
+	var executed chan error
+	if c.execute != nil {
+		// The executor outlives ctx so that an interrupted run can record
+		// its cancellation before the CLI exits.
+		execCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+		executed = make(chan error, 1)
+		go func() { executed <- c.execute(execCtx, runID) }()
+		defer func() {
+			stop()
+			if executed != nil {
+				<-executed
+			}
+		}()
+	}
+	tick := time.NewTicker(c.pollInterval)
+	defer tick.Stop()
+	interrupted := ctx.Done()
+	ctx = context.WithoutCancel(ctx)
+	for {
+		run, err := c.sim.GetRun(ctx, runID)
+		if err != nil {
+			return nil, err
+		}
+		if !run.Status.Active() {
+			return run, nil
+		}
+		select {
+		case <-interrupted:
+			interrupted = nil
+			if err := c.sim.CancelRun(ctx, runID); err != nil && !errors.Is(err, domain.ErrRunNotActive) {
+				return nil, err
+			}
+		case err := <-executed:
+			// The run has finished here, or is someone else's to finish.
+			executed = nil
+			if err != nil {
+				return nil, fmt.Errorf("execute run: %w", err)
+			}
+		case <-tick.C:
+		}
+	}
+}
+
+// printRun prints a run's outcome and, once it has results, their summary.
+func printRun(w io.Writer, run domain.Run) {
+	fmt.Fprintf(w, "%s %s", run.ID, run.Status)
+	if run.Error != "" {
+		fmt.Fprintf(w, ": %s", run.Error)
+	}
+	fmt.Fprintf(w, " (%d/%d paths)\n", run.ProgressDone, run.ProgressTotal)
+	if export.Check(run) != nil {
+		return
+	}
+	s := run.Stats
+	fmt.Fprintf(w, "  p5 %.2f  p50 %.2f  p95 %.2f\n", s.P5, s.P50, s.P95)
+	fmt.Fprintf(w, "  probability of loss %.1f%%  median max drawdown %.1f%%\n", s.ProbabilityOfLoss*100, s.MedianMaxDrawdown*100)
+	if in := run.Inputs; in != nil {
+		fmt.Fprintf(w, "  %s inputs:", in.Source)
+		for _, a := range in.Assets {
+			fmt.Fprintf(w, " %s %.2f%% ± %.2f%%", a.Symbol, a.ExpectedReturn*100, a.Volatility*100)
+		}
+		fmt.Fprintln(w)
+		if est := in.Estimator.Normalized(); !est.IsSample() {
+			fmt.Fprintf(w, "  estimators: %s means", est.Mean)
+			if in.MeanShrinkage > 0 {
+				fmt.Fprintf(w, " (shrinkage %.2f)", in.MeanShrinkage)
+			}
+			fmt.Fprintf(w, ", %s covariance", est.Cov)
+			if est.HalfLife > 0 {
+				fmt.Fprintf(w, " (half-life %d days)", est.HalfLife)
+			}
+			if in.CovShrinkage > 0 {
+				fmt.Fprintf(w, " (shrinkage %.2f)", in.CovShrinkage)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if f := run.Fit; f != nil {
+		fmt.Fprintf(w, "  fitted %s:", f.Model)
+		if f.LogLikelihood != 0 {
+			fmt.Fprintf(w, " log-likelihood %.2f", f.LogLikelihood)
+		}
+		printParams(w, f.Params)
+		for _, a := range f.Assets {
+			fmt.Fprintf(w, "    %s:", a.Symbol)
+			printParams(w, a.Params)
+		}
+	}
+}
+
+// printParams prints fitted parameters on one line, sorted by name.
+func printParams(w io.Writer, params map[string]float64) {
+	for _, k := range slices.Sorted(maps.Keys(params)) {
+		fmt.Fprintf(w, " %s %.4g", k, params[k])
+	}
+	fmt.Fprintln(w)
+}
+
+type runListJSON struct {
+	Runs []runJSON `json:"runs"`
+}
+
+// runRunsList lists the runs of one experiment, or of every experiment.
+func (c *CLI) runRunsList(ctx context.Context, args []string) int {
+	fs := c.flags("runs list")
+	expID := fs.String("experiment", "", "list only this experiment's runs")
+	asJSON := fs.Bool("json", false, "print JSON")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) > 0 {
+		return c.usageError("runs list: want no arguments")
+	}
+	out := output{c: c, json: *asJSON}
+	ids := []string{*expID}
+	if *expID == "" {
+		exps, err := c.results.ListExperiments(ctx)
+		if err != nil {
+			return out.fail(err)
+		}
+		ids = ids[:0]
+		for _, e := range exps {
+			ids = append(ids, e.ID)
+		}
+	} else if _, err := c.results.GetExperiment(ctx, *expID); err != nil {
+		return out.fail(fmt.Errorf("experiment %s: %w", *expID, err))
+	}
+	var runs []domain.Run
+	for _, id := range ids {
+		rs, err := c.results.ListRuns(ctx, id)
+		if err != nil {
+			return out.fail(err)
+		}
+		runs = append(runs, rs...)
+	}
+	// Each experiment's runs are newest first; merge them into one order.
+	slices.SortStableFunc(runs, func(a, b domain.Run) int { return b.QueuedAt.Compare(a.QueuedAt) })
+
+	res := runListJSON{Runs: make([]runJSON, len(runs))}
+	for i, r := range runs {
+		res.Runs[i] = newRunJSON(r)
+	}
+	out.print(res, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "RUN\tEXPERIMENT\tSTATUS\tQUEUED\tP50")
+		for _, r := range runs {
+			p50 := "-"
+			if export.Check(r) == nil {
+				p50 = fmt.Sprintf("%.2f", r.Stats.P50)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.ExperimentID, r.Status, r.QueuedAt.Format("2006-01-02 15:04"), p50)
+		}
+		_ = tw.Flush()
+	})
+	return ExitOK
+}
+
+// runExport writes a run's results JSON, or its stored paths as CSV, to
+// stdout.
+func (c *CLI) runExport(ctx context.Context, args []string) int {
+	fs := c.flags("export")
+	format := fs.String("format", "json", "json or csv")
+	paths := fs.Bool("paths", false, "include stored paths in the JSON export")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usageError("export: want one run ID")
+	}
+	if *format != "json" && *format != "csv" {
+		return c.usageError("export: --format must be json or csv")
+	}
+	out := output{c: c}
+	run, err := c.sim.GetRun(ctx, pos[0])
+	if err != nil {
+		return out.fail(fmt.Errorf("run %s: %w", pos[0], err))
+	}
+	if err := export.Check(*run); err != nil {
+		return out.fail(err)
+	}
+	var stored *export.Paths
+	if *paths || *format == "csv" {
+		if stored, err = export.OpenPaths(ctx, c.sim, run.ID); err != nil {
+			return out.fail(err)
+		}
+	}
+	if *format == "csv" {
+		err = export.WriteCSV(ctx, c.stdout, stored)
+	} else {
+		err = export.WriteJSON(ctx, c.stdout, *run, stored)
+	}
+	if err != nil {
+		return out.fail(err)
+	}
+	return ExitOK
+}
+
+// runSimulate runs an experiment config once over the price files given,
+// start to finish in this process. It expects services over an in-memory
+// store (see Headless), so nothing it loads or computes is kept. Like
+// "run --wait" it fails unless the run completes.
+func (c *CLI) runSimulate(ctx context.Context, args []string) int {
+	fs := c.flags("simulate")
+	config := fs.String("config", "", "JSON experiment config file, or - for stdin")
+	prices := fs.String("prices", "", "directory of CSV price files, or a single CSV file")
+	outFile := fs.String("out", "", "also write the results JSON to this file")
+	asJSON := fs.Bool("json", false, "print JSON")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if *config == "" || *prices == "" || len(pos) > 0 {
+		return c.usageError("simulate: want --config FILE and --prices DIR and no arguments")
+	}
+	out := output{c: c, json: *asJSON}
+	if c.execute == nil {
+		return out.fail(errors.New("simulate is not available"))
+	}
+	files, err := priceFiles(*prices)
+	if err != nil {
+		return out.fail(err)
+	}
+	for _, name := range files {
+		if _, err := c.ingestFile(ctx, name); err != nil {
+			return out.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	exp, err := c.importExperiment(ctx, *config)
+	if err != nil {
+		return out.fail(err)
+	}
+	run, err := c.sim.EnqueueRun(ctx, exp.ID)
+	if err != nil {
+		return out.fail(err)
+	}
+	if run, err = c.wait(ctx, run.ID); err != nil {
+		return out.fail(err)
+	}
+	if *outFile != "" && export.Check(*run) == nil {
+		if err := writeResults(ctx, *outFile, *run); err != nil {
+			return out.fail(err)
+		}
+	}
+	out.print(newRunJSON(*run), func(w io.Writer) { printRun(w, *run) })
+	if run.Status != domain.StatusComplete {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// priceFiles returns the CSV files in dir, or dir itself if it is a file.
+func priceFiles(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{dir}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .csv files in %s", dir)
+	}
+	return files, nil
+}
+
+// writeResults writes the run's results JSON to the named file.
+func writeResults(ctx context.Context, name string, run domain.Run) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteJSON(ctx, f, run, nil); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,212 @@
+// Package export writes a run's results in Drift's download formats: the
+// results export JSON of drift-plan.md §4.3 and a CSV of the stored paths.
+// The HTTP handlers and the CLI share it so both produce the same files.
+//
+// Stored paths are read through inbound.SimulationService a page at a time
+// and written as they arrive, so a large run is never held in memory whole.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// PageSize is how many stored paths are read at a time.
+const PageSize = 500
+
+// ErrNoResults is returned by Check for a run that is still queued or
+// running, failed, or was cancelled without keeping partial results.
+var ErrNoResults = errors.New("run has no results yet")
+
+// Results is the results export, and the results of a run wherever Drift
+// prints one: the API and the CLI embed it too. Days, Paths and NetCashFlows
+// are present only when paths are included; WriteJSON streams them rather
+// than filling Paths.
+type Results struct {
+	ExperimentID           string      `json:"experiment_id"`
+	RunID                  string      `json:"run_id"`
+	RanAt                  time.Time   `json:"ran_at"`
+	NumPaths               int         `json:"num_paths"`
+	Percentiles            Percentiles `json:"percentiles"`
+	Mean                   float64     `json:"mean"`
+	StdDev                 float64     `json:"std_dev"`
+	ProbabilityOfLoss      float64     `json:"probability_of_loss"`
+	ProbabilityOfDepletion float64     `json:"probability_of_depletion"`
+	MaxDrawdown            Drawdown    `json:"max_drawdown"`
+	MedianCAGR             float64     `json:"median_cagr"`
+	Days                   []int       `json:"days,omitempty"`
+	Paths                  [][]float64 `json:"paths,omitempty"`
+	NetCashFlows           []float64   `json:"net_cash_flows,omitempty"`
+}
+
+// Percentiles are the terminal values at the reported percentiles.
+type Percentiles struct {
+	P5  float64 `json:"p5"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P95 float64 `json:"p95"`
+}
+
+// Drawdown summarises the per-path maximum drawdown.
+type Drawdown struct {
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+}
+
+// NewResults maps run onto the results export, without paths.
+func NewResults(run domain.Run) Results {
+	s := run.Stats
+	ranAt := run.StartedAt
+	if ranAt.IsZero() {
+		ranAt = run.QueuedAt
+	}
+	return Results{
+		ExperimentID:           run.ExperimentID,
+		RunID:                  run.ID,
+		RanAt:                  ranAt.UTC(),
+		NumPaths:               run.ProgressDone,
+		Percentiles:            Percentiles{P5: s.P5, P25: s.P25, P50: s.P50, P75: s.P75, P95: s.P95},
+		Mean:                   s.Mean,
+		StdDev:                 s.StdDev,
+		ProbabilityOfLoss:      s.ProbabilityOfLoss,
+		ProbabilityOfDepletion: s.ProbabilityOfDepletion,
+		MaxDrawdown:            Drawdown{Median: s.MedianMaxDrawdown, P95: s.P95MaxDrawdown},
+		MedianCAGR:             s.MedianCAGR,
+	}
+}
+
+// Check returns ErrNoResults unless run has results to export: it completed,
+// or was cancelled with its partial results kept.
+func Check(run domain.Run) error {
+	if run.Status != domain.StatusComplete && run.Bands == nil {
+		return fmt.Errorf("run %s is %s: %w", run.ID, run.Status, ErrNoResults)
+	}
+	return nil
+}
+
+// Paths is a run's stored paths, read a page at a time as they are written.
+type Paths struct {
+	sim   inbound.SimulationService
+	first *domain.PathSet
+}
+
+// OpenPaths reads the first page of a run's stored paths, so that a run
+// without any is reported, as an error wrapping domain.ErrNotFound, before
+// anything has been written.
+func OpenPaths(ctx context.Context, sim inbound.SimulationService, runID string) (*Paths, error) {
+	set, err := sim.GetRunPaths(ctx, runID, 0, PageSize)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("stored paths for run %s: %w (set store_paths on the experiment to keep them)", runID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Paths{sim: sim, first: set}, nil
+}
+
+// each calls fn with the index and values of every path in order.
+func (p *Paths) each(ctx context.Context, fn func(i int, path domain.SimulatedPath) error) error {
+	set := p.first
+	for {
+		for j, path := range set.Paths {
+			if err := fn(set.Offset+j, path); err != nil {
+				return err
+			}
+		}
+		next := set.Offset + len(set.Paths)
+		if len(set.Paths) == 0 || next >= set.Total {
+			return nil
+		}
+		var err error
+		if set, err = p.sim.GetRunPaths(ctx, p.first.RunID, next, PageSize); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteJSON writes run's results export to w, with every stored path when
+// paths is not nil.
+func WriteJSON(ctx context.Context, w io.Writer, run domain.Run, paths *Paths) error {
+	out := NewResults(run)
+	if paths == nil {
+		return json.NewEncoder(w).Encode(out)
+	}
+	out.Days = paths.first.Days
+	head, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	// Reopen the encoded object to append the paths array to it.
+	if _, err := fmt.Fprintf(w, `%s,"paths":[`, head[:len(head)-1]); err != nil {
+		return err
+	}
+	flows := make([]float64, 0, paths.first.Total)
+	err = paths.each(ctx, func(i int, path domain.SimulatedPath) error {
+		flows = append(flows, path.NetCashFlow)
+		buf := make([]byte, 0, 16*len(path.Values)+2)
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '[')
+		buf = appendValues(buf, path.Values, paths.first.Precision)
+		buf = append(buf, ']')
+		_, err := w.Write(buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	buf := append([]byte(`],"net_cash_flows":[`), appendValues(nil, flows, domain.PrecisionFloat64)...)
+	_, err = w.Write(append(buf, "]}\n"...))
+	return err
+}
+
+// WriteCSV writes the stored paths to w as CSV: a header row of path, a
+// day_N column per stored trading day and net_cash_flow, then one row per
+// path.
+func WriteCSV(ctx context.Context, w io.Writer, paths *Paths) error {
+	buf := []byte("path")
+	for _, d := range paths.first.Days {
+		buf = append(buf, ",day_"...)
+		buf = strconv.AppendInt(buf, int64(d), 10)
+	}
+	buf = append(buf, ",net_cash_flow\n"...)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	return paths.each(ctx, func(i int, path domain.SimulatedPath) error {
+		buf := strconv.AppendInt(make([]byte, 0, 16*len(path.Values)+24), int64(i), 10)
+		buf = append(buf, ',')
+		buf = appendValues(buf, path.Values, paths.first.Precision)
+		buf = append(buf, ',')
+		buf = strconv.AppendFloat(buf, path.NetCashFlow, 'f', -1, 64)
+		buf = append(buf, '\n')
+		_, err := w.Write(buf)
+		return err
+	})
+}
+
+// appendValues appends vs comma-separated, formatted at the precision they
+// were stored with so float32 paths do not gain spurious digits.
+func appendValues(buf []byte, vs []float64, precision domain.PathPrecision) []byte {
+	bits := 64
+	if precision == domain.PrecisionFloat32 {
+		bits = 32
+	}
+	for i, v := range vs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, v, 'f', -1, bits)
+	}
+	return buf
+}
@@ -0,0 +1,194 @@
+// Package api serves Drift's versioned JSON REST API, mounted at /api/v1
+// alongside the HTML UI. It talks to the same inbound services as the UI
+// handlers and owns only the JSON representation of their results.
+//
+// Every response body is JSON. Failures use a single envelope:
+//
+//	{"error": {"code": "not_found", "message": "experiment exp_1: not found"}}
+//
+// with the HTTP status derived from the domain error that caused it.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// maxBodyBytes caps request bodies; price uploads are the largest.
+const maxBodyBytes = 32 << 20
+
+// API holds the services behind the JSON routes. It is an http.Handler
+// serving the /api/v1 route tree; its handler methods can also be used on
+// their own, e.g. to answer a UI route that negotiated JSON. Such routes must
+// name their path parameters as the API routes do.
+type API struct {
+	ingest  inbound.DataIngestionService
+	results inbound.ResultsService
+	sim     inbound.SimulationService
+	router  chi.Router
+}
+
+// New returns the /api/v1 route tree. Mount it under that prefix.
+func New(
+	ingest inbound.DataIngestionService,
+	results inbound.ResultsService,
+	sim inbound.SimulationService,
+) *API {
+	a := &API{ingest: ingest, results: results, sim: sim}
+
+	r := chi.NewRouter()
+	r.NotFound(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such route")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed on this route")
+	})
+
+	r.Route("/assets", func(r chi.Router) {
+		r.Get("/", a.ListAssets)
+		r.Get("/{symbol}", a.GetAsset)
+		r.Delete("/{symbol}", a.DeleteAsset)
+		r.Get("/{symbol}/prices", a.ListPrices)
+		r.Post("/{symbol}/prices", a.UpsertPrices)
+	})
+
+	r.Route("/experiments", func(r chi.Router) {
+		r.Get("/", a.ListExperiments)
+		r.Post("/", a.CreateExperiment)
+		r.Post("/import", a.ImportExperiment)
+		r.Get("/{id}", a.GetExperiment)
+		r.Put("/{id}", a.ReplaceExperiment)
+		r.Delete("/{id}", a.DeleteExperiment)
+		r.Get("/{id}/runs", a.ListRuns)
+		r.Post("/{id}/runs", a.CreateRun)
+	})
+
+	r.Get("/models", a.ListModels)
+
+	r.Route("/runs", func(r chi.Router) {
+		r.Get("/{id}", a.GetRun)
+		r.Delete("/{id}", a.DeleteRun)
+		r.Post("/{id}/cancel", a.CancelRun)
+		r.Get("/{id}/paths", a.GetRunPaths)
+	})
+
+	a.router = r
+	return a
+}
+
+// ServeHTTP dispatches to the /api/v1 routes.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.router.ServeHTTP(w, r)
+}
+
+// Router returns the /api/v1 route tree. Mounting it rather than the API
+// itself lets chi.Walk see the individual routes.
+func (a *API) Router() chi.Router {
+	return a.router
+}
+
+// errorBody is the envelope of every error response.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Fields  []fieldErrorJSON `json:"fields,omitempty"`
+}
+
+// fieldErrorJSON names one invalid field of the request.
+type fieldErrorJSON struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// writeJSON writes v as the response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("api: encode response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, errorBody{Error: errorDetail{Code: code, Message: msg}})
+}
+
+// fail maps a service error onto a status and error code. Errors the caller
+// cannot act on are logged and reported without detail.
+func fail(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, domain.ErrInvalid):
+		detail := errorDetail{Code: "invalid", Message: err.Error()}
+		var fields domain.FieldErrors
+		if errors.As(err, &fields) {
+			for _, fe := range fields {
+				detail.Fields = append(detail.Fields, fieldErrorJSON{Field: fe.Field, Message: fe.Message})
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: detail})
+	case errors.Is(err, domain.ErrRunActive), errors.Is(err, domain.ErrRunNotActive):
+		writeError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		slog.Error("api: request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal", "internal server error")
+	}
+}
+
+// named labels a not-found error with the resource that was missing, e.g.
+// "run run_1: not found"; any other error is returned unchanged.
+func named(err error, kind, id string) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("%s %s: %w", kind, id, domain.ErrNotFound)
+	}
+	return err
+}
+
+// badRequest reports input the API layer itself rejected.
+func badRequest(w http.ResponseWriter, format string, args ...any) {
+	writeError(w, http.StatusBadRequest, "invalid", fmt.Sprintf(format, args...))
+}
+
+// upload returns the request body, or for a multipart form the file in its
+// "file" field, capped at maxBodyBytes.
+func upload(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != "multipart/form-data" {
+		return r.Body, nil
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("%w: multipart form needs a file field: %w", domain.ErrInvalid, err)
+	}
+	return f, nil
+}
+
+// decodeJSON strictly decodes the request body into v: unknown fields and
+// trailing data are errors.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decode request body: %w", err)
+	}
+	if dec.More() {
+		return errors.New("decode request body: unexpected data after JSON value")
+	}
+	return nil
+}
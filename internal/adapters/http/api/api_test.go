@@ -0,0 +1,297 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// The fakes implement only what the routes under test call; anything else
+// panics through the nil embedded interface.
+
+type fakeIngest struct {
+	inbound.DataIngestionService
+	ingested []domain.PriceRecord
+}
+
+func (f *fakeIngest) IngestRecords(_ context.Context, recs []domain.PriceRecord) (int, error) {
+	f.ingested = append(f.ingested, recs...)
+	return len(recs), nil
+}
+
+type fakeResults struct {
+	inbound.ResultsService
+	exps map[string]domain.Experiment
+}
+
+func (f *fakeResults) GetExperiment(_ context.Context, id string) (*domain.Experiment, error) {
+	exp, ok := f.exps[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &exp, nil
+}
+
+func (f *fakeResults) ImportExperiment(_ context.Context, r io.Reader) (*domain.Experiment, error) {
+	var cfg struct {
+		Experiment struct{ Name string } `json:"experiment"`
+	}
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, domain.ErrInvalid
+	}
+	if cfg.Experiment.Name == "" {
+		return nil, domain.FieldErrors{{Field: "experiment.name", Message: "is required"}}
+	}
+	exp := domain.Experiment{ID: "exp_new", Name: cfg.Experiment.Name}
+	f.exps[exp.ID] = exp
+	return &exp, nil
+}
+
+func (f *fakeResults) EncodeExperiment(exp domain.Experiment) ([]byte, error) {
+	return json.Marshal(map[string]any{"version": "1", "experiment": map[string]string{"name": exp.Name}})
+}
+
+type fakeSim struct {
+	inbound.SimulationService
+	runs map[string]domain.Run
+}
+
+func (f *fakeSim) EnqueueRun(_ context.Context, experimentID string) (*domain.Run, error) {
+	run := domain.Run{ID: "run_new", ExperimentID: experimentID, Status: domain.StatusQueued, ProgressTotal: 10}
+	f.runs[run.ID] = run
+	return &run, nil
+}
+
+func (f *fakeSim) GetRun(_ context.Context, runID string) (*domain.Run, error) {
+	run, ok := f.runs[runID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &run, nil
+}
+
+func (f *fakeSim) CancelRun(_ context.Context, runID string) error {
+	if !f.runs[runID].Status.Active() {
+		return domain.ErrRunNotActive
+	}
+	return nil
+}
+
+func (f *fakeSim) ListModels() []domain.ModelSpec {
+	return []domain.ModelSpec{
+		{Name: domain.ModelGBM, Label: "GBM"},
+		{Name: domain.ModelBlockBootstrap, Label: "Block bootstrap", Params: []domain.ParamSpec{
+			{Name: "block_scheme", Type: domain.ParamString, Default: "fixed", Enum: []string{"fixed", "circular"}},
+		}},
+	}
+}
+
+func newTestAPI() (*API, *fakeIngest) {
+	ingest := &fakeIngest{}
+	results := &fakeResults{exps: map[string]domain.Experiment{"exp_1": {ID: "exp_1", Name: "60/40"}}}
+	sim := &fakeSim{runs: map[string]domain.Run{
+		"run_done": {ID: "run_done", ExperimentID: "exp_1", Status: domain.StatusComplete, Stats: domain.ResultStats{P50: 1.5},
+			Inputs: &domain.MarketInputs{
+				Source:       domain.SourceAssumptions,
+				Assets:       []domain.AssetAssumption{{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16}},
+				Correlations: [][]float64{{1}},
+			}},
+	}}
+	return New(ingest, results, sim), ingest
+}
+
+func do(t *testing.T, a *API, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if ct := rec.Header().Get("Content-Type"); rec.Code != http.StatusNoContent && ct != "application/json" {
+		t.Errorf("%s %s Content-Type = %q, want application/json", method, path, ct)
+	}
+	conforms(t, a, method, path, rec)
+	return rec
+}
+
+// spec is the API's own OpenAPI description, with experiment configs left
+// as plain objects.
+var spec = func() openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1"})
+	b.Define(ExperimentConfigSchema, nil)
+	b.Add("", Operations()...)
+	return b.Document()
+}()
+
+// conforms fails the test unless the response to a routed request is
+// documented by Operations and its body matches the documented schema.
+func conforms(t *testing.T, a *API, method, path string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	rctx := chi.NewRouteContext()
+	path, _, _ = strings.Cut(path, "?")
+	if !a.Router().Match(rctx, method, path) {
+		return // the router's own 404 and 405 replies describe no route
+	}
+	route := openapi.Path(rctx.RoutePattern())
+	op := spec.Paths[route][strings.ToLower(method)]
+	if op == nil {
+		t.Errorf("%s %s is not documented", method, route)
+		return
+	}
+	if _, ok := op.Responses[strconv.Itoa(rec.Code)]; !ok {
+		t.Errorf("%s %s: status %d is not documented", method, route, rec.Code)
+		return
+	}
+	if rec.Code == http.StatusNoContent {
+		return
+	}
+	schema := spec.ResponseSchema(method, route, rec.Code, "application/json")
+	if err := spec.Validate(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("%s %s %d: body does not match the spec: %v", method, route, rec.Code, err)
+	}
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestErrorsUseJSONEnvelope(t *testing.T) {
+	h, _ := newTestAPI()
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/experiments/missing", "", http.StatusNotFound, "not_found"},
+		{"GET", "/runs/missing", "", http.StatusNotFound, "not_found"},
+		{"GET", "/no/such/route", "", http.StatusNotFound, "not_found"},
+		{"PATCH", "/experiments/exp_1", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"POST", "/experiments", `{"experiment":{}}`, http.StatusBadRequest, "invalid"},
+		{"POST", "/runs/run_done/cancel", "", http.StatusConflict, "conflict"},
+		{"GET", "/runs/run_done/paths?limit=0", "", http.StatusBadRequest, "invalid"},
+	}
+	for _, tc := range tests {
+		rec := do(t, h, tc.method, tc.path, tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		got := decodeBody[errorBody](t, rec)
+		if got.Error.Code != tc.code || got.Error.Message == "" {
+			t.Errorf("%s %s error = %+v, want code %q with a message", tc.method, tc.path, got.Error, tc.code)
+		}
+	}
+	if got := decodeBody[errorBody](t, do(t, h, "GET", "/experiments/missing", "")); got.Error.Message != "experiment missing: not found" {
+		t.Errorf("not-found message = %q, want it to name the experiment", got.Error.Message)
+	}
+}
+
+func TestCreateExperimentAndRun(t *testing.T) {
+	h, _ := newTestAPI()
+
+	rec := do(t, h, "POST", "/experiments", `{"version":"1","experiment":{"name":"glide"}}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/api/v1/experiments/exp_new" {
+		t.Fatalf("create = %d Location %q, want 201 /api/v1/experiments/exp_new", rec.Code, rec.Header().Get("Location"))
+	}
+	exp := decodeBody[experimentJSON](t, rec)
+	if exp.ID != "exp_new" || !strings.Contains(string(exp.Config), `"name":"glide"`) {
+		t.Errorf("created experiment = %s %s, want the encoded config", exp.ID, exp.Config)
+	}
+
+	rec = do(t, h, "POST", "/experiments/exp_new/runs", "")
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Location") != "/api/v1/runs/run_new" {
+		t.Fatalf("create run = %d Location %q, want 202 /api/v1/runs/run_new", rec.Code, rec.Header().Get("Location"))
+	}
+	run := decodeBody[runJSON](t, rec)
+	if run.Status != "queued" || run.Progress.Total != 10 || run.Results != nil {
+		t.Errorf("queued run = %+v, want queued with no stats", run)
+	}
+
+	done := decodeBody[runJSON](t, do(t, h, "GET", "/runs/run_done", ""))
+	if done.Results == nil || done.Results.Percentiles.P50 != 1.5 {
+		t.Errorf("complete run results = %+v, want P50 1.5", done.Results)
+	}
+	if in := done.Inputs; in == nil || in.Source != "assumptions" || in.Assets[0].ExpectedReturn != 0.06 {
+		t.Errorf("complete run inputs = %+v, want the assumptions it used", done.Inputs)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	h, _ := newTestAPI()
+
+	got := decodeBody[modelListJSON](t, do(t, h, "GET", "/models", ""))
+	if len(got.Models) != 2 || got.Models[0].Name != "gbm" || got.Models[0].Params == nil {
+		t.Fatalf("models = %+v, want gbm first with an empty params list", got.Models)
+	}
+	p := got.Models[1].Params
+	if len(p) != 1 || p[0].Type != "string" || p[0].Default != "fixed" || len(p[0].Enum) != 2 {
+		t.Errorf("block_bootstrap params = %+v, want block_scheme with its enum", p)
+	}
+}
+
+func TestUpsertPricesValidatesRecords(t *testing.T) {
+	h, ingest := newTestAPI()
+
+	rec := do(t, h, "POST", "/assets/spy/prices", `{"records":[{"date":"2024-01-02","adjusted_close":470.1},{"adjusted_close":471}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing date status = %d, want 400", rec.Code)
+	}
+	if got := decodeBody[errorBody](t, rec); !strings.Contains(got.Error.Message, "records[1].date") {
+		t.Errorf("message = %q, want it to name records[1].date", got.Error.Message)
+	}
+	if rec := do(t, h, "POST", "/assets/spy/prices", `{"records":[],"extra":1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown field status = %d, want 400", rec.Code)
+	}
+	if len(ingest.ingested) != 0 {
+		t.Fatalf("rejected bodies ingested %d records", len(ingest.ingested))
+	}
+
+	rec = do(t, h, "POST", "/assets/spy/prices", `{"records":[{"date":"2024-01-02","close":470,"adjusted_close":470.1}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+	}
+	if len(ingest.ingested) != 1 || ingest.ingested[0].Symbol != "SPY" || ingest.ingested[0].AdjustedClose != 470.1 {
+		t.Errorf("ingested %+v, want one SPY record", ingest.ingested)
+	}
+}
+
+func TestImportExperimentFromMultipartOrBody(t *testing.T) {
+	a, _ := newTestAPI()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "glide.json")
+	_, _ = io.WriteString(fw, `{"version":"1","experiment":{"name":"glide"}}`)
+	_ = mw.Close()
+	req := httptest.NewRequest("POST", "/experiments/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	conforms(t, a, "POST", "/experiments/import", rec)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/api/v1/experiments/exp_new" {
+		t.Fatalf("multipart import = %d Location %q, want 201 /api/v1/experiments/exp_new", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = do(t, a, "POST", "/experiments/import", `{"version":"1","experiment":{}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid import status = %d, want 400", rec.Code)
+	}
+	got := decodeBody[errorBody](t, rec).Error.Fields
+	if len(got) != 1 || got[0].Field != "experiment.name" || got[0].Message != "is required" {
+		t.Errorf("fields = %+v, want experiment.name is required", got)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// dateLayout is the wire format of every calendar date in the API.
+const dateLayout = "2006-01-02"
+
+type assetJSON struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+type priceJSON struct {
+	Date          string  `json:"date"`
+	Open          float64 `json:"open,omitempty"`
+	High          float64 `json:"high,omitempty"`
+	Low           float64 `json:"low,omitempty"`
+	Close         float64 `json:"close,omitempty"`
+	Volume        int64   `json:"volume,omitempty"`
+	AdjustedClose float64 `json:"adjusted_close"`
+}
+
+type assetListJSON struct {
+	Assets []assetJSON `json:"assets"`
+}
+
+type ingestedJSON struct {
+	Symbol   string `json:"symbol"`
+	Ingested int    `json:"ingested"`
+}
+
+type pricesJSON struct {
+	Symbol  string      `json:"symbol,omitempty"`
+	Records []priceJSON `json:"records"`
+}
+
+func newAssetJSON(a domain.Asset) assetJSON {
+	return assetJSON{Symbol: a.Symbol, Name: a.Name}
+}
+
+// ListAssets returns every asset with price data.
+func (a *API) ListAssets(w http.ResponseWriter, r *http.Request) {
+	assets, err := a.ingest.ListAssets(r.Context())
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	out := make([]assetJSON, len(assets))
+	for i, as := range assets {
+		out[i] = newAssetJSON(as)
+	}
+	writeJSON(w, http.StatusOK, assetListJSON{Assets: out})
+}
+
+// GetAsset returns a single asset by symbol.
+func (a *API) GetAsset(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+	asset, err := a.ingest.GetAsset(r.Context(), symbol)
+	if err != nil {
+		fail(w, r, named(err, "asset", symbol))
+		return
+	}
+	writeJSON(w, http.StatusOK, newAssetJSON(*asset))
+}
+
+// DeleteAsset removes an asset and all of its price records.
+func (a *API) DeleteAsset(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+	if _, err := a.ingest.GetAsset(r.Context(), symbol); err != nil {
+		fail(w, r, named(err, "asset", symbol))
+		return
+	}
+	if err := a.ingest.DeleteAsset(r.Context(), symbol); err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPrices returns an asset's price records in ascending date order,
+// optionally limited to the inclusive ?from= and ?to= dates (YYYY-MM-DD).
+func (a *API) ListPrices(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+	from, err := queryDate(r, "from")
+	if err != nil {
+		badRequest(w, "%v", err)
+		return
+	}
+	to, err := queryDate(r, "to")
+	if err != nil {
+		badRequest(w, "%v", err)
+		return
+	}
+	if _, err := a.ingest.GetAsset(r.Context(), symbol); err != nil {
+		fail(w, r, named(err, "asset", symbol))
+		return
+	}
+	recs, err := a.ingest.GetAssetPricesBetween(r.Context(), symbol, from, to)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	out := pricesJSON{Symbol: symbol, Records: make([]priceJSON, len(recs))}
+	for i, rec := range recs {
+		out.Records[i] = priceJSON{
+			Date:          rec.Date.Format(dateLayout),
+			Open:          rec.Open,
+			High:          rec.High,
+			Low:           rec.Low,
+			Close:         rec.Close,
+			Volume:        rec.Volume,
+			AdjustedClose: rec.AdjustedClose,
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// UpsertPrices stores the posted price records for an asset, creating the
+// asset if needed. A record for a date that already exists replaces it.
+// Symbols are upper-cased, as they are for CSV uploads.
+func (a *API) UpsertPrices(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(chi.URLParam(r, "symbol"))
+	var body pricesJSON
+	if err := decodeJSON(w, r, &body); err != nil {
+		badRequest(w, "%v", err)
+		return
+	}
+	if body.Symbol != "" && !strings.EqualFold(body.Symbol, symbol) {
+		badRequest(w, "symbol %q does not match the URL symbol %q", body.Symbol, symbol)
+		return
+	}
+	if len(body.Records) == 0 {
+		badRequest(w, "records must not be empty")
+		return
+	}
+	recs := make([]domain.PriceRecord, len(body.Records))
+	for i, p := range body.Records {
+		date, err := time.Parse(dateLayout, p.Date)
+		if err != nil {
+			badRequest(w, "records[%d].date must be YYYY-MM-DD", i)
+			return
+		}
+		if p.AdjustedClose <= 0 {
+			badRequest(w, "records[%d].adjusted_close must be positive", i)
+			return
+		}
+		recs[i] = domain.PriceRecord{
+			Symbol:        symbol,
+			Date:          date,
+			Open:          p.Open,
+			High:          p.High,
+			Low:           p.Low,
+			Close:         p.Close,
+			Volume:        p.Volume,
+			AdjustedClose: p.AdjustedClose,
+		}
+	}
+	n, err := a.ingest.IngestRecords(r.Context(), recs)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ingestedJSON{Symbol: symbol, Ingested: n})
+}
+
+// queryDate parses the named YYYY-MM-DD query parameter; absent is zero.
+func queryDate(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be YYYY-MM-DD", name)
+	}
+	return t, nil
+}
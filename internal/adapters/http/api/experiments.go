@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// experimentJSON is the API representation of an experiment. Config is the
+// experiment in the JSON experiment config format, exactly as accepted by
+// POST /api/v1/experiments, so a fetched config can be edited and sent back.
+type experimentJSON struct {
+	ID        string          `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Config    json.RawMessage `json:"config" openapi:"ref=ExperimentConfig"`
+}
+
+type experimentListJSON struct {
+	Experiments []experimentJSON `json:"experiments"`
+}
+
+func (a *API) newExperimentJSON(exp domain.Experiment) (experimentJSON, error) {
+	cfg, err := a.results.EncodeExperiment(exp)
+	if err != nil {
+		return experimentJSON{}, err
+	}
+	return experimentJSON{ID: exp.ID, CreatedAt: exp.CreatedAt, UpdatedAt: exp.UpdatedAt, Config: cfg}, nil
+}
+
+// writeExperiment responds with exp as an experimentJSON.
+func (a *API) writeExperiment(w http.ResponseWriter, r *http.Request, status int, exp domain.Experiment) {
+	out, err := a.newExperimentJSON(exp)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, status, out)
+}
+
+// ListExperiments returns every experiment, newest first.
+func (a *API) ListExperiments(w http.ResponseWriter, r *http.Request) {
+	exps, err := a.results.ListExperiments(r.Context())
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	out := make([]experimentJSON, len(exps))
+	for i, exp := range exps {
+		if out[i], err = a.newExperimentJSON(exp); err != nil {
+			fail(w, r, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, experimentListJSON{Experiments: out})
+}
+
+// CreateExperiment creates an experiment from a JSON experiment config body
+// and responds 201 with its Location.
+func (a *API) CreateExperiment(w http.ResponseWriter, r *http.Request) {
+	exp, err := a.results.ImportExperiment(r.Context(), http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/experiments/"+exp.ID)
+	a.writeExperiment(w, r, http.StatusCreated, *exp)
+}
+
+// ImportExperiment is CreateExperiment for files: the JSON experiment config
+// is either the raw body or the file field of a multipart form.
+func (a *API) ImportExperiment(w http.ResponseWriter, r *http.Request) {
+	body, err := upload(w, r)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	defer body.Close()
+	exp, err := a.results.ImportExperiment(r.Context(), body)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/experiments/"+exp.ID)
+	a.writeExperiment(w, r, http.StatusCreated, *exp)
+}
+
+// GetExperiment returns a single experiment.
+func (a *API) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	exp, err := a.results.GetExperiment(r.Context(), id)
+	if err != nil {
+		fail(w, r, named(err, "experiment", id))
+		return
+	}
+	a.writeExperiment(w, r, http.StatusOK, *exp)
+}
+
+// ReplaceExperiment overwrites an experiment with a JSON experiment config
+// body. Runs already recorded keep their results.
+func (a *API) ReplaceExperiment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	exp, err := a.results.ReplaceExperiment(r.Context(), id, http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		fail(w, r, named(err, "experiment", id))
+		return
+	}
+	a.writeExperiment(w, r, http.StatusOK, *exp)
+}
+
+// DeleteExperiment removes an experiment and its runs; 409 while one of its
+// runs is queued or running.
+func (a *API) DeleteExperiment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := a.results.DeleteExperiment(r.Context(), id); err != nil {
+		fail(w, r, named(err, "experiment", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRuns returns an experiment's runs, most recently queued first. Runs in
+// the list omit their bands; fetch a run for those.
+func (a *API) ListRuns(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := a.results.GetExperiment(r.Context(), id); err != nil {
+		fail(w, r, named(err, "experiment", id))
+		return
+	}
+	runs, err := a.results.ListRuns(r.Context(), id)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	out := make([]runJSON, len(runs))
+	for i, run := range runs {
+		out[i] = newRunJSON(run)
+		out[i].Bands = nil
+	}
+	writeJSON(w, http.StatusOK, runListJSON{Runs: out})
+}
+
+// CreateRun queues a run of the experiment and responds 202 with the run and
+// its Location. If the experiment already has a queued or running run, that
+// run is returned instead of queueing another.
+func (a *API) CreateRun(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	run, err := a.sim.EnqueueRun(r.Context(), id)
+	if err != nil {
+		fail(w, r, named(err, "experiment", id))
+		return
+	}
+	w.Header().Set("Location", "/api/v1/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, newRunJSON(*run))
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// modelJSON is the API representation of a simulation model. Params are the
+// model-specific keys of the experiment config's simulation section;
+// TakesAssumptions is whether the model accepts simulation.assumptions.
+type modelJSON struct {
+	Name             string      `json:"name"`
+	Label            string      `json:"label"`
+	Description      string      `json:"description"`
+	Params           []paramJSON `json:"params"`
+	TakesAssumptions bool        `json:"takes_assumptions"`
+}
+
+type modelListJSON struct {
+	Models []modelJSON `json:"models"`
+}
+
+// paramJSON describes one model parameter in JSON Schema terms.
+type paramJSON struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type" openapi:"enum=number|integer|boolean|string"`
+	Description string   `json:"description"`
+	Default     any      `json:"default"`
+	Minimum     *float64 `json:"minimum,omitempty"`
+	Maximum     *float64 `json:"maximum,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+func newModelJSON(m domain.ModelSpec) modelJSON {
+	out := modelJSON{
+		Name:             string(m.Name),
+		Label:            m.Label,
+		Description:      m.Description,
+		Params:           []paramJSON{},
+		TakesAssumptions: m.TakesAssumptions,
+	}
+	for _, p := range m.Params {
+		out.Params = append(out.Params, paramJSON{
+			Name:        p.Name,
+			Type:        string(p.Type),
+			Description: p.Description,
+			Default:     p.Default,
+			Minimum:     p.Min,
+			Maximum:     p.Max,
+			Enum:        p.Enum,
+		})
+	}
+	return out
+}
+
+// ListModels returns the simulation models experiments can use, with the
+// parameters each reads.
+func (a *API) ListModels(w http.ResponseWriter, _ *http.Request) {
+	specs := a.sim.ListModels()
+	out := make([]modelJSON, len(specs))
+	for i, m := range specs {
+		out[i] = newModelJSON(m)
+	}
+	writeJSON(w, http.StatusOK, modelListJSON{Models: out})
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+)
+
+// ExperimentConfigSchema is the component name under which the server
+// registers the JSON experiment config schema that experimentJSON.Config and
+// experiment request bodies refer to.
+const ExperimentConfigSchema = "ExperimentConfig"
+
+// Operations describes the API's routes, relative to its /api/v1 mount
+// point, for the OpenAPI document. Keep it in step with New; a test in the
+// http adapter fails if a route is missing from either.
+func Operations() []openapi.Route {
+	experimentConfig := openapi.Body{
+		ContentType: openapi.JSON,
+		Ref:         ExperimentConfigSchema,
+		Description: "A JSON experiment config.",
+	}
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/assets", OperationID: "listAssets", Tags: tagAssets,
+			Summary:   "List assets",
+			Responses: responses(reply(http.StatusOK, assetListJSON{}), failure(http.StatusInternalServerError)),
+		},
+		{
+			Method: http.MethodGet, Path: "/assets/{symbol}", OperationID: "getAsset", Tags: tagAssets,
+			Summary:   "Get an asset",
+			Responses: responses(reply(http.StatusOK, assetJSON{}), failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodDelete, Path: "/assets/{symbol}", OperationID: "deleteAsset", Tags: tagAssets,
+			Summary:   "Delete an asset and all of its price records",
+			Responses: responses(noContent(), failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodGet, Path: "/assets/{symbol}/prices", OperationID: "listPrices", Tags: tagAssets,
+			Summary: "List an asset's price records in ascending date order",
+			Query: []openapi.Param{
+				{Name: "from", Format: "date", Description: "First date to include (YYYY-MM-DD)."},
+				{Name: "to", Format: "date", Description: "Last date to include (YYYY-MM-DD)."},
+			},
+			Responses: responses(reply(http.StatusOK, pricesJSON{}), failure(http.StatusBadRequest, http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodPost, Path: "/assets/{symbol}/prices", OperationID: "upsertPrices", Tags: tagAssets,
+			Summary:     "Insert or replace price records, creating the asset if needed",
+			Request:     []openapi.Body{{ContentType: openapi.JSON, Type: pricesJSON{}}},
+			Responses:   responses(reply(http.StatusOK, ingestedJSON{}), failure(http.StatusBadRequest)),
+			Description: "Records are keyed by date; a record for a date already stored replaces it.",
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments", OperationID: "listExperiments", Tags: tagExperiments,
+			Summary:   "List experiments, newest first",
+			Responses: responses(reply(http.StatusOK, experimentListJSON{}), failure(http.StatusInternalServerError)),
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments", OperationID: "createExperiment", Tags: tagExperiments,
+			Summary:   "Create an experiment from a JSON experiment config",
+			Request:   []openapi.Body{experimentConfig},
+			Responses: responses(reply(http.StatusCreated, experimentJSON{}, "Location"), failure(http.StatusBadRequest)),
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments/import", OperationID: "importExperiment", Tags: tagExperiments,
+			Summary:     "Create an experiment from an uploaded JSON experiment config file",
+			Description: "Send the config as the raw body, as for createExperiment, or as the file field of a multipart form.",
+			Request: []openapi.Body{
+				experimentConfig,
+				{ContentType: openapi.Multipart, Type: uploadForm{}, Description: "Or a multipart form with the config file in the file field."},
+			},
+			Responses: responses(reply(http.StatusCreated, experimentJSON{}, "Location"), failure(http.StatusBadRequest)),
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments/{id}", OperationID: "getExperiment", Tags: tagExperiments,
+			Summary:   "Get an experiment",
+			Responses: responses(reply(http.StatusOK, experimentJSON{}), failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodPut, Path: "/experiments/{id}", OperationID: "replaceExperiment", Tags: tagExperiments,
+			Summary:   "Replace an experiment's config, keeping its ID and runs",
+			Request:   []openapi.Body{experimentConfig},
+			Responses: responses(reply(http.StatusOK, experimentJSON{}), failure(http.StatusBadRequest, http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodDelete, Path: "/experiments/{id}", OperationID: "deleteExperiment", Tags: tagExperiments,
+			Summary:   "Delete an experiment and its runs",
+			Responses: responses(noContent(), failure(http.StatusNotFound, http.StatusConflict)),
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments/{id}/runs", OperationID: "listRuns", Tags: tagRuns,
+			Summary:   "List an experiment's runs, most recently queued first, without bands",
+			Responses: responses(reply(http.StatusOK, runListJSON{}), failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments/{id}/runs", OperationID: "createRun", Tags: tagRuns,
+			Summary:     "Queue a run of an experiment",
+			Description: "If the experiment already has a queued or running run, that run is returned instead.",
+			Responses: responses([]openapi.Response{{
+				Status:      http.StatusAccepted,
+				Description: "The queued run, or the experiment's run already queued or running; nothing new is queued then.",
+				Headers:     []string{"Location"},
+				Bodies:      []openapi.Body{{ContentType: openapi.JSON, Type: runJSON{}}},
+			}}, failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodGet, Path: "/models", OperationID: "listModels", Tags: tagModels,
+			Summary:     "List the simulation models and their parameters",
+			Description: "Each parameter is a key of the experiment config's simulation section, read only by its model.",
+			Responses:   responses(reply(http.StatusOK, modelListJSON{})),
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}", OperationID: "getRun", Tags: tagRuns,
+			Summary:   "Get a run with its statistics and percentile bands",
+			Responses: responses(reply(http.StatusOK, runJSON{}), failure(http.StatusNotFound)),
+		},
+		{
+			Method: http.MethodDelete, Path: "/runs/{id}", OperationID: "deleteRun", Tags: tagRuns,
+			Summary:   "Delete a finished run and its results",
+			Responses: responses(noContent(), failure(http.StatusNotFound, http.StatusConflict)),
+		},
+		{
+			Method: http.MethodPost, Path: "/runs/{id}/cancel", OperationID: "cancelRun", Tags: tagRuns,
+			Summary:   "Cancel a queued or running run",
+			Responses: responses(reply(http.StatusAccepted, runJSON{}), failure(http.StatusNotFound, http.StatusConflict)),
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}/paths", OperationID: "getRunPaths", Tags: tagRuns,
+			Summary: "Page through the paths stored for a run",
+			Query: []openapi.Param{
+				{Name: "offset", Type: "integer", Description: "Index of the first path (default 0)."},
+				{Name: "limit", Type: "integer", Description: "Number of paths, 1 to 1000 (default 100)."},
+			},
+			Responses: responses(reply(http.StatusOK, pathsJSON{}), failure(http.StatusBadRequest, http.StatusNotFound)),
+		},
+	}
+}
+
+// uploadForm is the multipart body upload reads.
+type uploadForm struct {
+	File string `json:"file" openapi:"format=binary"`
+}
+
+var (
+	tagAssets      = []string{"assets"}
+	tagExperiments = []string{"experiments"}
+	tagModels      = []string{"models"}
+	tagRuns        = []string{"runs"}
+)
+
+// responses collects one route's responses.
+func responses(rs ...[]openapi.Response) []openapi.Response {
+	var out []openapi.Response
+	for _, r := range rs {
+		out = append(out, r...)
+	}
+	return out
+}
+
+// reply documents a JSON success response of type v.
+func reply(status int, v any, headers ...string) []openapi.Response {
+	return []openapi.Response{{
+		Status:  status,
+		Headers: headers,
+		Bodies:  []openapi.Body{{ContentType: openapi.JSON, Type: v}},
+	}}
+}
+
+func noContent() []openapi.Response {
+	return []openapi.Response{{Status: http.StatusNoContent}}
+}
+
+// failure documents error responses, which all share errorBody.
+func failure(statuses ...int) []openapi.Response {
+	out := make([]openapi.Response, len(statuses))
+	for i, s := range statuses {
+		out[i] = openapi.Response{Status: s, Bodies: []openapi.Body{{ContentType: openapi.JSON, Type: errorBody{}}}}
+	}
+	return out
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Path window sizes for GET /runs/{id}/paths.
+const (
+	defaultPathLimit = 100
+	maxPathLimit     = 1000
+)
+
+// runJSON is the API representation of a run. Results, in the results
+// export format, and Bands are present once the run has results: when it
+// completed, or was cancelled with keep_partial set.
+type runJSON struct {
+	ID           string          `json:"id"`
+	ExperimentID string          `json:"experiment_id"`
+	Status       string          `json:"status" openapi:"enum=queued|running|complete|failed|cancelled"`
+	Error        string          `json:"error,omitempty"`
+	QueuedAt     *time.Time      `json:"queued_at,omitempty"`
+	StartedAt    *time.Time      `json:"started_at,omitempty"`
+	FinishedAt   *time.Time      `json:"finished_at,omitempty"`
+	Progress     progressJSON    `json:"progress"`
+	Results      *export.Results `json:"results,omitempty"`
+	Bands        *bandsJSON      `json:"bands,omitempty"`
+	Fit          *fitJSON        `json:"fit,omitempty"`
+	Inputs       *inputsJSON     `json:"inputs,omitempty"`
+}
+
+type runListJSON struct {
+	Runs []runJSON `json:"runs"`
+}
+
+type progressJSON struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+type bandsJSON struct {
+	Days      []int       `json:"days"`
+	Quantiles []float64   `json:"quantiles"`
+	Values    [][]float64 `json:"values"`
+	Sample    [][]float64 `json:"sample"`
+}
+
+// fitJSON is what the run's model estimated from the lookback window.
+type fitJSON struct {
+	Model         string             `json:"model"`
+	LogLikelihood float64            `json:"log_likelihood"`
+	Params        map[string]float64 `json:"params,omitempty"`
+	Assets        []assetFitJSON     `json:"assets,omitempty"`
+}
+
+type assetFitJSON struct {
+	Symbol        string             `json:"symbol"`
+	LogLikelihood float64            `json:"log_likelihood,omitempty"`
+	Params        map[string]float64 `json:"params"`
+}
+
+// inputsJSON is what the run's model was calibrated to, and where it came
+// from: annual expected simple returns and volatilities, and correlations,
+// in portfolio order, with the estimators that produced them.
+type inputsJSON struct {
+	Source        string           `json:"source" openapi:"enum=historical|assumptions"`
+	Assets        []assumptionJSON `json:"assets"`
+	Correlations  [][]float64      `json:"correlations"`
+	MeanEstimator string           `json:"mean_estimator" openapi:"enum=sample|bayes_stein"`
+	CovEstimator  string           `json:"cov_estimator" openapi:"enum=sample|ewma|ledoit_wolf"`
+	EWMAHalfLife  int              `json:"ewma_half_life,omitempty"`
+	MeanShrinkage float64          `json:"mean_shrinkage"`
+	CovShrinkage  float64          `json:"cov_shrinkage"`
+}
+
+type assumptionJSON struct {
+	Symbol         string  `json:"symbol"`
+	ExpectedReturn float64 `json:"expected_return"`
+	Volatility     float64 `json:"volatility"`
+}
+
+type pathsJSON struct {
+	RunID     string      `json:"run_id"`
+	Total     int         `json:"total"`
+	Offset    int         `json:"offset"`
+	Days      []int       `json:"days"`
+	Precision string      `json:"precision"`
+	Paths     [][]float64 `json:"paths"`
+	// NetCashFlows holds each path's contributions minus withdrawals,
+	// aligned with Paths.
+	NetCashFlows []float64 `json:"net_cash_flows"`
+}
+
+func newRunJSON(run domain.Run) runJSON {
+	out := runJSON{
+		ID:           run.ID,
+		ExperimentID: run.ExperimentID,
+		Status:       string(run.Status),
+		Error:        run.Error,
+		QueuedAt:     timePtr(run.QueuedAt),
+		StartedAt:    timePtr(run.StartedAt),
+		FinishedAt:   run.FinishedAt,
+		Progress:     progressJSON{Done: run.ProgressDone, Total: run.ProgressTotal},
+	}
+	if export.Check(run) == nil {
+		res := export.NewResults(run)
+		out.Results = &res
+	}
+	if b := run.Bands; b != nil {
+		out.Bands = &bandsJSON{Days: b.Days, Quantiles: b.Quantiles, Values: b.Values, Sample: b.Sample}
+	}
+	if f := run.Fit; f != nil {
+		out.Fit = &fitJSON{Model: string(f.Model), LogLikelihood: f.LogLikelihood, Params: f.Params}
+		for _, a := range f.Assets {
+			out.Fit.Assets = append(out.Fit.Assets, assetFitJSON{Symbol: a.Symbol, LogLikelihood: a.LogLikelihood, Params: a.Params})
+		}
+	}
+	if in := run.Inputs; in != nil {
+		est := in.Estimator.Normalized()
+		out.Inputs = &inputsJSON{
+			Source:        string(in.Source),
+			Assets:        []assumptionJSON{},
+			Correlations:  in.Correlations,
+			MeanEstimator: string(est.Mean),
+			CovEstimator:  string(est.Cov),
+			EWMAHalfLife:  est.HalfLife,
+			MeanShrinkage: in.MeanShrinkage,
+			CovShrinkage:  in.CovShrinkage,
+		}
+		for _, a := range in.Assets {
+			out.Inputs.Assets = append(out.Inputs.Assets, assumptionJSON(a))
+		}
+	}
+	return out
+}
+
+// timePtr returns nil for the zero time so it is omitted from JSON.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// GetRun returns a run with its statistics and percentile bands.
+func (a *API) GetRun(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	run, err := a.sim.GetRun(r.Context(), id)
+	if err != nil {
+		fail(w, r, named(err, "run", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, newRunJSON(*run))
+}
+
+// DeleteRun removes a finished run and its results; 409 while it is queued
+// or running.
+func (a *API) DeleteRun(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := a.sim.DeleteRun(r.Context(), id); err != nil {
+		fail(w, r, named(err, "run", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelRun stops a queued or running run and responds 202 with its state;
+// 409 if it has already finished. A running run is recorded as cancelled
+// once its workers stop, so poll GET /runs/{id} for the final state.
+func (a *API) CancelRun(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := a.sim.GetRun(r.Context(), id); err != nil {
+		fail(w, r, named(err, "run", id))
+		return
+	}
+	if err := a.sim.CancelRun(r.Context(), id); err != nil {
+		fail(w, r, err)
+		return
+	}
+	run, err := a.sim.GetRun(r.Context(), id)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, newRunJSON(*run))
+}
+
+// GetRunPaths returns a window of the paths stored for a run, selected by
+// ?offset= (default 0) and ?limit= (default 100, at most 1000). Runs whose
+// experiment did not set store_paths have none, which is a 404.
+func (a *API) GetRunPaths(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		badRequest(w, "offset must be a non-negative integer")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPathLimit)
+	if err != nil || limit < 1 || limit > maxPathLimit {
+		badRequest(w, "limit must be an integer between 1 and %d", maxPathLimit)
+		return
+	}
+	if _, err := a.sim.GetRun(r.Context(), id); err != nil {
+		fail(w, r, named(err, "run", id))
+		return
+	}
+	set, err := a.sim.GetRunPaths(r.Context(), id, offset, limit)
+	if err != nil {
+		fail(w, r, named(err, "stored paths for run", id))
+		return
+	}
+	out := pathsJSON{
+		RunID:        set.RunID,
+		Total:        set.Total,
+		Offset:       set.Offset,
+		Days:         set.Days,
+		Precision:    string(set.Precision),
+		Paths:        make([][]float64, len(set.Paths)),
+		NetCashFlows: make([]float64, len(set.Paths)),
+	}
+	for i, p := range set.Paths {
+		out.Paths[i] = p.Values
+		out.NetCashFlows[i] = p.NetCashFlow
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// queryInt parses the named integer query parameter, returning def if absent.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
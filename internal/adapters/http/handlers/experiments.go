@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -45,15 +52,18 @@ func (h *H) NewExperimentForm(w http.ResponseWriter, r *http.Request) {
 	data := map[string]any{
 		"Title":  "New Experiment",
 		"Assets": assets,
+		"Models": h.sim.ListModels(),
 	}
 	if err := h.page("experiment-builder.html").ExecuteTemplate(w, "layout", data); err != nil {
 		renderErr(w, err)
 	}
 }
 
-// CreateExperiment handles form submission to create a new experiment.
+// CreateExperiment handles form submission to create a new experiment. The
+// builder posts a multipart form so it can carry an assumptions file; plain
+// URL-encoded forms are accepted too.
 func (h *H) CreateExperiment(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -63,6 +73,19 @@ func (h *H) CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	lookback, _ := strconv.Atoi(r.FormValue("lookback_days"))
 	startVal, _ := strconv.ParseFloat(r.FormValue("start_value"), 64)
 	contrib, _ := strconv.ParseFloat(r.FormValue("annual_contribution"), 64)
+	withdrawal, _ := strconv.ParseFloat(r.FormValue("annual_withdrawal"), 64)
+	withdrawalPct, _ := strconv.ParseFloat(r.FormValue("withdrawal_rate"), 64)
+	inflationPct, _ := strconv.ParseFloat(r.FormValue("inflation_rate"), 64)
+	blockLen, _ := strconv.Atoi(r.FormValue("block_length"))
+	jumpIntensity, _ := strconv.ParseFloat(r.FormValue("jump_intensity"), 64)
+	jumpMeanPct, _ := strconv.ParseFloat(r.FormValue("jump_mean"), 64)
+	jumpVolPct, _ := strconv.ParseFloat(r.FormValue("jump_vol"), 64)
+	regimeStates, _ := strconv.Atoi(r.FormValue("regime_states"))
+	hestonKappa, _ := strconv.ParseFloat(r.FormValue("heston_kappa"), 64)
+	hestonLongRunVolPct, _ := strconv.ParseFloat(r.FormValue("heston_long_run_vol"), 64)
+	hestonXi, _ := strconv.ParseFloat(r.FormValue("heston_xi"), 64)
+	hestonRho, _ := strconv.ParseFloat(r.FormValue("heston_rho"), 64)
+	pathEvery, _ := strconv.Atoi(r.FormValue("path_every"))
 
 	if numPaths <= 0 {
 		numPaths = 1000
@@ -90,17 +113,88 @@ func (h *H) CreateExperiment(w http.ResponseWriter, r *http.Request) {
 		assets = append(assets, domain.PortfolioAsset{Symbol: sym, Weight: weight})
 	}
 
+	rebalance, ok := domain.ParseRebalanceFrequency(r.FormValue("rebalance"))
+	if !ok {
+		http.Error(w, "unknown rebalance frequency: "+r.FormValue("rebalance"), http.StatusBadRequest)
+		return
+	}
+
+	var asOf *time.Time
+	if v := r.FormValue("as_of"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "as_of must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		asOf = &t
+	}
+
+	quantiles, err := parsePercentiles(r.FormValue("quantiles"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assumptions, err := h.formAssumptions(r)
+	if err != nil {
+		http.Error(w, "capital market assumptions: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The half-life field is always posted; it only means something for EWMA.
+	estimator := domain.EstimatorParams{
+		Mean: domain.MeanEstimator(r.FormValue("mean_estimator")),
+		Cov:  domain.CovEstimator(r.FormValue("cov_estimator")),
+	}
+	if estimator.Cov == domain.CovEWMA {
+		estimator.HalfLife, _ = strconv.Atoi(r.FormValue("ewma_half_life"))
+	}
+
 	exp := domain.Experiment{
 		Name:        r.FormValue("name"),
 		Description: r.FormValue("description"),
-		Portfolio:   domain.Portfolio{Assets: assets, Rebalance: domain.RebalanceAnnual},
+		Portfolio:   domain.Portfolio{Assets: assets, Rebalance: rebalance},
 		Config: domain.SimulationConfig{
 			Model:              domain.SimulationModel(r.FormValue("model")),
 			NumPaths:           numPaths,
 			HorizonDays:        horizon,
 			LookbackDays:       lookback,
+			AsOf:               asOf,
 			StartValue:         startVal,
 			AnnualContribution: contrib,
+			AnnualWithdrawal:   withdrawal,
+			WithdrawalRate:     withdrawalPct / 100.0,
+			CashFlowFrequency:  domain.CashFlowFrequency(r.FormValue("cash_flow_frequency")),
+			InflationRate:      inflationPct / 100.0,
+			RepairCovariance:   r.FormValue("repair_covariance") == "1",
+			Assumptions:        assumptions,
+			Estimator:          estimator,
+			Block: domain.BlockParams{
+				Length: blockLen,
+				Scheme: domain.BlockScheme(r.FormValue("block_scheme")),
+			},
+			StudentT: domain.StudentTParams{Skewed: r.FormValue("skewed_t") == "1"},
+			GARCH:    domain.GARCHParams{GJR: r.FormValue("gjr") == "1"},
+			Jump: domain.JumpParams{
+				Intensity: jumpIntensity,
+				Mean:      jumpMeanPct / 100.0,
+				Vol:       jumpVolPct / 100.0,
+			},
+			Regime: domain.RegimeParams{
+				States:       regimeStates,
+				StartCurrent: r.FormValue("regime_start_current") == "1",
+			},
+			Heston: domain.HestonParams{
+				Kappa: hestonKappa,
+				Theta: (hestonLongRunVolPct / 100.0) * (hestonLongRunVolPct / 100.0),
+				Xi:    hestonXi,
+				Rho:   hestonRho,
+			},
+			StorePaths:    r.FormValue("store_paths") == "1",
+			PathPrecision: domain.PathPrecision(r.FormValue("path_precision")),
+			PathEvery:     pathEvery,
+			Quantiles:     quantiles,
+			KeepPartial:   r.FormValue("keep_partial") == "1",
 		},
 	}
 	if exp.Config.Model == "" {
@@ -108,7 +202,11 @@ func (h *H) CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	created, err := h.results.CreateExperiment(r.Context(), exp)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -120,6 +218,77 @@ func (h *H) CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// formAssumptions reads the builder's capital market assumptions: the
+// uploaded file if there is one, otherwise the typed-in CSV. nil means the
+// form has none.
+func (h *H) formAssumptions(r *http.Request) (*domain.CapitalMarketAssumptions, error) {
+	if file, _, err := r.FormFile("assumptions_file"); err == nil {
+		defer file.Close() //nolint:errcheck // read-only upload
+		return h.ingest.ParseAssumptions(file)
+	}
+	if text := strings.TrimSpace(r.FormValue("assumptions")); text != "" {
+		return h.ingest.ParseAssumptions(strings.NewReader(text))
+	}
+	return nil, nil
+}
+
+// ImportExperiment creates an experiment from a JSON experiment config, sent
+// either as the file field of a multipart form (the "Import JSON" control on
+// the experiment list) or as the raw request body. On success it redirects to
+// the new experiment, with an HX-Redirect header for HTMX. A config that fails
+// validation gets a 422 listing every invalid field: as a fragment for HTMX,
+// otherwise as a page.
+func (h *H) ImportExperiment(w http.ResponseWriter, r *http.Request) {
+	body := io.ReadCloser(r.Body)
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			h.importFailed(w, r, http.StatusBadRequest, fmt.Errorf("parse form: %w", err))
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			h.importFailed(w, r, http.StatusBadRequest, errors.New("file field required"))
+			return
+		}
+		body = file
+	}
+	defer body.Close() //nolint:errcheck // read-only body; error is non-actionable
+
+	exp, err := h.results.ImportExperiment(r.Context(), body)
+	switch {
+	case errors.Is(err, domain.ErrInvalid):
+		h.importFailed(w, r, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		renderErr(w, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/experiments/"+exp.ID)
+	http.Redirect(w, r, "/experiments/"+exp.ID, http.StatusSeeOther)
+}
+
+// importFailed renders the reasons an import was rejected.
+func (h *H) importFailed(w http.ResponseWriter, r *http.Request, status int, err error) {
+	data := map[string]any{
+		"Title": "Import failed",
+		"Error": err.Error(),
+	}
+	var fields domain.FieldErrors
+	if errors.As(err, &fields) {
+		data["Error"] = "The experiment config is invalid."
+		data["Fields"] = fields
+	}
+	name := "layout"
+	if r.Header.Get("HX-Request") == "true" {
+		name = "import-errors"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.page("experiment-import.html").ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("render import errors", "err", err)
+	}
+}
+
 // ExperimentDetail renders a single experiment page with its run history.
 func (h *H) ExperimentDetail(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -138,3 +307,21 @@ func (h *H) ExperimentDetail(w http.ResponseWriter, r *http.Request) {
 		renderErr(w, err)
 	}
 }
+
+// parsePercentiles parses a comma-separated list of percentiles such as
+// "5, 25, 50, 75, 95" into quantile fractions. A blank value yields nil so the
+// engine falls back to its defaults.
+func parsePercentiles(v string) ([]float64, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	var qs []float64
+	for _, f := range strings.Split(v, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("percentile %q must be a number between 0 and 100", strings.TrimSpace(f))
+		}
+		qs = append(qs, p/100)
+	}
+	return qs, nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// fakeExperiments stands in for the results service on the create path: it
+// validates as the service does and keeps what it would save.
+type fakeExperiments struct {
+	inbound.ResultsService
+	saved []domain.Experiment
+}
+
+func (f *fakeExperiments) CreateExperiment(_ context.Context, exp domain.Experiment) (*domain.Experiment, error) {
+	if errs := exp.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+	exp.ID = "exp_new"
+	f.saved = append(f.saved, exp)
+	return &exp, nil
+}
+
+// postExperiment submits the builder form for a valid one-asset GBM
+// experiment, with fields added or overriding its values.
+func postExperiment(t *testing.T, results *fakeExperiments, fields url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	form := url.Values{"name": {"test"}, "symbols": {"SPY"}, "weights": {"100"}, "model": {"gbm"}}
+	for k, v := range fields {
+		form[k] = v
+	}
+	req := httptest.NewRequest(http.MethodPost, "/experiments", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	New(nil, results, nil, nil, "").CreateExperiment(w, req)
+	return w
+}
+
+// wantRejected checks that posting fields fails with a 400 naming field, and
+// that nothing is saved.
+func wantRejected(t *testing.T, fields url.Values, field string) {
+	t.Helper()
+	results := &fakeExperiments{}
+	w := postExperiment(t, results, fields)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), field) {
+		t.Errorf("POST %v: status %d %q, want 400 naming %s", fields, w.Code, w.Body.String(), field)
+	}
+	if len(results.saved) > 0 {
+		t.Errorf("POST %v saved %+v", fields, results.saved)
+	}
+}
+
+func TestCreateExperimentSavesValidForm(t *testing.T) {
+	results := &fakeExperiments{}
+	w := postExperiment(t, results, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/experiments/exp_new" {
+		t.Fatalf("status %d, location %q, want a redirect to the new experiment", w.Code, w.Header().Get("Location"))
+	}
+	if len(results.saved) != 1 || results.saved[0].Portfolio.Assets[0].Weight != 1 {
+		t.Errorf("saved %+v, want SPY at weight 1", results.saved)
+	}
+}
+
+func TestCreateExperimentRejectsHestonOutOfRange(t *testing.T) {
+	wantRejected(t, url.Values{"model": {"heston"}, "heston_kappa": {"2"}, "heston_rho": {"2"}}, "heston_rho")
+}
+
+func TestCreateExperimentRejectsWithdrawalRateOver100(t *testing.T) {
+	wantRejected(t, url.Values{"withdrawal_rate": {"150"}}, "withdrawal_rate")
+}
+
+func TestCreateExperimentRejectsNegativeBlockLength(t *testing.T) {
+	wantRejected(t, url.Values{"model": {"block_bootstrap"}, "block_length": {"-5"}}, "block_length")
+}
+
+func TestCreateExperimentRejectsNegativeJumpVol(t *testing.T) {
+	wantRejected(t, url.Values{"model": {"jump_diffusion"}, "jump_intensity": {"2"}, "jump_vol": {"-5"}}, "jump_vol")
+}
+
+func TestCreateExperimentRejectsUnknownRegimeCount(t *testing.T) {
+	wantRejected(t, url.Values{"model": {"regime_switching"}, "regime_states": {"4"}}, "regime_states")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// ExportRun downloads a run's results as JSON. ?include=paths adds every
+// stored path, streamed a page at a time; runs whose experiment did not set
+// store_paths have none, which is a 404. Runs without results yet are a 409.
+func (h *H) ExportRun(w http.ResponseWriter, r *http.Request) {
+	include := r.URL.Query().Get("include")
+	if include != "" && include != "paths" {
+		http.Error(w, "include must be paths", http.StatusBadRequest)
+		return
+	}
+	run, ok := h.finishedRun(w, r)
+	if !ok {
+		return
+	}
+	var paths *export.Paths
+	if include == "paths" {
+		if paths, ok = h.openPaths(w, r, run.ID); !ok {
+			return
+		}
+	}
+	attach(w, "application/json", run.ID+".json")
+	// Once the body has started the status line is gone; truncating the
+	// body is all an error can do.
+	_ = export.WriteJSON(r.Context(), w, *run, paths)
+}
+
+// ExportRunPaths downloads a run's stored paths as CSV: a header row of
+// path, day_N columns for each kept trading day and net_cash_flow, then one
+// row per path.
+func (h *H) ExportRunPaths(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.finishedRun(w, r)
+	if !ok {
+		return
+	}
+	paths, ok := h.openPaths(w, r, run.ID)
+	if !ok {
+		return
+	}
+	attach(w, "text/csv; charset=utf-8", run.ID+"-paths.csv")
+	_ = export.WriteCSV(r.Context(), w, paths)
+}
+
+// ExportExperiment downloads an experiment as a JSON experiment config, the
+// format ImportExperiment reads, so an exported config imports unchanged.
+func (h *H) ExportExperiment(w http.ResponseWriter, r *http.Request) {
+	exp, err := h.results.GetExperiment(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "experiment not found", http.StatusNotFound)
+		return
+	}
+	cfg, err := h.results.EncodeExperiment(*exp)
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+	attach(w, "application/json", exp.ID+".json")
+	_, _ = w.Write(append(cfg, '\n'))
+}
+
+// finishedRun loads the run named in the URL, writing a 404 if there is no
+// such run and a 409 if it has no results to export yet.
+func (h *H) finishedRun(w http.ResponseWriter, r *http.Request) (*domain.Run, bool) {
+	run, err := h.sim.GetRun(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err := export.Check(*run); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, false
+	}
+	return run, true
+}
+
+// openPaths opens a run's stored paths before anything is written, so a run
+// without stored paths can still be a 404.
+func (h *H) openPaths(w http.ResponseWriter, r *http.Request, runID string) (*export.Paths, bool) {
+	paths, err := export.OpenPaths(r.Context(), h.sim, runID)
+	if errors.Is(err, domain.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		renderErr(w, err)
+		return nil, false
+	}
+	return paths, true
+}
+
+// attach sets the headers of a file download named filename.
+func attach(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// The fakes implement only what the export routes call; anything else
+// panics through the nil embedded interface.
+
+type fakeResults struct {
+	inbound.ResultsService
+}
+
+func (fakeResults) GetExperiment(_ context.Context, id string) (*domain.Experiment, error) {
+	if id != "exp_1" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Experiment{ID: id, Name: "60/40"}, nil
+}
+
+func (fakeResults) EncodeExperiment(exp domain.Experiment) ([]byte, error) {
+	return json.Marshal(map[string]any{"version": "1", "experiment": map[string]string{"name": exp.Name}})
+}
+
+// fakeSim stores numPaths paths for run_paths, path i holding i on each of
+// three days, and counts the pages read.
+type fakeSim struct {
+	inbound.SimulationService
+	numPaths int
+	pages    int
+}
+
+var ranAt = time.Date(2026, 2, 27, 14, 32, 1, 0, time.UTC)
+
+func (f *fakeSim) GetRun(_ context.Context, runID string) (*domain.Run, error) {
+	switch runID {
+	case "run_paths", "run_done":
+		return &domain.Run{
+			ID: runID, ExperimentID: "exp_1", Status: domain.StatusComplete, StartedAt: ranAt,
+			ProgressDone: f.numPaths, ProgressTotal: f.numPaths,
+			Stats: domain.ResultStats{P5: 1, P25: 2, P50: 3, P75: 4, P95: 5, ProbabilityOfLoss: 0.1, MedianMaxDrawdown: -0.2, P95MaxDrawdown: -0.4},
+		}, nil
+	case "run_active":
+		return &domain.Run{ID: runID, ExperimentID: "exp_1", Status: domain.StatusRunning}, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeSim) GetRunPaths(_ context.Context, runID string, offset, limit int) (*domain.PathSet, error) {
+	if runID != "run_paths" {
+		return nil, domain.ErrNotFound
+	}
+	f.pages++
+	set := &domain.PathSet{RunID: runID, Total: f.numPaths, Offset: offset, Days: []int{0, 5, 10}, Precision: domain.PrecisionFloat64}
+	for i := offset; i < min(offset+limit, f.numPaths); i++ {
+		v := float64(i)
+		set.Paths = append(set.Paths, domain.SimulatedPath{Values: []float64{v, v + 0.5, v}, NetCashFlow: -v})
+	}
+	return set, nil
+}
+
+func newExportRouter(sim *fakeSim) http.Handler {
+	h := New(nil, fakeResults{}, sim, nil, "")
+	r := chi.NewRouter()
+	r.Get("/experiments/{id}/export.json", h.ExportExperiment)
+	r.Get("/runs/{id}/export.json", h.ExportRun)
+	r.Get("/runs/{id}/paths.csv", h.ExportRunPaths)
+	return r
+}
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func TestExportRun(t *testing.T) {
+	sim := &fakeSim{numPaths: 2*export.PageSize + 1}
+	h := newExportRouter(sim)
+	b := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1"})
+	b.Define(experimentConfigSchema, nil)
+	b.Add("", Operations()...)
+	doc := b.Document()
+	schema := doc.ResponseSchema("GET", "/runs/{id}/export.json", http.StatusOK, openapi.JSON)
+
+	rec := get(h, "/runs/run_done/export.json")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Disposition") != `attachment; filename=run_done.json` {
+		t.Fatalf("export = %d %q, want 200 as an attachment", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+	if err := doc.Validate(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("export does not match its schema: %v\n%s", err, rec.Body)
+	}
+	var out export.Results
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.RunID != "run_done" || !out.RanAt.Equal(ranAt) || out.Percentiles.P50 != 3 || out.MaxDrawdown.P95 != -0.4 || out.Paths != nil {
+		t.Errorf("export = %+v", out)
+	}
+
+	rec = get(h, "/runs/run_paths/export.json?include=paths")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export with paths = %d: %s", rec.Code, rec.Body)
+	}
+	if err := doc.Validate(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("export with paths does not match its schema: %v", err)
+	}
+	out = export.Results{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Paths) != sim.numPaths || len(out.Days) != 3 || out.Paths[export.PageSize+1][1] != export.PageSize+1.5 {
+		t.Errorf("export has %d paths and days %v, want %d paths over 3 days", len(out.Paths), out.Days, sim.numPaths)
+	}
+	if len(out.NetCashFlows) != sim.numPaths || out.NetCashFlows[export.PageSize+1] != -(export.PageSize+1) {
+		t.Errorf("export has net cash flows %v, want one per path", out.NetCashFlows)
+	}
+	if sim.pages != 3 {
+		t.Errorf("read %d pages of paths, want 3", sim.pages)
+	}
+
+	for path, want := range map[string]int{
+		"/runs/missing/export.json":                http.StatusNotFound,
+		"/runs/run_active/export.json":             http.StatusConflict,
+		"/runs/run_done/export.json?include=all":   http.StatusBadRequest,
+		"/runs/run_done/export.json?include=paths": http.StatusNotFound,
+	} {
+		if rec := get(h, path); rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestExportRunPaths(t *testing.T) {
+	h := newExportRouter(&fakeSim{numPaths: export.PageSize + 2})
+	rec := get(h, "/runs/run_paths/paths.csv")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("paths.csv = %d %q, want 200 CSV", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rows[0], ","); got != "path,day_0,day_5,day_10,net_cash_flow" {
+		t.Errorf("header = %q", got)
+	}
+	if len(rows) != export.PageSize+3 {
+		t.Fatalf("%d rows, want a header and %d paths", len(rows), export.PageSize+2)
+	}
+	if got := strings.Join(rows[len(rows)-1], ","); got != "501,501,501.5,501,-501" {
+		t.Errorf("last row = %q", got)
+	}
+	if rec := get(h, "/runs/run_done/paths.csv"); rec.Code != http.StatusNotFound {
+		t.Errorf("paths.csv without stored paths = %d, want 404", rec.Code)
+	}
+}
+
+func TestExportExperiment(t *testing.T) {
+	h := newExportRouter(&fakeSim{})
+	rec := get(h, "/experiments/exp_1/export.json")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("export = %d %q, want 200 JSON", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"experiment":{"name":"60/40"},"version":"1"}` {
+		t.Errorf("export = %s", got)
+	}
+	if rec := get(h, "/experiments/missing/export.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing experiment = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import "net/http"
+
+// Models renders the page describing the simulation models experiments can
+// use and the parameters each reads.
+func (h *H) Models(w http.ResponseWriter, _ *http.Request) {
+	data := map[string]any{
+		"Title":  "Models",
+		"Models": h.sim.ListModels(),
+	}
+	if err := h.page("models.html").ExecuteTemplate(w, "layout", data); err != nil {
+		renderErr(w, err)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gjcourt/drift/internal/adapters/export"
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+)
+
+// uploadForm is the multipart body UploadCSV and ImportExperiment read.
+type uploadForm struct {
+	File string `json:"file" openapi:"format=binary"`
+}
+
+// Operations describes the UI routes for the OpenAPI document. Routes that
+// also negotiate JSON get their JSON responses from the API's description
+// when the server assembles the document. Keep it in step with the router; a
+// test in the http adapter fails if a route is missing from either.
+func Operations() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/", OperationID: "dashboard", Tags: tagUI,
+			Summary:   "Dashboard page",
+			Responses: []openapi.Response{page()},
+		},
+		{
+			Method: http.MethodGet, Path: "/data", OperationID: "dataManager", Tags: tagUI,
+			Summary:   "Data manager page listing stored assets",
+			Responses: []openapi.Response{page()},
+		},
+		{
+			Method: http.MethodPost, Path: "/data/upload", OperationID: "uploadCSV", Tags: tagUI,
+			Summary: "Upload a CSV of daily prices",
+			Request: []openapi.Body{{ContentType: openapi.Multipart, Type: uploadForm{}, Description: "A CSV file in the file field."}},
+			Responses: []openapi.Response{
+				{Status: http.StatusSeeOther, Description: "Uploaded; redirects to the data manager.", Headers: []string{"Location", "HX-Redirect"}},
+				text(http.StatusBadRequest, "The form has no file."),
+				text(http.StatusUnprocessableEntity, "The CSV could not be parsed or stored."),
+			},
+		},
+		{
+			Method: http.MethodDelete, Path: "/data/{symbol}", OperationID: "removeAsset", Tags: tagUI,
+			Summary: "Delete an asset and its price records",
+			Responses: []openapi.Response{
+				{Status: http.StatusNoContent},
+				text(http.StatusInternalServerError, "The asset could not be deleted."),
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments", OperationID: "experimentList", Tags: tagUI,
+			Summary:   "Experiment list page",
+			Responses: []openapi.Response{page()},
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments/new", OperationID: "newExperimentForm", Tags: tagUI,
+			Summary:   "New experiment form",
+			Responses: []openapi.Response{page()},
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments", OperationID: "submitExperiment", Tags: tagUI,
+			Summary: "Create an experiment from the new experiment form",
+			Request: []openapi.Body{{ContentType: openapi.Form, Description: "The fields of the new experiment form."}},
+			Responses: []openapi.Response{
+				{Status: http.StatusSeeOther, Description: "Created; redirects to the experiment, or to its run when run_now=1.", Headers: []string{"Location"}},
+				text(http.StatusBadRequest, "A field could not be parsed."),
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments/import", OperationID: "uploadExperiment", Tags: tagUI,
+			Summary: "Import an experiment from a JSON experiment config file",
+			Request: []openapi.Body{
+				{ContentType: openapi.Multipart, Type: uploadForm{}, Description: "A JSON experiment config file in the file field,"},
+				{ContentType: openapi.JSON, Description: "or the config as the raw body."},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusSeeOther, Description: "Imported; redirects to the experiment.", Headers: []string{"Location", "HX-Redirect"}},
+				{Status: http.StatusBadRequest, Description: "The form has no file.", Bodies: []openapi.Body{{ContentType: openapi.HTML}}},
+				{Status: http.StatusUnprocessableEntity, Description: "The config is invalid; the page or HTMX fragment lists every invalid field.", Bodies: []openapi.Body{{ContentType: openapi.HTML}}},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments/{id}/export.json", OperationID: "exportExperiment", Tags: tagUI,
+			Summary:     "Download an experiment as a JSON experiment config",
+			Description: "The config is in the format POST /experiments/import accepts, so it imports unchanged.",
+			Responses: []openapi.Response{
+				download(openapi.Body{ContentType: openapi.JSON, Ref: experimentConfigSchema}),
+				text(http.StatusNotFound, "No such experiment."),
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/experiments/{id}", OperationID: "experimentDetail", Tags: tagUI,
+			Summary:   "Experiment page with its runs",
+			Responses: []openapi.Response{page(), text(http.StatusNotFound, "No such experiment.")},
+		},
+		{
+			Method: http.MethodPost, Path: "/experiments/{id}/run", OperationID: "runExperiment", Tags: tagUI,
+			Summary: "Queue a run and redirect to it",
+			Responses: []openapi.Response{
+				{Status: http.StatusSeeOther, Description: "Queued; redirects to the run. An experiment with a run already queued or running queues nothing and redirects to that run.", Headers: []string{"Location"}},
+				text(http.StatusInternalServerError, "The run could not be queued."),
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/models", OperationID: "modelList", Tags: tagUI,
+			Summary:   "Page describing the simulation models and their parameters",
+			Responses: []openapi.Response{page()},
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}", OperationID: "runResults", Tags: tagUI,
+			Summary:   "Run results page",
+			Responses: []openapi.Response{page(), text(http.StatusNotFound, "No such run.")},
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}/export.json", OperationID: "exportRun", Tags: tagUI,
+			Summary:     "Download a run's results as JSON",
+			Description: "With include=paths the export also carries every stored path, one value per entry of days, streamed so large runs are not held in memory.",
+			Query:       []openapi.Param{{Name: "include", Description: "paths to add the stored paths."}},
+			Responses: []openapi.Response{
+				download(openapi.Body{ContentType: openapi.JSON, Type: export.Results{}}),
+				text(http.StatusBadRequest, "include is not paths."),
+				text(http.StatusNotFound, "No such run, or include=paths and the run has no stored paths."),
+				text(http.StatusConflict, "The run has no results yet."),
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}/paths.csv", OperationID: "exportRunPaths", Tags: tagUI,
+			Summary:     "Download a run's stored paths as CSV",
+			Description: "A header row of path and day_N columns, one per kept trading day, then one row per path.",
+			Responses: []openapi.Response{
+				download(openapi.Body{ContentType: openapi.CSV}),
+				text(http.StatusNotFound, "No such run, or the run has no stored paths."),
+				text(http.StatusConflict, "The run has no results yet."),
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/runs/{id}/events", OperationID: "runEvents", Tags: tagUI,
+			Summary:     "Stream a run's progress as Server-Sent Events",
+			Description: "Each event's data is a JSON progress update; the stream ends after the run reaches a terminal status.",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Bodies: []openapi.Body{{ContentType: openapi.EventStream, Type: progressEvent{}}}},
+				text(http.StatusNotFound, "No such run."),
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/runs/{id}/cancel", OperationID: "stopRun", Tags: tagUI,
+			Summary: "Cancel a run and redirect to it",
+			Responses: []openapi.Response{
+				{Status: http.StatusSeeOther, Description: "Cancelled; redirects to the run.", Headers: []string{"Location"}},
+				text(http.StatusNotFound, "No such run."),
+				text(http.StatusConflict, "The run has already finished."),
+			},
+		},
+	}
+}
+
+var tagUI = []string{"ui"}
+
+// experimentConfigSchema names the experiment config component the server
+// registers for the API (api.ExperimentConfigSchema); handlers cannot import
+// the api package, so the name is repeated here.
+const experimentConfigSchema = "ExperimentConfig"
+
+// page documents an HTML page response.
+func page() openapi.Response {
+	return openapi.Response{Status: http.StatusOK, Bodies: []openapi.Body{{ContentType: openapi.HTML}}}
+}
+
+// download documents a file download of body.
+func download(body openapi.Body) openapi.Response {
+	return openapi.Response{Status: http.StatusOK, Headers: []string{"Content-Disposition"}, Bodies: []openapi.Body{body}}
+}
+
+// text documents a plain-text error response, as written by http.Error.
+func text(status int, description string) openapi.Response {
+	return openapi.Response{Status: status, Description: description, Bodies: []openapi.Body{{ContentType: openapi.Text}}}
+}
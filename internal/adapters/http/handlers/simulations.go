@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/domain"
 )
 
-// RunExperiment triggers an async simulation run for the given experiment ID.
+// RunExperiment queues a simulation run for the given experiment ID and
+// redirects to its results page, which tracks the run until it finishes.
 func (h *H) RunExperiment(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	run, err := h.sim.RunExperiment(r.Context(), id)
+	run, err := h.sim.EnqueueRun(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -17,7 +24,8 @@ func (h *H) RunExperiment(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/runs/"+run.ID, http.StatusSeeOther)
 }
 
-// RunResults renders the results page for a completed simulation run.
+// RunResults renders the results page for a simulation run. Runs that are
+// still queued or running render a status page that refreshes itself.
 func (h *H) RunResults(w http.ResponseWriter, r *http.Request) {
 	runID := chi.URLParam(r, "id")
 	run, err := h.sim.GetRun(r.Context(), runID)
@@ -31,7 +39,104 @@ func (h *H) RunResults(w http.ResponseWriter, r *http.Request) {
 		"Run":        run,
 		"Experiment": exp,
 	}
+	if run.Status.Active() {
+		data["Refresh"] = 2
+	}
 	if err := h.page("results.html").ExecuteTemplate(w, "layout", data); err != nil {
 		renderErr(w, err)
 	}
 }
+
+// CancelRun stops a queued or running run and redirects back to its page.
+// Cancelling a run that has already finished is a 409.
+func (h *H) CancelRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+	if _, err := h.sim.GetRun(r.Context(), runID); err != nil {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	if err := h.sim.CancelRun(r.Context(), runID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrRunNotActive) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	http.Redirect(w, r, "/runs/"+runID, http.StatusSeeOther)
+}
+
+// sseKeepAlive is how often an idle event stream sends a comment line so
+// proxies do not close it.
+const sseKeepAlive = 15 * time.Second
+
+// RunEvents streams a run's progress as Server-Sent Events. Each update is a
+// "progress" event while the run is queued or running; the stream ends with a
+// single "complete", "failed" or "cancelled" event. Clients that cannot hold
+// the stream open fall back to polling /runs/{id}.
+func (h *H) RunEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events, err := h.sim.WatchRun(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case p, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeProgressEvent(w, p); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// progressEvent is the JSON payload of a run event.
+type progressEvent struct {
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+	Done      int    `json:"done"`
+	Total     int    `json:"total"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func writeProgressEvent(w http.ResponseWriter, p domain.RunProgress) error {
+	name := "progress"
+	if !p.Status.Active() {
+		name = string(p.Status)
+	}
+	data, err := json.Marshal(progressEvent{
+		RunID:     p.RunID,
+		Status:    string(p.Status),
+		Done:      p.Done,
+		Total:     p.Total,
+		ElapsedMS: p.Elapsed.Milliseconds(),
+		Error:     p.Error,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
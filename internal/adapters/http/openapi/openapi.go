@@ -0,0 +1,269 @@
+// Package openapi builds the OpenAPI 3 document for Drift's HTTP surface.
+//
+// Handlers describe their routes as [Route] values next to the code that
+// serves them; request and response bodies are given as Go values of the
+// types the handlers actually decode and encode, and [Builder] derives their
+// JSON schemas by reflection. Nothing here is hand-maintained JSON, so the
+// document changes when the handlers' types do.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the documents built here.
+const Version = "3.0.3"
+
+// Content types used by route descriptions.
+const (
+	JSON        = "application/json"
+	HTML        = "text/html"
+	Form        = "application/x-www-form-urlencoded"
+	Multipart   = "multipart/form-data"
+	EventStream = "text/event-stream"
+	Text        = "text/plain"
+	CSV         = "text/csv"
+)
+
+// Route documents one method and path. Path uses chi pattern syntax; its
+// {name} segments become required string path parameters and a trailing
+// "/*" becomes a {path} parameter.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Query       []Param
+	Request     []Body // one per accepted content type; empty for no body
+	Responses   []Response
+}
+
+// Param documents a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	Type        string // "string" or "integer"; empty means string
+	Format      string // e.g. "date"
+}
+
+// Body documents a request or response body. Type is a value of the Go type
+// encoded on the wire (a zero value is enough). Ref instead names a schema
+// registered with [Builder.Define]. With neither, the body has no fixed
+// schema, such as an HTML page.
+type Body struct {
+	ContentType string
+	Type        any
+	Ref         string
+	Description string
+}
+
+// Response documents one status code of a route. A response may carry more
+// than one body when the route negotiates its content type.
+type Response struct {
+	Status      int
+	Description string
+	Headers     []string // response headers worth documenting, e.g. "Location"
+	Bodies      []Body
+}
+
+// Document is an OpenAPI 3 document. Only the parts Drift uses are modelled.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info is the document's metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+// Operation is one method on one path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]RespSpec `json:"responses"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body.
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// RespSpec describes one response of an operation.
+type RespSpec struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header.
+type Header struct {
+	Schema *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds the named schemas referenced from operations.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Builder accumulates routes into a Document.
+type Builder struct {
+	doc     Document
+	schemas *schemaSet
+}
+
+// NewBuilder returns a Builder for a document with the given metadata.
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      map[string]PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		schemas: newSchemaSet(),
+	}
+}
+
+// Define registers the schema of v's type under name, for fields tagged
+// `openapi:"ref=<name>"` that carry pre-encoded JSON. It replaces any schema
+// already registered under name.
+func (b *Builder) Define(name string, v any) {
+	b.schemas.define(name, v)
+}
+
+// Add documents routes, each with prefix prepended to its path. It panics if
+// a method and path is documented twice, which is always a programming error.
+func (b *Builder) Add(prefix string, routes ...Route) {
+	for _, rt := range routes {
+		path := Path(prefix + rt.Path)
+		item := b.doc.Paths[path]
+		if item == nil {
+			item = PathItem{}
+			b.doc.Paths[path] = item
+		}
+		method := strings.ToLower(rt.Method)
+		if item[method] != nil {
+			panic(fmt.Sprintf("openapi: %s %s documented twice", rt.Method, path))
+		}
+		item[method] = b.operation(path, rt)
+	}
+}
+
+// Document returns the assembled document.
+func (b *Builder) Document() Document {
+	b.doc.Components.Schemas = b.schemas.components()
+	return b.doc
+}
+
+var pathParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// Path converts a chi route pattern to an OpenAPI path: trailing slashes are
+// dropped (chi serves both forms) and a trailing "/*" wildcard becomes {path}.
+func Path(pattern string) string {
+	if strings.HasSuffix(pattern, "/*") {
+		pattern = strings.TrimSuffix(pattern, "*") + "{path}"
+	}
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+func (b *Builder) operation(path string, rt Route) *Operation {
+	op := &Operation{
+		OperationID: rt.OperationID,
+		Summary:     rt.Summary,
+		Description: rt.Description,
+		Tags:        rt.Tags,
+		Responses:   map[string]RespSpec{},
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range rt.Query {
+		s := &Schema{Type: q.Type, Format: q.Format}
+		if s.Type == "" {
+			s.Type = "string"
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: q.Name, In: "query", Description: q.Description, Schema: s})
+	}
+	if len(rt.Request) > 0 {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+		var descs []string
+		for _, body := range rt.Request {
+			op.RequestBody.Content[body.ContentType] = MediaType{Schema: b.schemas.body(body)}
+			if body.Description != "" {
+				descs = append(descs, body.Description)
+			}
+		}
+		op.RequestBody.Description = strings.Join(descs, " ")
+	}
+	for _, resp := range rt.Responses {
+		spec := RespSpec{Description: resp.Description}
+		if spec.Description == "" {
+			spec.Description = http.StatusText(resp.Status)
+		}
+		for _, h := range resp.Headers {
+			if spec.Headers == nil {
+				spec.Headers = map[string]Header{}
+			}
+			spec.Headers[h] = Header{Schema: &Schema{Type: "string"}}
+		}
+		for _, body := range resp.Bodies {
+			if spec.Content == nil {
+				spec.Content = map[string]MediaType{}
+			}
+			spec.Content[body.ContentType] = MediaType{Schema: b.schemas.body(body)}
+		}
+		op.Responses[strconv.Itoa(resp.Status)] = spec
+	}
+	return op
+}
+
+// Operations lists the document's method and path pairs as "METHOD /path",
+// sorted; tests use it to compare the document with a router.
+func (d Document) Operations() []string {
+	var out []string
+	for path, item := range d.Paths {
+		for method := range item {
+			out = append(out, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+type itemJSON struct {
+	ID      string          `json:"id"`
+	State   string          `json:"state" openapi:"enum=open|shut"`
+	Count   int             `json:"count,omitempty"`
+	Closed  *time.Time      `json:"closed"`
+	Parent  *itemJSON       `json:"parent,omitempty"`
+	Payload json.RawMessage `json:"payload" openapi:"ref=Payload"`
+}
+
+func TestBuilderDerivesSchemas(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.Define("Payload", nil)
+	b.Add("/v1", Route{
+		Method: "GET", Path: "/items/{id}/", OperationID: "getItem",
+		Responses: []Response{{Status: 200, Bodies: []Body{{ContentType: JSON, Type: itemJSON{}}}}},
+	})
+	doc := b.Document()
+
+	if got := doc.Operations(); !slices.Equal(got, []string{"GET /v1/items/{id}"}) {
+		t.Fatalf("Operations = %v", got)
+	}
+	op := doc.Paths["/v1/items/{id}"]["get"]
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" {
+		t.Errorf("parameters = %+v, want the id path parameter", op.Parameters)
+	}
+	item := doc.Components.Schemas["Item"]
+	if item == nil {
+		t.Fatalf("components = %v, want Item", doc.Components.Schemas)
+	}
+	if want := []string{"id", "state", "closed", "payload"}; !slices.Equal(item.Required, want) {
+		t.Errorf("required = %v, want %v", item.Required, want)
+	}
+	if !item.Properties["closed"].Nullable || item.Properties["closed"].Format != "date-time" {
+		t.Errorf("closed = %+v, want a nullable date-time", item.Properties["closed"])
+	}
+	if item.Properties["parent"].Ref != refPrefix+"Item" {
+		t.Errorf("parent = %+v, want a reference to Item", item.Properties["parent"])
+	}
+
+	schema := doc.ResponseSchema("GET", "/v1/items/{id}", 200, JSON)
+	tests := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"id":"a","state":"open","closed":null,"payload":{"any":1}}`, true},
+		{`{"id":"a","state":"open","closed":null,"payload":{},"parent":{"id":"b","state":"shut","closed":"2024-01-02T00:00:00Z","payload":{}}}`, true},
+		{`{"id":"a","state":"ajar","closed":null,"payload":{}}`, false},
+		{`{"id":"a","state":"open","payload":{}}`, false},
+		{`{"id":"a","state":"open","closed":null,"payload":{},"count":1.5}`, false},
+		{`{"id":"a","state":"open","closed":null,"payload":{},"extra":true}`, false},
+	}
+	for _, tc := range tests {
+		if err := doc.Validate(schema, []byte(tc.body)); (err == nil) != tc.ok {
+			t.Errorf("Validate(%s) = %v, want ok %v", tc.body, err, tc.ok)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/":             "/",
+		"/data/":        "/data",
+		"/static/*":     "/static/{path}",
+		"/runs/{id}/":   "/runs/{id}",
+		"/api/v1/runs/": "/api/v1/runs",
+	} {
+		if got := Path(in); got != want {
+			t.Errorf("Path(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object Drift needs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaSet derives schemas from Go types, naming each struct type once as a
+// component.
+type schemaSet struct {
+	byName map[string]*Schema
+	names  map[reflect.Type]string
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{byName: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+func (s *schemaSet) components() map[string]*Schema { return s.byName }
+
+func (s *schemaSet) define(name string, v any) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		s.byName[name] = &Schema{Type: "object"}
+		return
+	}
+	s.names[t] = name
+	s.byName[name] = &Schema{} // placeholder for recursive types
+	s.byName[name] = s.structSchema(t)
+}
+
+// body returns the schema of b; nil documents an untyped body.
+func (s *schemaSet) body(b Body) *Schema {
+	switch {
+	case b.Ref != "":
+		return &Schema{Ref: refPrefix + b.Ref}
+	case b.Type == nil:
+		return nil
+	}
+	return s.typeSchema(reflect.TypeOf(b.Type))
+}
+
+func (s *schemaSet) typeSchema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.typeSchema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return &Schema{Ref: refPrefix + s.name(t)}
+	}
+	return &Schema{}
+}
+
+// name registers t as a component on first use. Response types are named
+// after their Go type with any "JSON" suffix dropped: runJSON becomes Run.
+func (s *schemaSet) name(t reflect.Type) string {
+	if n, ok := s.names[t]; ok {
+		return n
+	}
+	n := strings.TrimSuffix(t.Name(), "JSON")
+	if r := []rune(n); len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+		n = string(r)
+	}
+	s.names[t] = n
+	s.byName[n] = &Schema{} // placeholder for recursive types
+	s.byName[n] = s.structSchema(t)
+	return n
+}
+
+// structSchema maps a struct's exported fields by their json tags. Fields
+// without omitempty are required; pointers without it are nullable. An
+// `openapi:"ref=Name"` tag points a field at a named component and
+// `openapi:"enum=a|b"` lists its allowed values; `openapi:"format=binary"`
+// overrides the format.
+func (s *schemaSet) structSchema(t reflect.Type) *Schema {
+	out := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := s.typeSchema(f.Type)
+		for _, o := range strings.Split(f.Tag.Get("openapi"), ",") {
+			key, val, _ := strings.Cut(o, "=")
+			switch key {
+			case "ref":
+				fs = &Schema{Ref: refPrefix + val}
+			case "enum":
+				fs.Enum = strings.Split(val, "|")
+			case "format":
+				fs.Format = val
+			}
+		}
+		omit := strings.Contains(opts, "omitempty")
+		if f.Type.Kind() == reflect.Pointer && !omit && fs.Ref == "" {
+			fs.Nullable = true
+		}
+		if !omit {
+			out.Required = append(out.Required, name)
+		}
+		out.Properties[name] = fs
+	}
+	return out
+}
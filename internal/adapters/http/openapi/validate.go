@@ -0,0 +1,128 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ResponseSchema returns the schema documented for a response, or nil if the
+// document has none for that method, path, status and content type.
+func (d Document) ResponseSchema(method, path string, status int, contentType string) *Schema {
+	op := d.Paths[path][strings.ToLower(method)]
+	if op == nil {
+		return nil
+	}
+	resp, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		return nil
+	}
+	return resp.Content[contentType].Schema
+}
+
+// Validate checks that data, a JSON document, conforms to schema. Objects
+// that list properties are closed: a property the schema does not list is an
+// error, so a handler cannot start emitting a field the document does not
+// describe. An object schema listing nothing accepts any object.
+func (d Document) Validate(schema *Schema, data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return d.validate(schema, v, "$")
+}
+
+func (d Document) validate(s *Schema, v any, at string) error {
+	if s == nil {
+		return fmt.Errorf("%s: no schema", at)
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, refPrefix)
+		ref, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %q", at, name)
+		}
+		return d.validate(ref, v, at)
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not %s", at, s.Type)
+	}
+	switch s.Type {
+	case "":
+		return nil
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", at, v)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: want %s, got %T", at, s.Type, v)
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: want integer, got %v", at, n)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: want string, got %T", at, v)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %v", at, str, s.Enum)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", at, v)
+		}
+		for i, item := range items {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", at, v)
+		}
+		return d.validateObject(s, obj, at)
+	default:
+		return fmt.Errorf("%s: unsupported schema type %q", at, s.Type)
+	}
+	return nil
+}
+
+func (d Document) validateObject(s *Schema, obj map[string]any, at string) error {
+	if s.Properties == nil && s.AdditionalProperties == nil {
+		return nil
+	}
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", at, name)
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ps, ok := s.Properties[k]
+		if !ok {
+			ps = s.AdditionalProperties
+		}
+		if ps == nil {
+			return fmt.Errorf("%s: undocumented property %q", at, k)
+		}
+		if err := d.validate(ps, obj[k], at+"."+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
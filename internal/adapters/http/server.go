@@ -12,26 +12,35 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/gjcourt/drift/internal/adapters/http/api"
 	"github.com/gjcourt/drift/internal/adapters/http/handlers"
 	"github.com/gjcourt/drift/internal/domain"
 	"github.com/gjcourt/drift/internal/ports/inbound"
 )
 
 // New builds the main HTTP handler. tmplDir is the directory containing *.html
-// templates; staticDir is the directory served under /static/.
+// templates; staticDir is the directory served under /static/. The OpenAPI
+// document for every route is served at /openapi.json.
 func New(
 	ingest inbound.DataIngestionService,
 	results inbound.ResultsService,
 	sim inbound.SimulationService,
 	tmplDir string,
 	staticDir string,
+	opts ...Option,
 ) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	base, err := loadBase(tmplDir)
 	if err != nil {
 		slog.Error("load base template", "dir", tmplDir, "err", err)
 	}
 
 	h := handlers.New(ingest, results, sim, base, tmplDir)
+	// The JSON API shares the services above but none of the UI handlers.
+	jsonAPI := api.New(ingest, results, sim)
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -40,26 +49,44 @@ func New(
 	r.Get("/", h.Dashboard)
 
 	r.Route("/data", func(r chi.Router) {
-		r.Get("/", h.DataManager)
+		r.Get("/", negotiate(h.DataManager, jsonAPI.ListAssets))
 		r.Post("/upload", h.UploadCSV)
-		r.Delete("/{symbol}", h.DeleteAsset)
+		r.Delete("/{symbol}", negotiate(h.DeleteAsset, jsonAPI.DeleteAsset))
 	})
 
 	r.Route("/experiments", func(r chi.Router) {
-		r.Get("/", h.ListExperiments)
+		r.Get("/", negotiate(h.ListExperiments, jsonAPI.ListExperiments))
 		r.Get("/new", h.NewExperimentForm)
 		r.Post("/", h.CreateExperiment)
-		r.Get("/{id}", h.ExperimentDetail)
-		r.Post("/{id}/run", h.RunExperiment)
+		r.Post("/import", negotiate(h.ImportExperiment, jsonAPI.ImportExperiment))
+		r.Get("/{id}", negotiate(h.ExperimentDetail, jsonAPI.GetExperiment))
+		r.Get("/{id}/export.json", h.ExportExperiment)
+		r.Post("/{id}/run", negotiate(h.RunExperiment, jsonAPI.CreateRun))
 	})
 
-	r.Get("/runs/{id}", h.RunResults)
+	r.Get("/models", negotiate(h.Models, jsonAPI.ListModels))
+
+	r.Get("/runs/{id}", negotiate(h.RunResults, jsonAPI.GetRun))
+	r.Get("/runs/{id}/events", h.RunEvents)
+	r.Get("/runs/{id}/export.json", h.ExportRun)
+	r.Get("/runs/{id}/paths.csv", h.ExportRunPaths)
+	r.Post("/runs/{id}/cancel", negotiate(h.CancelRun, jsonAPI.CancelRun))
+
+	r.Mount("/api/v1", jsonAPI.Router())
+
+	spec := buildSpec(o)
+	r.Get("/openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
 
 	// Serve /static/ from a rooted fs.FS so requests cannot escape staticDir
 	// via traversal (e.g. ..%2F encodings); http.Dir alone permits any
 	// well-formed path under the host directory and offers no defence in
 	// depth if staticDir is misconfigured to a directory containing secrets.
-	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(os.DirFS(staticDir)))))
+	static := http.StripPrefix("/static/", http.FileServer(http.FS(os.DirFS(staticDir))))
+	r.Method(http.MethodGet, "/static/*", static)
+	r.Method(http.MethodHead, "/static/*", static)
 
 	return r
 }
@@ -76,6 +103,12 @@ func loadBase(dir string) (*template.Template, error) {
 			b, _ := json.Marshal(s)
 			return template.JS(b)
 		},
+		// bandsJSON serialises a run's percentile bands for the fan chart; nil
+		// bands become a JSON null.
+		"bandsJSON": func(b *domain.Bands) template.JS {
+			out, _ := json.Marshal(b)
+			return template.JS(out)
+		},
 	}
 	layoutFile := filepath.Join(dir, "layout.html")
 	return template.New("").Funcs(funcs).ParseFiles(layoutFile)
@@ -0,0 +1,161 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/ports/inbound"
+)
+
+// fakeSim knows no runs and one model; anything else it is asked panics through the nil
+// embedded interface.
+type fakeSim struct {
+	inbound.SimulationService
+}
+
+func (fakeSim) GetRun(context.Context, string) (*domain.Run, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (fakeSim) ListModels() []domain.ModelSpec {
+	return []domain.ModelSpec{{Name: domain.ModelGBM, Label: "GBM"}}
+}
+
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	return New(nil, nil, fakeSim{}, t.TempDir(), t.TempDir())
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	h := newTestServer(t)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /openapi.json = %d %q, want 200 JSON", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, openapi.Version)
+	}
+
+	var routes []string
+	err := chi.Walk(h.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+openapi.Path(route))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(routes)
+	routes = slices.Compact(routes)
+	documented := doc.Operations()
+	for _, r := range routes {
+		if !slices.Contains(documented, r) {
+			t.Errorf("route %s is not in the OpenAPI document", r)
+		}
+	}
+	for _, op := range documented {
+		if !slices.Contains(routes, op) {
+			t.Errorf("OpenAPI document describes %s, which is not routed", op)
+		}
+	}
+}
+
+// negotiates reports whether handler serves route through negotiate, which
+// marks every response Vary: Accept. Services the handler calls may be nil,
+// so a panic after that point is expected and ignored.
+func negotiates(method, route string, handler http.Handler) (ok bool) {
+	req := httptest.NewRequest(method, route, nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	defer func() {
+		_ = recover()
+		ok = strings.Contains(rec.Header().Get("Vary"), "Accept")
+	}()
+	handler.ServeHTTP(rec, req)
+	return false
+}
+
+func TestOpenAPIDocumentsNegotiatedJSON(t *testing.T) {
+	h := newTestServer(t)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	err := chi.Walk(h.(chi.Routes), func(method, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") || !negotiates(method, route, handler) {
+			return nil
+		}
+		path := openapi.Path(route)
+		if _, ok := negotiated[method+" "+path]; !ok {
+			t.Errorf("%s %s negotiates but is not in negotiated", method, path)
+		}
+		op := doc.Paths[path][strings.ToLower(method)]
+		if op == nil {
+			return nil // TestOpenAPIDocumentsEveryRoute reports it
+		}
+		for _, resp := range op.Responses {
+			if _, ok := resp.Content[openapi.JSON]; ok {
+				return nil
+			}
+		}
+		t.Errorf("%s %s negotiates but documents no JSON response", method, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNegotiatedRoutesAnswerInJSON(t *testing.T) {
+	h := newTestServer(t)
+	tests := []struct {
+		accept   string
+		wantJSON bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", false},
+		{"application/json", true},
+		{"text/html;q=0.5, application/json", true},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("GET", "/runs/missing", nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Accept %q: status = %d, want 404", tc.accept, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type") == "application/json"; got != tc.wantJSON {
+			t.Errorf("Accept %q: Content-Type = %q, want JSON %v", tc.accept, rec.Header().Get("Content-Type"), tc.wantJSON)
+		}
+		if !strings.Contains(rec.Header().Get("Vary"), "Accept") {
+			t.Errorf("Accept %q: Vary = %q, want Accept", tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestModelsAnswerInJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/models", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	newTestServer(t).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" || !strings.Contains(rec.Body.String(), `"gbm"`) {
+		t.Errorf("GET /models = %d %q %s, want the models as JSON", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
@@ -0,0 +1,150 @@
+package httpadapter
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gjcourt/drift/internal/adapters/http/api"
+	"github.com/gjcourt/drift/internal/adapters/http/handlers"
+	"github.com/gjcourt/drift/internal/adapters/http/openapi"
+)
+
+// Option configures the handler built by New.
+type Option func(*options)
+
+type options struct {
+	experimentConfig any
+}
+
+// WithExperimentConfigType documents experiment config bodies in the OpenAPI
+// document with the schema of v's type, the type the configured
+// outbound.ExperimentCodec reads and writes. Without it they are documented
+// as plain objects.
+func WithExperimentConfigType(v any) Option {
+	return func(o *options) { o.experimentConfig = v }
+}
+
+// negotiated maps UI routes that also answer in JSON to the API operation
+// whose handler serves the JSON form; the document gives such routes both
+// sets of responses. The router must wrap the same routes with negotiate.
+var negotiated = map[string]string{
+	"GET /data":                  "listAssets",
+	"DELETE /data/{symbol}":      "deleteAsset",
+	"GET /experiments":           "listExperiments",
+	"POST /experiments/import":   "importExperiment",
+	"GET /experiments/{id}":      "getExperiment",
+	"POST /experiments/{id}/run": "createRun",
+	"GET /models":                "listModels",
+	"GET /runs/{id}":             "getRun",
+	"POST /runs/{id}/cancel":     "cancelRun",
+}
+
+// negotiate serves html unless the request's Accept header prefers JSON to
+// HTML, in which case it serves json. Browsers and htmx never do, so only
+// API clients that ask get JSON.
+func negotiate(html, json http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if prefersJSON(r.Header.Get("Accept")) {
+			json(w, r)
+			return
+		}
+		html(w, r)
+	}
+}
+
+// prefersJSON reports whether accept gives application/json a higher
+// quality than text/html. Wildcards count towards both, so a tie, including
+// a missing header, goes to HTML.
+func prefersJSON(accept string) bool {
+	var qJSON, qHTML float64
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mt {
+		case "application/json", "application/*":
+			qJSON = max(qJSON, q)
+		case "text/html", "text/*":
+			qHTML = max(qHTML, q)
+		case "*/*":
+			qJSON, qHTML = max(qJSON, q), max(qHTML, q)
+		}
+	}
+	return qJSON > qHTML
+}
+
+// buildSpec assembles the OpenAPI document for every route New registers
+// and returns it encoded.
+func buildSpec(o options) []byte {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "Drift",
+		Version:     "1",
+		Description: "Monte Carlo portfolio simulation. The JSON API lives under /api/v1; the HTML UI routes listed under \"ui\" also answer in JSON when the Accept header prefers it.",
+	})
+	b.Define(api.ExperimentConfigSchema, o.experimentConfig)
+
+	apiRoutes := api.Operations()
+	byID := map[string]openapi.Route{}
+	for _, rt := range apiRoutes {
+		byID[rt.OperationID] = rt
+	}
+	b.Add("/api/v1", apiRoutes...)
+
+	ui := handlers.Operations()
+	for i, rt := range ui {
+		if id, ok := negotiated[rt.Method+" "+rt.Path]; ok {
+			ui[i] = withJSON(rt, byID[id])
+		}
+	}
+	b.Add("", ui...)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		b.Add("", openapi.Route{
+			Method: method, Path: "/static/*", OperationID: "static" + method[:1] + strings.ToLower(method[1:]), Tags: []string{"ui"},
+			Summary:   "Static assets for the UI",
+			Responses: []openapi.Response{{Status: http.StatusOK}, {Status: http.StatusNotFound}},
+		})
+	}
+	b.Add("", openapi.Route{
+		Method: http.MethodGet, Path: "/openapi.json", OperationID: "openapi", Tags: []string{"meta"},
+		Summary:   "This document",
+		Responses: []openapi.Response{{Status: http.StatusOK, Description: "An OpenAPI 3 document.", Bodies: []openapi.Body{{ContentType: openapi.JSON}}}},
+	})
+
+	out, err := json.MarshalIndent(b.Document(), "", "  ")
+	if err != nil {
+		panic("openapi: encode document: " + err.Error()) // only plain data types are encoded
+	}
+	return out
+}
+
+// withJSON adds the responses of the API route that serves rt's JSON form to
+// rt's own, merging bodies where both document a status.
+func withJSON(rt, json openapi.Route) openapi.Route {
+	rt.Description = strings.TrimSpace(rt.Description + " Send Accept: application/json for the " + json.OperationID + " response.")
+	rt.Responses = append([]openapi.Response(nil), rt.Responses...)
+	for _, jr := range json.Responses {
+		merged := false
+		for i := range rt.Responses {
+			if rt.Responses[i].Status == jr.Status {
+				rt.Responses[i].Bodies = append(rt.Responses[i].Bodies, jr.Bodies...)
+				merged = true
+			}
+		}
+		if !merged {
+			rt.Responses = append(rt.Responses, jr)
+		}
+	}
+	return rt
+}
@@ -0,0 +1,97 @@
+package ingestion
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// ParseAssumptionsCSV parses capital market assumptions: a header row
+// naming the columns symbol, expected_return and volatility, then one row
+// per asset. Returns and volatilities are annual decimals ("0.06") or
+// percentages ("6%"). Any further columns are headed by the assets' symbols
+// and hold their correlation matrix, in any column order; without them the
+// assumptions leave correlations to the lookback window. The result is not
+// validated.
+func ParseAssumptionsCSV(r io.Reader) (domain.CapitalMarketAssumptions, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err != nil {
+		return domain.CapitalMarketAssumptions{}, fmt.Errorf("read header: %w", err)
+	}
+	idx := buildIndex(headers)
+	for _, col := range []string{"symbol", "expected_return", "volatility"} {
+		if _, ok := idx[col]; !ok {
+			return domain.CapitalMarketAssumptions{}, fmt.Errorf("header: missing %s column", col)
+		}
+	}
+	corrCols := map[string]int{} // symbol → column of its correlations
+	for i, h := range headers {
+		switch name := strings.ToLower(strings.TrimSpace(h)); name {
+		case "symbol", "expected_return", "volatility":
+		default:
+			corrCols[strings.ToUpper(strings.TrimSpace(h))] = i
+		}
+	}
+
+	var a domain.CapitalMarketAssumptions
+	var rows [][]string
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return domain.CapitalMarketAssumptions{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		aa := domain.AssetAssumption{Symbol: strings.ToUpper(strings.TrimSpace(row[idx["symbol"]]))}
+		if aa.ExpectedReturn, err = parseRate(row[idx["expected_return"]]); err != nil {
+			return domain.CapitalMarketAssumptions{}, fmt.Errorf("line %d: expected_return: %w", line, err)
+		}
+		if aa.Volatility, err = parseRate(row[idx["volatility"]]); err != nil {
+			return domain.CapitalMarketAssumptions{}, fmt.Errorf("line %d: volatility: %w", line, err)
+		}
+		a.Assets = append(a.Assets, aa)
+		rows = append(rows, row)
+	}
+	if len(corrCols) == 0 {
+		return a, nil
+	}
+
+	a.Correlations = make([][]float64, len(a.Assets))
+	for i, row := range rows {
+		a.Correlations[i] = make([]float64, len(a.Assets))
+		for j, other := range a.Assets {
+			col, ok := corrCols[other.Symbol]
+			if !ok {
+				return domain.CapitalMarketAssumptions{}, fmt.Errorf("header: missing correlation column for %s", other.Symbol)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(row[col]), 64)
+			if err != nil {
+				return domain.CapitalMarketAssumptions{}, fmt.Errorf("line %d: correlation with %s: %w", i+2, other.Symbol, err)
+			}
+			a.Correlations[i][j] = v
+		}
+	}
+	if len(corrCols) != len(a.Assets) {
+		return domain.CapitalMarketAssumptions{}, fmt.Errorf("header: %d correlation columns for %d assets", len(corrCols), len(a.Assets))
+	}
+	return a, nil
+}
+
+// parseRate parses a decimal, or a percentage with a trailing "%".
+func parseRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	pct, isPct := strings.CutSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+	if isPct {
+		v /= 100
+	}
+	return v, err
+}
@@ -0,0 +1,49 @@
+package ingestion
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func TestParseAssumptionsCSV(t *testing.T) {
+	got, err := ParseAssumptionsCSV(strings.NewReader(`Symbol, Expected_Return, Volatility, agg, spy
+spy, 6%, 0.16, 0.1, 1
+AGG, 0.035, 5%, 1, 0.1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := domain.CapitalMarketAssumptions{
+		Assets: []domain.AssetAssumption{
+			{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16},
+			{Symbol: "AGG", ExpectedReturn: 0.035, Volatility: 0.05},
+		},
+		Correlations: [][]float64{{1, 0.1}, {0.1, 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	got, err = ParseAssumptionsCSV(strings.NewReader("symbol,expected_return,volatility\nSPY,0.06,0.16\n"))
+	if err != nil || len(got.Assets) != 1 || got.Correlations != nil {
+		t.Errorf("without correlations: got %+v, %v", got, err)
+	}
+}
+
+func TestParseAssumptionsCSVErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing column":      "symbol,expected_return\nSPY,0.06\n",
+		"bad number":          "symbol,expected_return,volatility\nSPY,six,0.16\n",
+		"missing correlation": "symbol,expected_return,volatility,SPY\nSPY,0.06,0.16,1\nAGG,0.035,0.05,0.1\n",
+		"extra correlation":   "symbol,expected_return,volatility,SPY,GLD\nSPY,0.06,0.16,1,0\n",
+		"bad correlation":     "symbol,expected_return,volatility,SPY\nSPY,0.06,0.16,one\n",
+	}
+	for name, in := range tests {
+		if _, err := ParseAssumptionsCSV(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: ParseAssumptionsCSV = nil error", name)
+		}
+	}
+}
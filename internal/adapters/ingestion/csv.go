@@ -1,5 +1,6 @@
-// Package ingestion parses external data formats (CSV price files and JSON
-// experiment configs) into domain objects.
+// Package ingestion parses external data formats (CSV price files, CSV
+// capital market assumptions and JSON experiment configs) into domain
+// objects.
 package ingestion
 
 import (
@@ -2,12 +2,20 @@ package ingestion
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gjcourt/drift/internal/domain"
 )
 
+// ExperimentConfigVersion is the schema version written by EncodeExperimentJSON.
+const ExperimentConfigVersion = "1"
+
 // ExperimentConfig is the JSON schema for staging an experiment programmatically.
 type ExperimentConfig struct {
 	Version    string       `json:"version"`
@@ -43,29 +51,128 @@ type SimCfg struct {
 	LookbackDays int     `json:"lookback_days"`
 	StartValue   float64 `json:"start_value"`
 	Seed         *int64  `json:"seed"`
+	AsOf         string  `json:"as_of"`
+
+	RepairCovariance bool            `json:"repair_covariance"`
+	Assumptions      *AssumptionsCfg `json:"assumptions"`
+	BlockLength      int             `json:"block_length"`
+	BlockScheme      string          `json:"block_scheme"`
+	SkewedT          bool            `json:"skewed_t"`
+	GJR              bool            `json:"gjr"`
+
+	MeanEstimator string `json:"mean_estimator"`
+	CovEstimator  string `json:"cov_estimator"`
+	EWMAHalfLife  int    `json:"ewma_half_life"`
+
+	JumpIntensity float64 `json:"jump_intensity"`
+	JumpMean      float64 `json:"jump_mean"`
+	JumpVol       float64 `json:"jump_vol"`
+
+	RegimeStates       int  `json:"regime_states"`
+	RegimeStartCurrent bool `json:"regime_start_current"`
+
+	HestonKappa float64 `json:"heston_kappa"`
+	HestonTheta float64 `json:"heston_theta"`
+	HestonXi    float64 `json:"heston_xi"`
+	HestonRho   float64 `json:"heston_rho"`
+
+	StorePaths    bool   `json:"store_paths"`
+	PathPrecision string `json:"path_precision"`
+	PathEvery     int    `json:"path_every"`
+
+	Quantiles []float64 `json:"quantiles"`
+
+	KeepPartial bool `json:"keep_partial"`
+}
+
+// AssumptionsCfg holds capital market assumptions in a JSON experiment
+// config: annual figures as decimals, and optionally the correlation matrix
+// of the assets in the order listed.
+type AssumptionsCfg struct {
+	Assets       []AssetAssumptionCfg `json:"assets"`
+	Correlations [][]float64          `json:"correlations"`
+}
+
+// AssetAssumptionCfg is one asset's expected return and volatility in a JSON
+// experiment config.
+type AssetAssumptionCfg struct {
+	Symbol         string  `json:"symbol"`
+	ExpectedReturn float64 `json:"expected_return"`
+	Volatility     float64 `json:"volatility"`
+}
+
+func (a *AssumptionsCfg) assumptions() *domain.CapitalMarketAssumptions {
+	if a == nil {
+		return nil
+	}
+	out := &domain.CapitalMarketAssumptions{Correlations: a.Correlations}
+	for _, aa := range a.Assets {
+		out.Assets = append(out.Assets, domain.AssetAssumption(aa))
+	}
+	return out
+}
+
+func newAssumptionsCfg(a *domain.CapitalMarketAssumptions) *AssumptionsCfg {
+	if a == nil {
+		return nil
+	}
+	out := &AssumptionsCfg{Assets: []AssetAssumptionCfg{}, Correlations: a.Correlations}
+	for _, aa := range a.Assets {
+		out.Assets = append(out.Assets, AssetAssumptionCfg(aa))
+	}
+	return out
 }
 
 // ParamCfg holds optional cash-flow parameters in a JSON experiment config.
 type ParamCfg struct {
 	AnnualContribution float64  `json:"annual_contribution"`
+	AnnualWithdrawal   float64  `json:"annual_withdrawal"`
 	WithdrawalRate     *float64 `json:"withdrawal_rate"`
+	Frequency          string   `json:"frequency"`
+	InflationRate      float64  `json:"inflation_rate"`
 }
 
 // ParseExperimentJSON parses a JSON experiment config into domain objects.
+// Parsing is strict: the version must be ExperimentConfigVersion, fields the
+// schema does not define are rejected, and the portfolio and simulation
+// settings are validated. Every problem found is reported together as a
+// domain.FieldErrors naming fields by their JSON path; input that is not a
+// single JSON object fails with a plain error.
 func ParseExperimentJSON(r io.Reader) (domain.Experiment, error) {
-	var cfg ExperimentConfig
-	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return domain.Experiment{}, fmt.Errorf("read experiment JSON: %w", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
 		return domain.Experiment{}, fmt.Errorf("decode experiment JSON: %w", err)
 	}
+	errs := unknownFields(raw, reflect.TypeOf(ExperimentConfig{}), "")
+	var cfg ExperimentConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		var te *json.UnmarshalTypeError
+		if !errors.As(err, &te) {
+			return domain.Experiment{}, fmt.Errorf("decode experiment JSON: %w", err)
+		}
+		return domain.Experiment{}, append(errs, domain.FieldError{Field: te.Field, Message: "must be " + jsonKind(te.Type)})
+	}
+
+	switch cfg.Version {
+	case ExperimentConfigVersion:
+	case "":
+		errs = append(errs, domain.FieldError{Field: "version", Message: "is required"})
+	default:
+		errs = append(errs, domain.FieldError{Field: "version", Message: fmt.Sprintf("unsupported version %q, want %q", cfg.Version, ExperimentConfigVersion)})
+	}
 
 	assets := make([]domain.PortfolioAsset, len(cfg.Portfolio.Assets))
 	for i, a := range cfg.Portfolio.Assets {
 		assets[i] = domain.PortfolioAsset{Symbol: a.Symbol, Weight: a.Weight}
 	}
 
-	rebalance := domain.RebalanceFrequency(cfg.Portfolio.Rebalance)
-	if rebalance == "" {
-		rebalance = domain.RebalanceNone
+	rebalance, ok := domain.ParseRebalanceFrequency(cfg.Portfolio.Rebalance)
+	if !ok {
+		errs = append(errs, domain.FieldError{Field: "portfolio.rebalance", Message: fmt.Sprintf("unknown frequency %q", cfg.Portfolio.Rebalance)})
 	}
 
 	model := domain.SimulationModel(cfg.Simulation.Model)
@@ -73,12 +180,22 @@ func ParseExperimentJSON(r io.Reader) (domain.Experiment, error) {
 		model = domain.ModelGBM
 	}
 
+	var asOf *time.Time
+	if cfg.Simulation.AsOf != "" {
+		t, err := time.Parse("2006-01-02", cfg.Simulation.AsOf)
+		if err != nil {
+			errs = append(errs, domain.FieldError{Field: "simulation.as_of", Message: "must be a YYYY-MM-DD date"})
+		} else {
+			asOf = &t
+		}
+	}
+
 	withdrawalRate := 0.0
 	if cfg.Parameters.WithdrawalRate != nil {
 		withdrawalRate = *cfg.Parameters.WithdrawalRate
 	}
 
-	return domain.Experiment{
+	exp := domain.Experiment{
 		Name:        cfg.Experiment.Name,
 		Description: cfg.Experiment.Description,
 		Portfolio: domain.Portfolio{
@@ -86,14 +203,201 @@ func ParseExperimentJSON(r io.Reader) (domain.Experiment, error) {
 			Rebalance: rebalance,
 		},
 		Config: domain.SimulationConfig{
-			Model:              model,
-			NumPaths:           cfg.Simulation.NumPaths,
-			HorizonDays:        cfg.Simulation.HorizonDays,
-			LookbackDays:       cfg.Simulation.LookbackDays,
-			StartValue:         cfg.Simulation.StartValue,
-			Seed:               cfg.Simulation.Seed,
+			Model:            model,
+			NumPaths:         cfg.Simulation.NumPaths,
+			HorizonDays:      cfg.Simulation.HorizonDays,
+			LookbackDays:     cfg.Simulation.LookbackDays,
+			StartValue:       cfg.Simulation.StartValue,
+			Seed:             cfg.Simulation.Seed,
+			AsOf:             asOf,
+			RepairCovariance: cfg.Simulation.RepairCovariance,
+			Assumptions:      cfg.Simulation.Assumptions.assumptions(),
+			Estimator: domain.EstimatorParams{
+				Mean:     domain.MeanEstimator(cfg.Simulation.MeanEstimator),
+				Cov:      domain.CovEstimator(cfg.Simulation.CovEstimator),
+				HalfLife: cfg.Simulation.EWMAHalfLife,
+			},
+			Block: domain.BlockParams{
+				Length: cfg.Simulation.BlockLength,
+				Scheme: domain.BlockScheme(cfg.Simulation.BlockScheme),
+			},
+			StudentT: domain.StudentTParams{Skewed: cfg.Simulation.SkewedT},
+			GARCH:    domain.GARCHParams{GJR: cfg.Simulation.GJR},
+			Jump: domain.JumpParams{
+				Intensity: cfg.Simulation.JumpIntensity,
+				Mean:      cfg.Simulation.JumpMean,
+				Vol:       cfg.Simulation.JumpVol,
+			},
+			Regime: domain.RegimeParams{
+				States:       cfg.Simulation.RegimeStates,
+				StartCurrent: cfg.Simulation.RegimeStartCurrent,
+			},
+			Heston: domain.HestonParams{
+				Kappa: cfg.Simulation.HestonKappa,
+				Theta: cfg.Simulation.HestonTheta,
+				Xi:    cfg.Simulation.HestonXi,
+				Rho:   cfg.Simulation.HestonRho,
+			},
+			StorePaths:         cfg.Simulation.StorePaths,
+			PathPrecision:      domain.PathPrecision(cfg.Simulation.PathPrecision),
+			PathEvery:          cfg.Simulation.PathEvery,
+			Quantiles:          cfg.Simulation.Quantiles,
+			KeepPartial:        cfg.Simulation.KeepPartial,
 			AnnualContribution: cfg.Parameters.AnnualContribution,
+			AnnualWithdrawal:   cfg.Parameters.AnnualWithdrawal,
 			WithdrawalRate:     withdrawalRate,
+			CashFlowFrequency:  domain.CashFlowFrequency(cfg.Parameters.Frequency),
+			InflationRate:      cfg.Parameters.InflationRate,
 		},
-	}, nil
+	}
+	errs = append(errs, exp.Portfolio.ValidateFields().Prefix("portfolio")...)
+	for _, fe := range append(exp.Config.ValidateFields(), exp.Config.ValidateAssumptions(exp.Portfolio)...) {
+		errs = append(errs, domain.FieldError{Field: configPath(fe.Field), Message: fe.Message})
+	}
+	if len(errs) > 0 {
+		return domain.Experiment{}, errs
+	}
+	return exp, nil
+}
+
+// configPath maps a domain.SimulationConfig field name onto its JSON path.
+func configPath(field string) string {
+	switch field {
+	case "annual_withdrawal", "withdrawal_rate", "inflation_rate":
+		return "parameters." + field
+	case "cash_flow_frequency":
+		return "parameters.frequency"
+	}
+	return "simulation." + field
+}
+
+// unknownFields returns an error for every key of v, at any depth, that the
+// json tags of t do not define. prefix is v's own JSON path.
+func unknownFields(v any, t reflect.Type, prefix string) domain.FieldErrors {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var errs domain.FieldErrors
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil // a type mismatch, reported when decoding
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields[name] = t.Field(i).Type
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				errs = append(errs, domain.FieldError{Field: path, Message: "unknown field"})
+				continue
+			}
+			errs = append(errs, unknownFields(obj[k], ft, path)...)
+		}
+	case reflect.Slice:
+		items, _ := v.([]any)
+		for i, item := range items {
+			errs = append(errs, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i))...)
+		}
+	}
+	return errs
+}
+
+// jsonKind describes the JSON value expected for a Go type.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array"
+	}
+	return "an object"
+}
+
+// EncodeExperimentJSON renders exp in the ExperimentConfig format that
+// ParseExperimentJSON reads, so a config round-trips through the two.
+func EncodeExperimentJSON(exp domain.Experiment) ([]byte, error) {
+	return json.Marshal(NewExperimentConfig(exp))
+}
+
+// NewExperimentConfig maps exp onto the JSON experiment config schema.
+func NewExperimentConfig(exp domain.Experiment) ExperimentConfig {
+	assets := make([]AssetCfg, len(exp.Portfolio.Assets))
+	for i, a := range exp.Portfolio.Assets {
+		assets[i] = AssetCfg{Symbol: a.Symbol, Weight: a.Weight}
+	}
+	c := exp.Config
+	var asOf string
+	if c.AsOf != nil {
+		asOf = c.AsOf.Format("2006-01-02")
+	}
+	var withdrawalRate *float64
+	if c.WithdrawalRate != 0 {
+		withdrawalRate = &c.WithdrawalRate
+	}
+	return ExperimentConfig{
+		Version:    ExperimentConfigVersion,
+		Experiment: ExpMeta{Name: exp.Name, Description: exp.Description},
+		Portfolio: PortfolioCfg{
+			Assets:    assets,
+			Rebalance: string(exp.Portfolio.Rebalance),
+		},
+		Simulation: SimCfg{
+			Model:              string(c.Model),
+			NumPaths:           c.NumPaths,
+			HorizonDays:        c.HorizonDays,
+			LookbackDays:       c.LookbackDays,
+			StartValue:         c.StartValue,
+			Seed:               c.Seed,
+			AsOf:               asOf,
+			RepairCovariance:   c.RepairCovariance,
+			Assumptions:        newAssumptionsCfg(c.Assumptions),
+			BlockLength:        c.Block.Length,
+			BlockScheme:        string(c.Block.Scheme),
+			SkewedT:            c.StudentT.Skewed,
+			GJR:                c.GARCH.GJR,
+			MeanEstimator:      string(c.Estimator.Mean),
+			CovEstimator:       string(c.Estimator.Cov),
+			EWMAHalfLife:       c.Estimator.HalfLife,
+			JumpIntensity:      c.Jump.Intensity,
+			JumpMean:           c.Jump.Mean,
+			JumpVol:            c.Jump.Vol,
+			RegimeStates:       c.Regime.States,
+			RegimeStartCurrent: c.Regime.StartCurrent,
+			HestonKappa:        c.Heston.Kappa,
+			HestonTheta:        c.Heston.Theta,
+			HestonXi:           c.Heston.Xi,
+			HestonRho:          c.Heston.Rho,
+			StorePaths:         c.StorePaths,
+			PathPrecision:      string(c.PathPrecision),
+			PathEvery:          c.PathEvery,
+			Quantiles:          c.Quantiles,
+			KeepPartial:        c.KeepPartial,
+		},
+		Parameters: ParamCfg{
+			AnnualContribution: c.AnnualContribution,
+			AnnualWithdrawal:   c.AnnualWithdrawal,
+			WithdrawalRate:     withdrawalRate,
+			Frequency:          string(c.CashFlowFrequency),
+			InflationRate:      c.InflationRate,
+		},
+	}
 }
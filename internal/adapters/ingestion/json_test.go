@@ -0,0 +1,158 @@
+package ingestion
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func experimentJSON(rebalance string) string {
+	return `{
+  "version": "1",
+  "experiment": {"name": "60/40"},
+  "portfolio": {
+    "assets": [{"symbol": "SPY", "weight": 0.6}, {"symbol": "AGG", "weight": 0.4}],
+    "rebalance": "` + rebalance + `"
+  },
+  "simulation": {"model": "gbm", "num_paths": 100, "horizon_days": 252, "lookback_days": 756, "start_value": 100000}
+}`
+}
+
+func TestParseExperimentJSONRebalance(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    domain.RebalanceFrequency
+		wantErr bool
+	}{
+		{"", domain.RebalanceNone, false},
+		{"daily", domain.RebalanceDaily, false},
+		{"monthly", domain.RebalanceMonthly, false},
+		{"yearly", domain.RebalanceAnnual, false},
+		{"annual", domain.RebalanceAnnual, false},
+		{"fortnightly", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			exp, err := ParseExperimentJSON(strings.NewReader(experimentJSON(tc.in)))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exp.Portfolio.Rebalance != tc.want {
+				t.Errorf("Rebalance = %q, want %q", exp.Portfolio.Rebalance, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncodeExperimentJSONRoundTrips(t *testing.T) {
+	seed := int64(42)
+	asOf := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	want := domain.Experiment{
+		Name:        "glide",
+		Description: "retirement drawdown",
+		Portfolio: domain.Portfolio{
+			Assets:    []domain.PortfolioAsset{{Symbol: "SPY", Weight: 0.6}, {Symbol: "AGG", Weight: 0.4}},
+			Rebalance: domain.RebalanceQuarterly,
+		},
+		Config: domain.SimulationConfig{
+			Model:        domain.ModelBlockBootstrap,
+			NumPaths:     500,
+			HorizonDays:  2520,
+			LookbackDays: 756,
+			StartValue:   1_000_000,
+			Seed:         &seed,
+			AsOf:         &asOf,
+			Block:        domain.BlockParams{Length: 21, Scheme: domain.BlockStationary},
+			Assumptions: &domain.CapitalMarketAssumptions{
+				Assets: []domain.AssetAssumption{
+					{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16},
+					{Symbol: "AGG", ExpectedReturn: 0.035, Volatility: 0.05},
+				},
+				Correlations: [][]float64{{1, 0.1}, {0.1, 1}},
+			},
+			Estimator:         domain.EstimatorParams{Mean: domain.MeanBayesStein, Cov: domain.CovEWMA, HalfLife: 126},
+			StorePaths:        true,
+			PathPrecision:     domain.PrecisionFloat32,
+			PathEvery:         5,
+			Quantiles:         []float64{0.1, 0.5, 0.9},
+			KeepPartial:       true,
+			WithdrawalRate:    0.04,
+			CashFlowFrequency: domain.CashFlowMonthly,
+			InflationRate:     0.025,
+		},
+	}
+	data, err := EncodeExperimentJSON(want)
+	if err != nil {
+		t.Fatalf("EncodeExperimentJSON: %v", err)
+	}
+	got, err := ParseExperimentJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseExperimentJSON(%s): %v", data, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the experiment:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseExperimentJSONReportsEveryField(t *testing.T) {
+	_, err := ParseExperimentJSON(strings.NewReader(`{
+  "version": "2",
+  "experiment": {"name": "typo", "colour": "red"},
+  "portfolio": {
+    "assets": [{"symbol": "SPY", "weight": 0.6}, {"symbol": "AGG", "weight": 0.3, "note": ""}],
+    "rebalance": "fortnightly"
+  },
+  "simulation": {"num_paths": 0, "horizon_days": 252, "lookback_days": 756, "start_value": 1, "as_of": "June"},
+  "parameters": {"frequency": "weekly"}
+}`))
+	var fields domain.FieldErrors
+	if !errors.As(err, &fields) || !errors.Is(err, domain.ErrInvalid) {
+		t.Fatalf("err = %v, want FieldErrors wrapping ErrInvalid", err)
+	}
+	want := []string{
+		"experiment.colour",
+		"portfolio.assets[1].note",
+		"version",
+		"portfolio.rebalance",
+		"simulation.as_of",
+		"portfolio.assets",
+		"simulation.num_paths",
+		"parameters.frequency",
+	}
+	var got []string
+	for _, fe := range fields {
+		got = append(got, fe.Field)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v\nwant %v", got, want)
+	}
+
+	_, err = ParseExperimentJSON(strings.NewReader(`{"version": "1", "simulation": {"num_paths": "many"}}`))
+	if !errors.As(err, &fields) || len(fields) != 1 || fields[0].Field != "simulation.num_paths" {
+		t.Errorf("wrong type: err = %v, want a FieldError for simulation.num_paths", err)
+	}
+	_, err = ParseExperimentJSON(strings.NewReader(`{"version": "1",
+  "portfolio": {"assets": [{"symbol": "SPY", "weight": 1}]},
+  "simulation": {"num_paths": 1, "horizon_days": 1, "lookback_days": 1, "start_value": 1,
+    "assumptions": {"assets": [{"symbol": "AGG", "expected_return": 0.03, "volatility": 0}]}}}`))
+	if !errors.As(err, &fields) || len(fields) != 2 ||
+		fields[0].Field != "simulation.assumptions.assets[0].volatility" || fields[1].Field != "simulation.assumptions.assets" {
+		t.Errorf("bad assumptions: err = %v, want the volatility and the uncovered SPY reported", err)
+	}
+	for _, in := range []string{`{"version": "1"`, `[]`, `{"version": "1"} {}`} {
+		if _, err := ParseExperimentJSON(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseExperimentJSON(%s) = nil error, want a decode error", in)
+		}
+	}
+}
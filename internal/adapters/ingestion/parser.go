@@ -6,10 +6,27 @@ import (
 	"github.com/gjcourt/drift/internal/domain"
 )
 
-// Parser implements outbound.CSVParser backed by ParseCSV.
+// Parser implements outbound.CSVParser and outbound.ExperimentCodec backed by
+// the package-level parse and encode functions.
 type Parser struct{}
 
 // ParseCSV parses a CSV price-data stream via the ingestion adapter.
 func (Parser) ParseCSV(r io.Reader, filename string) ([]domain.PriceRecord, error) {
 	return ParseCSV(r, filename)
 }
+
+// ParseAssumptionsCSV parses capital market assumptions via the ingestion
+// adapter.
+func (Parser) ParseAssumptionsCSV(r io.Reader) (domain.CapitalMarketAssumptions, error) {
+	return ParseAssumptionsCSV(r)
+}
+
+// ParseExperimentJSON parses a JSON experiment config via the ingestion adapter.
+func (Parser) ParseExperimentJSON(r io.Reader) (domain.Experiment, error) {
+	return ParseExperimentJSON(r)
+}
+
+// EncodeExperimentJSON renders an experiment as a JSON experiment config.
+func (Parser) EncodeExperimentJSON(exp domain.Experiment) ([]byte, error) {
+	return EncodeExperimentJSON(exp)
+}
@@ -0,0 +1,418 @@
+// Package memory implements the outbound repository interfaces in process
+// memory. Nothing outlives the Store: it backs one-shot commands that must
+// not touch a database, and service tests that want real repository
+// behaviour without SQLite. It follows the SQLite store's semantics,
+// including ordering, cascading deletes and stored path precision.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Store implements AssetRepository, ExperimentRepository,
+// SimulationRepository and PathRepository over maps guarded by one mutex.
+// The zero value is not usable; call New.
+type Store struct {
+	mu          sync.Mutex
+	assets      map[string]domain.Asset
+	records     map[string][]domain.PriceRecord // by symbol, ascending by date
+	experiments map[string]domain.Experiment
+	runs        []domain.Run // insertion order breaks queued_at ties, like SQLite's rowid
+	paths       map[string]domain.PathSet
+	cancels     map[string]bool // running runs RequestCancel has flagged
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		assets:      map[string]domain.Asset{},
+		records:     map[string][]domain.PriceRecord{},
+		experiments: map[string]domain.Experiment{},
+		paths:       map[string]domain.PathSet{},
+		cancels:     map[string]bool{},
+	}
+}
+
+// ──────────────────── AssetRepository ────────────────────────────────────────
+
+// UpsertAsset inserts or updates a single asset by symbol.
+func (s *Store) UpsertAsset(_ context.Context, a domain.Asset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.assets[a.Symbol]; ok {
+		a.ID = old.ID
+	} else if a.ID == "" {
+		a.ID = a.Symbol
+	}
+	s.assets[a.Symbol] = a
+	return nil
+}
+
+// GetAsset returns the asset with the given symbol, or an error wrapping
+// domain.ErrNotFound if there is none.
+func (s *Store) GetAsset(_ context.Context, symbol string) (*domain.Asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.assets[symbol]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &a, nil
+}
+
+// ListAssets returns all stored assets ordered by symbol.
+func (s *Store) ListAssets(_ context.Context) ([]domain.Asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []domain.Asset
+	for _, a := range s.assets {
+		out = append(out, a)
+	}
+	slices.SortFunc(out, func(a, b domain.Asset) int { return cmp.Compare(a.Symbol, b.Symbol) })
+	return out, nil
+}
+
+// DeleteAsset removes an asset and all of its price records.
+func (s *Store) DeleteAsset(_ context.Context, symbol string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.assets, symbol)
+	delete(s.records, symbol)
+	return nil
+}
+
+// UpsertPriceRecords stores records, replacing any with the same symbol and date.
+func (s *Store) UpsertPriceRecords(_ context.Context, records []domain.PriceRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range records {
+		recs := s.records[rec.Symbol]
+		i := sort.Search(len(recs), func(i int) bool { return !recs[i].Date.Before(rec.Date) })
+		if i < len(recs) && recs[i].Date.Equal(rec.Date) {
+			recs[i] = rec
+		} else {
+			recs = slices.Insert(recs, i, rec)
+		}
+		s.records[rec.Symbol] = recs
+	}
+	return nil
+}
+
+// GetPriceRecords returns up to limit records for symbol in ascending date
+// order; limit <= 0 returns them all.
+func (s *Store) GetPriceRecords(_ context.Context, symbol string, limit int) ([]domain.PriceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recs := s.records[symbol]
+	if limit > 0 && limit < len(recs) {
+		recs = recs[:limit]
+	}
+	return slices.Clone(recs), nil
+}
+
+// GetPriceRecordsBetween returns records with from <= date <= to in ascending
+// date order. A zero from or to leaves that end of the range open.
+func (s *Store) GetPriceRecordsBetween(_ context.Context, symbol string, from, to time.Time) ([]domain.PriceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(between(s.records[symbol], from, to)), nil
+}
+
+// GetRecentPriceRecords returns the last n records on or before asOf, in
+// ascending date order. A zero asOf means the latest available record.
+func (s *Store) GetRecentPriceRecords(_ context.Context, symbol string, n int, asOf time.Time) ([]domain.PriceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recs := between(s.records[symbol], time.Time{}, asOf)
+	if len(recs) > n {
+		recs = recs[len(recs)-n:]
+	}
+	return slices.Clone(recs), nil
+}
+
+// between returns the window of the date-ordered recs within [from, to].
+func between(recs []domain.PriceRecord, from, to time.Time) []domain.PriceRecord {
+	lo := 0
+	if !from.IsZero() {
+		lo = sort.Search(len(recs), func(i int) bool { return !recs[i].Date.Before(from) })
+	}
+	hi := len(recs)
+	if !to.IsZero() {
+		hi = sort.Search(len(recs), func(i int) bool { return recs[i].Date.After(to) })
+	}
+	if lo >= hi {
+		return nil
+	}
+	return recs[lo:hi]
+}
+
+// ──────────────────── ExperimentRepository ───────────────────────────────────
+
+// SaveExperiment inserts or replaces an experiment by ID.
+func (s *Store) SaveExperiment(_ context.Context, exp domain.Experiment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.experiments[exp.ID] = exp
+	return nil
+}
+
+// GetExperiment returns the experiment with the given ID, or an error
+// wrapping domain.ErrNotFound if there is none.
+func (s *Store) GetExperiment(_ context.Context, id string) (*domain.Experiment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.experiments[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &exp, nil
+}
+
+// ListExperiments returns all experiments, most recently created first.
+func (s *Store) ListExperiments(_ context.Context) ([]domain.Experiment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []domain.Experiment
+	for _, e := range s.experiments {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b domain.Experiment) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return out, nil
+}
+
+// DeleteExperiment removes an experiment together with its runs and their
+// results.
+func (s *Store) DeleteExperiment(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.experiments, id)
+	s.runs = slices.DeleteFunc(s.runs, func(r domain.Run) bool {
+		if r.ExperimentID != id {
+			return false
+		}
+		delete(s.paths, r.ID)
+		return true
+	})
+	return nil
+}
+
+// ──────────────────── SimulationRepository ───────────────────────────────────
+
+// SaveRun inserts or updates a simulation run (upsert by ID). As in SQLite,
+// an update keeps the run's QueuedAt, and keeps its bands unless run carries
+// new ones.
+func (s *Store) SaveRun(_ context.Context, run domain.Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(run.ID); i >= 0 {
+		run.QueuedAt = s.runs[i].QueuedAt
+		if run.Bands == nil {
+			run.Bands = s.runs[i].Bands
+		}
+		s.runs[i] = run
+		return nil
+	}
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+// GetRun returns the run with the given ID, including its percentile bands
+// when they were recorded. A missing run is an error wrapping
+// domain.ErrNotFound.
+func (s *Store) GetRun(_ context.Context, runID string) (*domain.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(runID)
+	if i < 0 {
+		return nil, domain.ErrNotFound
+	}
+	run := s.runs[i]
+	return &run, nil
+}
+
+// ListRuns returns all runs for the given experiment, most recent first.
+// Like SQLite's ListRuns it leaves out the bands.
+func (s *Store) ListRuns(_ context.Context, experimentID string) ([]domain.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []domain.Run
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if s.runs[i].ExperimentID == experimentID {
+			run := s.runs[i]
+			run.Bands = nil
+			out = append(out, run)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b domain.Run) int { return b.QueuedAt.Compare(a.QueuedAt) })
+	return out, nil
+}
+
+// UpdateRunProgress sets the number of paths a run has simulated so far and
+// reports whether RequestCancel has asked it to stop.
+func (s *Store) UpdateRunProgress(_ context.Context, runID string, done int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(runID); i >= 0 {
+		s.runs[i].ProgressDone = done
+	}
+	return s.cancels[runID], nil
+}
+
+// RequestCancel flags runID for cancellation if it is running and reports
+// whether it was.
+func (s *Store) RequestCancel(_ context.Context, runID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(runID)
+	if i < 0 || s.runs[i].Status != domain.StatusRunning {
+		return false, nil
+	}
+	s.cancels[runID] = true
+	return true, nil
+}
+
+// ClaimNextRun moves the oldest queued run to running and returns it, or nil
+// when the queue is empty.
+func (s *Store) ClaimNextRun(_ context.Context, claimedAt time.Time) (*domain.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := -1
+	for i, r := range s.runs {
+		if r.Status == domain.StatusQueued && (next < 0 || r.QueuedAt.Before(s.runs[next].QueuedAt)) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil, nil
+	}
+	s.runs[next].Status = domain.StatusRunning
+	s.runs[next].StartedAt = claimedAt
+	run := s.runs[next]
+	return &run, nil
+}
+
+// ClaimRun moves runID to running and returns it, or nil when it is no longer
+// queued.
+func (s *Store) ClaimRun(_ context.Context, runID string, claimedAt time.Time) (*domain.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(runID)
+	if i < 0 || s.runs[i].Status != domain.StatusQueued {
+		return nil, nil
+	}
+	s.runs[i].Status = domain.StatusRunning
+	s.runs[i].StartedAt = claimedAt
+	run := s.runs[i]
+	return &run, nil
+}
+
+// CancelQueuedRun marks the run cancelled if it is still queued and reports
+// whether it did.
+func (s *Store) CancelQueuedRun(_ context.Context, runID string, finishedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(runID)
+	if i < 0 || s.runs[i].Status != domain.StatusQueued {
+		return false, nil
+	}
+	s.runs[i].Status = domain.StatusCancelled
+	s.runs[i].FinishedAt = &finishedAt
+	return true, nil
+}
+
+// ReapRunning marks every running run as failed with reason and returns how
+// many it touched.
+func (s *Store) ReapRunning(_ context.Context, finishedAt time.Time, reason string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i := range s.runs {
+		if s.runs[i].Status == domain.StatusRunning {
+			s.runs[i].Status = domain.StatusFailed
+			s.runs[i].Error = reason
+			s.runs[i].FinishedAt = &finishedAt
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteRun removes a run together with its bands and stored paths.
+func (s *Store) DeleteRun(_ context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(runID); i >= 0 {
+		s.runs = slices.Delete(s.runs, i, i+1)
+	}
+	delete(s.paths, runID)
+	delete(s.cancels, runID)
+	return nil
+}
+
+// find returns the index of the run with runID, or -1. Callers hold s.mu.
+func (s *Store) find(runID string) int {
+	return slices.IndexFunc(s.runs, func(r domain.Run) bool { return r.ID == runID })
+}
+
+// ──────────────────── PathRepository ─────────────────────────────────────────
+
+// SavePaths stores a run's paths, replacing any stored before. Float32 sets
+// are rounded to float32 on the way in, so reads return what SQLite would.
+func (s *Store) SavePaths(_ context.Context, set domain.PathSet) error {
+	stored := domain.PathSet{
+		RunID:     set.RunID,
+		Total:     len(set.Paths),
+		Days:      slices.Clone(set.Days),
+		Precision: set.Precision,
+		Paths:     make([]domain.SimulatedPath, len(set.Paths)),
+	}
+	for i, p := range set.Paths {
+		if len(p.Values) != len(set.Days) {
+			return fmt.Errorf("path %d has %d values, want %d", i, len(p.Values), len(set.Days))
+		}
+		vals := slices.Clone(p.Values)
+		if set.Precision == domain.PrecisionFloat32 {
+			for j, v := range vals {
+				vals[j] = float64(float32(v))
+			}
+		}
+		stored.Paths[i] = domain.SimulatedPath{Values: vals, NetCashFlow: p.NetCashFlow}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths[set.RunID] = stored
+	return nil
+}
+
+// GetPaths returns up to limit stored paths for runID starting at offset.
+// A limit <= 0 returns every path from offset onward. A run without stored
+// paths is an error wrapping domain.ErrNotFound.
+func (s *Store) GetPaths(_ context.Context, runID string, offset, limit int) (*domain.PathSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.paths[runID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	set := stored
+	set.Offset = offset
+	set.Paths = nil
+	if offset < stored.Total {
+		end := stored.Total
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		set.Paths = stored.Paths[max(offset, 0):end]
+	}
+	return &set, nil
+}
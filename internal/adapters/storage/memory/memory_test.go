@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+var t0 = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+func TestPriceRecordRanges(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	// Inserted out of order, with day 2 written twice.
+	for _, d := range []int{3, 1, 2, 0, 2} {
+		rec := domain.PriceRecord{Symbol: "SPY", Date: t0.AddDate(0, 0, d), AdjustedClose: float64(100 + d)}
+		if err := s.UpsertPriceRecords(ctx, []domain.PriceRecord{rec}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, _ := s.GetPriceRecords(ctx, "SPY", 0)
+	if len(all) != 4 || !all[0].Date.Equal(t0) || !all[3].Date.Equal(t0.AddDate(0, 0, 3)) {
+		t.Fatalf("GetPriceRecords = %+v, want 4 records in date order", all)
+	}
+	mid, _ := s.GetPriceRecordsBetween(ctx, "SPY", t0.AddDate(0, 0, 1), t0.AddDate(0, 0, 2))
+	if len(mid) != 2 || mid[0].AdjustedClose != 101 {
+		t.Errorf("GetPriceRecordsBetween = %+v, want days 1 and 2", mid)
+	}
+	recent, _ := s.GetRecentPriceRecords(ctx, "SPY", 2, t0.AddDate(0, 0, 2))
+	if len(recent) != 2 || recent[1].AdjustedClose != 102 {
+		t.Errorf("GetRecentPriceRecords = %+v, want days 1 and 2", recent)
+	}
+	recent[0].AdjustedClose = 0
+	if again, _ := s.GetPriceRecords(ctx, "SPY", 2); again[1].AdjustedClose != 101 {
+		t.Error("returned records alias the store")
+	}
+}
+
+func TestRunQueue(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	for _, r := range []domain.Run{
+		{ID: "busy", QueuedAt: t0, Status: domain.StatusRunning},
+		{ID: "second", QueuedAt: t0.Add(2 * time.Minute), Status: domain.StatusQueued},
+		{ID: "first", QueuedAt: t0.Add(time.Minute), Status: domain.StatusQueued},
+	} {
+		r.ExperimentID = "exp"
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, _ := s.ListRuns(ctx, "exp")
+	if len(list) != 3 || list[0].ID != "second" || list[2].ID != "busy" {
+		t.Errorf("ListRuns = %+v, want most recently queued first", list)
+	}
+	if ok, _ := s.RequestCancel(ctx, "first"); ok {
+		t.Error("RequestCancel flagged a queued run")
+	}
+	if ok, _ := s.RequestCancel(ctx, "busy"); !ok {
+		t.Error("RequestCancel did not flag the running run")
+	}
+	if requested, _ := s.UpdateRunProgress(ctx, "busy", 1); !requested {
+		t.Error("UpdateRunProgress did not report the cancel request")
+	}
+	if got, err := s.ClaimRun(ctx, "busy", t0); got != nil || err != nil {
+		t.Errorf("ClaimRun of a running run = %+v, %v", got, err)
+	}
+	for _, want := range []string{"first", "second"} {
+		got, err := s.ClaimNextRun(ctx, t0.Add(time.Hour))
+		if err != nil || got == nil || got.ID != want || got.Status != domain.StatusRunning {
+			t.Fatalf("ClaimNextRun = %+v, %v; want %s running", got, err, want)
+		}
+	}
+	if got, err := s.ClaimNextRun(ctx, t0); got != nil || err != nil {
+		t.Errorf("ClaimNextRun on an empty queue = %+v, %v", got, err)
+	}
+	if n, _ := s.ReapRunning(ctx, t0, domain.RunInterrupted); n != 3 {
+		t.Errorf("ReapRunning touched %d runs, want 3", n)
+	}
+}
+
+func TestSaveRunKeepsQueuedAtAndBands(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	run := domain.Run{ID: "run", ExperimentID: "exp", QueuedAt: t0, Status: domain.StatusComplete, Bands: &domain.Bands{Days: []int{0, 1}}}
+	_ = s.SaveRun(ctx, run)
+
+	run.QueuedAt, run.Bands, run.Error = t0.Add(time.Hour), nil, "noted"
+	_ = s.SaveRun(ctx, run)
+	got, err := s.GetRun(ctx, "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.QueuedAt.Equal(t0) || got.Bands == nil || got.Error != "noted" {
+		t.Errorf("run = %+v, want the original QueuedAt and bands with the new error", got)
+	}
+	if list, _ := s.ListRuns(ctx, "exp"); len(list) != 1 || list[0].Bands != nil {
+		t.Errorf("ListRuns = %+v, want the run without bands", list)
+	}
+}
+
+func TestDeletesCascade(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	_ = s.SaveExperiment(ctx, domain.Experiment{ID: "exp"})
+	for _, id := range []string{"a", "b"} {
+		_ = s.SaveRun(ctx, domain.Run{ID: id, ExperimentID: "exp"})
+		_ = s.SavePaths(ctx, domain.NewPathSet(id, []domain.SimulatedPath{{Values: []float64{1, 2}}}, 1, 1, domain.PrecisionFloat64))
+	}
+
+	_ = s.DeleteRun(ctx, "a")
+	if _, err := s.GetPaths(ctx, "a", 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("paths of a deleted run: err = %v, want ErrNotFound", err)
+	}
+	_ = s.DeleteExperiment(ctx, "exp")
+	if _, err := s.GetRun(ctx, "b"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("run of a deleted experiment: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetPaths(ctx, "b", 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("paths of a deleted experiment's run: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPathsPagingAndPrecision(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	paths := make([]domain.SimulatedPath, 5)
+	for i := range paths {
+		paths[i] = domain.SimulatedPath{Values: []float64{100, 100.1 + float64(i)}, NetCashFlow: float64(i)}
+	}
+	if err := s.SavePaths(ctx, domain.NewPathSet("run", paths, 1, 1, domain.PrecisionFloat32)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetPaths(ctx, "run", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Total != 5 || got.Offset != 3 || len(got.Paths) != 2 {
+		t.Fatalf("got total=%d offset=%d len=%d, want 5/3/2", got.Total, got.Offset, len(got.Paths))
+	}
+	if v := got.Paths[0].Values[1]; v != float64(float32(103.1)) {
+		t.Errorf("float32 value = %v, want it stored at float32 precision", v)
+	}
+	if f := got.Paths[0].NetCashFlow; f != 3 {
+		t.Errorf("NetCashFlow = %v, want 3", f)
+	}
+	if page, _ := s.GetPaths(ctx, "run", 1, 2); len(page.Paths) != 2 || page.Paths[1].Values[1] != float64(float32(102.1)) {
+		t.Errorf("page = %+v, want paths 1 and 2", page.Paths)
+	}
+	bad := domain.PathSet{RunID: "run", Days: []int{0, 1}, Paths: []domain.SimulatedPath{{Values: []float64{1}}}}
+	if s.SavePaths(ctx, bad) == nil {
+		t.Error("SavePaths accepted a path with the wrong number of values")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,8 +15,8 @@ import (
 	"github.com/gjcourt/drift/internal/domain"
 )
 
-// Store implements AssetRepository, SimulationRepository, and ExperimentRepository
-// using a single SQLite database.
+// Store implements AssetRepository, SimulationRepository, ExperimentRepository
+// and PathRepository using a single SQLite database.
 type Store struct {
 	db *sql.DB
 }
@@ -35,10 +36,109 @@ func New(path string) (*Store, error) {
 }
 
 func (s *Store) migrate() error {
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	// Databases created before runs became a queue, or before model fits or
+	// inputs were recorded, lack these columns.
+	if err := s.addColumns("runs", runQueueColumns); err != nil {
+		return err
+	}
+	if err := s.addColumns("runs", runFitColumns); err != nil {
+		return err
+	}
+	if err := s.addColumns("runs", runInputsColumns); err != nil {
+		return err
+	}
+	if err := s.addColumns("runs", runCancelColumns); err != nil {
+		return err
+	}
+	if err := s.addColumns("run_path_chunks", pathChunkFlowColumns); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(runIndexes)
 	return err
 }
 
+// addColumns adds each named column to table unless it already exists.
+// SQLite has no ADD COLUMN IF NOT EXISTS, so existing columns are read first.
+func (s *Store) addColumns(table string, cols [][2]string) error {
+	have, err := s.columns(table)
+	if err != nil {
+		return err
+	}
+	for _, c := range cols {
+		if have[c[0]] {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + c[0] + ` ` + c[1]); err != nil {
+			return fmt.Errorf("add %s.%s: %w", table, c[0], err)
+		}
+		if table == "runs" && c[0] == "queued_at" {
+			// Rows written before the queue existed were never queued; treat
+			// their start as their enqueue time so listings keep their order.
+			if _, err := s.db.Exec(`UPDATE runs SET queued_at=started_at WHERE queued_at=''`); err != nil {
+				return fmt.Errorf("backfill runs.queued_at: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// columns returns the set of column names of table.
+func (s *Store) columns(table string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck // rows.Close in defer; final error captured by rows.Err()
+	have := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		have[name] = true
+	}
+	return have, rows.Err()
+}
+
+// runQueueColumns are the runs columns added for the background run queue.
+var runQueueColumns = [][2]string{
+	{"queued_at", "TEXT NOT NULL DEFAULT ''"},
+	{"progress_done", "INTEGER NOT NULL DEFAULT 0"},
+	{"progress_total", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// runFitColumns are the runs columns added to record what a model fitted.
+var runFitColumns = [][2]string{
+	{"fit", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// runInputsColumns are the runs columns added to record the moments a model
+// was calibrated to.
+var runInputsColumns = [][2]string{
+	{"inputs", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// runCancelColumns are the runs columns added so a run executing in another
+// process can be cancelled.
+var runCancelColumns = [][2]string{
+	{"cancel_requested", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// pathChunkFlowColumns are the run_path_chunks columns added to keep each
+// path's net cash flow. Chunks written before have none; their paths read
+// back with a zero flow.
+var pathChunkFlowColumns = [][2]string{
+	{"flows", "BLOB"},
+}
+
+const runIndexes = `
+CREATE INDEX IF NOT EXISTS idx_runs_status_queued_at ON runs(status, queued_at);
+CREATE INDEX IF NOT EXISTS idx_runs_experiment_id    ON runs(experiment_id, queued_at DESC);
+`
+
 const schema = `
 PRAGMA journal_mode=WAL;
 
@@ -71,13 +171,41 @@ CREATE TABLE IF NOT EXISTS experiments (
 );
 
 CREATE TABLE IF NOT EXISTS runs (
-	id            TEXT PRIMARY KEY,
-	experiment_id TEXT NOT NULL,
-	started_at    TEXT NOT NULL,
-	finished_at   TEXT,
-	status        TEXT NOT NULL,
-	error         TEXT NOT NULL DEFAULT '',
-	stats         TEXT NOT NULL DEFAULT '{}'
+	id               TEXT PRIMARY KEY,
+	experiment_id    TEXT NOT NULL,
+	queued_at        TEXT NOT NULL DEFAULT '',
+	started_at       TEXT NOT NULL,
+	finished_at      TEXT,
+	status           TEXT NOT NULL,
+	error            TEXT NOT NULL DEFAULT '',
+	stats            TEXT NOT NULL DEFAULT '{}',
+	progress_done    INTEGER NOT NULL DEFAULT 0,
+	progress_total   INTEGER NOT NULL DEFAULT 0,
+	fit              TEXT NOT NULL DEFAULT '',
+	inputs           TEXT NOT NULL DEFAULT '',
+	cancel_requested INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS run_bands (
+	run_id TEXT PRIMARY KEY,
+	bands  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS run_paths (
+	run_id      TEXT PRIMARY KEY,
+	num_paths   INTEGER NOT NULL,
+	horizon     INTEGER NOT NULL,
+	every       INTEGER NOT NULL,
+	precision   TEXT NOT NULL,
+	chunk_paths INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS run_path_chunks (
+	run_id TEXT NOT NULL,
+	chunk  INTEGER NOT NULL,
+	data   BLOB NOT NULL,
+	flows  BLOB,
+	PRIMARY KEY (run_id, chunk)
 );
 `
 
@@ -94,12 +222,13 @@ func (s *Store) UpsertAsset(ctx context.Context, a domain.Asset) error {
 	return err
 }
 
-// GetAsset returns the asset with the given symbol, or an error if not found.
+// GetAsset returns the asset with the given symbol, or an error wrapping
+// domain.ErrNotFound if there is none.
 func (s *Store) GetAsset(ctx context.Context, symbol string) (*domain.Asset, error) {
 	row := s.db.QueryRowContext(ctx, `SELECT id, symbol, name FROM assets WHERE symbol=?`, symbol)
 	var a domain.Asset
 	if err := row.Scan(&a.ID, &a.Symbol, &a.Name); err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &a, nil
 }
@@ -175,6 +304,43 @@ func (s *Store) GetPriceRecords(ctx context.Context, symbol string, limit int) (
 		q += " LIMIT ?"
 		args = append(args, limit)
 	}
+	return s.queryPriceRecords(ctx, q, args...)
+}
+
+// GetPriceRecordsBetween returns the records for symbol dated within [from, to]
+// in ascending date order. A zero from or to leaves that end open.
+func (s *Store) GetPriceRecordsBetween(ctx context.Context, symbol string, from, to time.Time) ([]domain.PriceRecord, error) {
+	q := `SELECT symbol,date,open,high,low,close,volume,adjusted_close
+	      FROM price_records WHERE symbol=?`
+	args := []any{symbol}
+	if !from.IsZero() {
+		q += " AND date >= ?"
+		args = append(args, from.Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		q += " AND date <= ?"
+		args = append(args, to.Format("2006-01-02"))
+	}
+	q += " ORDER BY date ASC"
+	return s.queryPriceRecords(ctx, q, args...)
+}
+
+// GetRecentPriceRecords returns the n most recent records for symbol dated on
+// or before asOf (the latest records when asOf is zero), in ascending date order.
+func (s *Store) GetRecentPriceRecords(ctx context.Context, symbol string, n int, asOf time.Time) ([]domain.PriceRecord, error) {
+	inner := `SELECT symbol,date,open,high,low,close,volume,adjusted_close
+	          FROM price_records WHERE symbol=?`
+	args := []any{symbol}
+	if !asOf.IsZero() {
+		inner += " AND date <= ?"
+		args = append(args, asOf.Format("2006-01-02"))
+	}
+	inner += " ORDER BY date DESC LIMIT ?"
+	args = append(args, n)
+	return s.queryPriceRecords(ctx, `SELECT * FROM (`+inner+`) ORDER BY date ASC`, args...)
+}
+
+func (s *Store) queryPriceRecords(ctx context.Context, q string, args ...any) ([]domain.PriceRecord, error) {
 	rows, err := s.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
@@ -209,11 +375,16 @@ func (s *Store) SaveExperiment(ctx context.Context, exp domain.Experiment) error
 	return err
 }
 
-// GetExperiment returns the experiment with the given ID.
+// GetExperiment returns the experiment with the given ID, or an error wrapping
+// domain.ErrNotFound if there is none.
 func (s *Store) GetExperiment(ctx context.Context, id string) (*domain.Experiment, error) {
 	row := s.db.QueryRowContext(ctx,
 		`SELECT id,name,description,portfolio,config,created_at,updated_at FROM experiments WHERE id=?`, id)
-	return scanExperiment(row)
+	e, err := scanExperiment(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return e, nil
 }
 
 // ListExperiments returns all experiments ordered by creation date (descending).
@@ -245,12 +416,31 @@ func (s *Store) DeleteExperiment(ctx context.Context, id string) error {
 	if _, err := tx.ExecContext(ctx, `DELETE FROM experiments WHERE id=?`, id); err != nil {
 		return fmt.Errorf("delete experiment: %w", err)
 	}
+	for _, table := range []string{"run_paths", "run_path_chunks"} {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE run_id IN (SELECT id FROM runs WHERE experiment_id=?)`, id); err != nil {
+			return fmt.Errorf("delete run paths: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM run_bands WHERE run_id IN (SELECT id FROM runs WHERE experiment_id=?)`, id); err != nil {
+		return fmt.Errorf("delete run bands: %w", err)
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE experiment_id=?`, id); err != nil {
 		return fmt.Errorf("delete runs: %w", err)
 	}
 	return tx.Commit()
 }
 
+// notFound translates sql.ErrNoRows into domain.ErrNotFound so callers outside
+// this adapter can recognise a missing record without importing database/sql.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
@@ -273,33 +463,75 @@ func scanExperiment(row scanner) (*domain.Experiment, error) {
 // SaveRun inserts or updates a simulation run record (upsert by ID).
 func (s *Store) SaveRun(ctx context.Context, run domain.Run) error {
 	statsJSON, _ := json.Marshal(run.Stats)
+	var fitJSON, inputsJSON []byte
+	var err error
+	// A fit or inputs with a non-finite number cannot be encoded; saving
+	// them empty would hide the bad fit.
+	if run.Fit != nil {
+		if fitJSON, err = json.Marshal(run.Fit); err != nil {
+			return fmt.Errorf("encode fit: %w", err)
+		}
+	}
+	if run.Inputs != nil {
+		if inputsJSON, err = json.Marshal(run.Inputs); err != nil {
+			return fmt.Errorf("encode inputs: %w", err)
+		}
+	}
 	var finishedAt *string
 	if run.FinishedAt != nil {
 		str := run.FinishedAt.Format(time.RFC3339)
 		finishedAt = &str
 	}
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO runs (id,experiment_id,started_at,finished_at,status,error,stats)
-		 VALUES (?,?,?,?,?,?,?)
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO runs (id,experiment_id,queued_at,started_at,finished_at,status,error,stats,progress_done,progress_total,fit,inputs)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?)
 		 ON CONFLICT(id) DO UPDATE SET
-		   finished_at=excluded.finished_at, status=excluded.status,
-		   error=excluded.error, stats=excluded.stats`,
-		run.ID, run.ExperimentID, run.StartedAt.Format(time.RFC3339),
-		finishedAt, string(run.Status), run.Error, string(statsJSON))
+		   started_at=excluded.started_at, finished_at=excluded.finished_at, status=excluded.status,
+		   error=excluded.error, stats=excluded.stats,
+		   progress_done=excluded.progress_done, progress_total=excluded.progress_total, fit=excluded.fit,
+		   inputs=excluded.inputs`,
+		run.ID, run.ExperimentID, formatTime(run.QueuedAt), formatTime(run.StartedAt),
+		finishedAt, string(run.Status), run.Error, string(statsJSON), run.ProgressDone, run.ProgressTotal, string(fitJSON),
+		string(inputsJSON))
+	if err != nil || run.Bands == nil {
+		return err
+	}
+	// Bands live in their own table so ListRuns never pays for them.
+	bandsJSON, _ := json.Marshal(run.Bands)
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO run_bands (run_id,bands) VALUES (?,?)
+		 ON CONFLICT(run_id) DO UPDATE SET bands=excluded.bands`,
+		run.ID, string(bandsJSON))
 	return err
 }
 
-// GetRun returns the simulation run with the given ID.
+// GetRun returns the simulation run with the given ID, including its
+// percentile bands when they were recorded. A missing run is an error
+// wrapping domain.ErrNotFound.
 func (s *Store) GetRun(ctx context.Context, runID string) (*domain.Run, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id,experiment_id,started_at,finished_at,status,error,stats FROM runs WHERE id=?`, runID)
-	return scanRun(row)
+		`SELECT `+runColumns+` FROM runs WHERE id=?`, runID)
+	r, err := scanRun(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var bandsJSON string
+	err = s.db.QueryRowContext(ctx, `SELECT bands FROM run_bands WHERE run_id=?`, runID).Scan(&bandsJSON)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("load bands: %w", err)
+	default:
+		r.Bands = &domain.Bands{}
+		_ = json.Unmarshal([]byte(bandsJSON), r.Bands)
+	}
+	return r, nil
 }
 
 // ListRuns returns all runs for the given experiment, most recent first.
 func (s *Store) ListRuns(ctx context.Context, experimentID string) ([]domain.Run, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id,experiment_id,started_at,finished_at,status,error,stats FROM runs WHERE experiment_id=? ORDER BY started_at DESC`,
+		`SELECT `+runColumns+` FROM runs WHERE experiment_id=? ORDER BY queued_at DESC, rowid DESC`,
 		experimentID)
 	if err != nil {
 		return nil, err
@@ -316,19 +548,143 @@ func (s *Store) ListRuns(ctx context.Context, experimentID string) ([]domain.Run
 	return runs, rows.Err()
 }
 
+// UpdateRunProgress sets the number of paths a run has simulated so far and
+// reports whether RequestCancel has asked it to stop.
+func (s *Store) UpdateRunProgress(ctx context.Context, runID string, done int) (bool, error) {
+	var requested bool
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE runs SET progress_done=? WHERE id=? RETURNING cancel_requested`, done, runID).Scan(&requested)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return requested, err
+}
+
+// RequestCancel flags runID for cancellation if it is running and reports
+// whether it was.
+func (s *Store) RequestCancel(ctx context.Context, runID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE runs SET cancel_requested=1 WHERE id=? AND status=?`, runID, string(domain.StatusRunning))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ClaimNextRun moves the oldest queued run to running and returns it, or nil
+// when the queue is empty. The select and update happen in one statement, so
+// two claimers can never both win the same row.
+func (s *Store) ClaimNextRun(ctx context.Context, claimedAt time.Time) (*domain.Run, error) {
+	row := s.db.QueryRowContext(ctx,
+		`UPDATE runs SET status=?, started_at=?
+		 WHERE id=(SELECT id FROM runs WHERE status=? ORDER BY queued_at, rowid LIMIT 1) AND status=?
+		 RETURNING `+runColumns,
+		string(domain.StatusRunning), formatTime(claimedAt), string(domain.StatusQueued), string(domain.StatusQueued))
+	r, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ClaimRun moves runID to running and returns it, or nil when it is no longer
+// queued. Like ClaimNextRun it is one statement, so it never races another
+// claimer.
+func (s *Store) ClaimRun(ctx context.Context, runID string, claimedAt time.Time) (*domain.Run, error) {
+	row := s.db.QueryRowContext(ctx,
+		`UPDATE runs SET status=?, started_at=? WHERE id=? AND status=? RETURNING `+runColumns,
+		string(domain.StatusRunning), formatTime(claimedAt), runID, string(domain.StatusQueued))
+	r, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// CancelQueuedRun cancels runID only while it is still queued, so it never
+// races a runner that has just claimed the row.
+func (s *Store) CancelQueuedRun(ctx context.Context, runID string, finishedAt time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE runs SET status=?, finished_at=? WHERE id=? AND status=?`,
+		string(domain.StatusCancelled), formatTime(finishedAt), runID, string(domain.StatusQueued))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReapRunning fails every run still marked running with reason as its error.
+func (s *Store) ReapRunning(ctx context.Context, finishedAt time.Time, reason string) (int, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE runs SET status=?, error=?, finished_at=? WHERE status=?`,
+		string(domain.StatusFailed), reason, formatTime(finishedAt), string(domain.StatusRunning))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeleteRun removes a run with its bands and stored paths atomically.
+func (s *Store) DeleteRun(ctx context.Context, runID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // Rollback is a no-op after Commit; error is intentionally ignored
+	if err := deletePaths(ctx, tx, runID); err != nil {
+		return fmt.Errorf("delete run paths: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM run_bands WHERE run_id=?`, runID); err != nil {
+		return fmt.Errorf("delete run bands: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE id=?`, runID); err != nil {
+		return fmt.Errorf("delete run: %w", err)
+	}
+	return tx.Commit()
+}
+
+const runColumns = `id,experiment_id,queued_at,started_at,finished_at,status,error,stats,progress_done,progress_total,fit,inputs`
+
 func scanRun(row scanner) (*domain.Run, error) {
 	var r domain.Run
-	var startedStr string
+	var queuedStr, startedStr string
 	var finishedStr *string
-	var statsJSON string
-	if err := row.Scan(&r.ID, &r.ExperimentID, &startedStr, &finishedStr, &r.Status, &r.Error, &statsJSON); err != nil {
+	var statsJSON, fitJSON, inputsJSON string
+	if err := row.Scan(&r.ID, &r.ExperimentID, &queuedStr, &startedStr, &finishedStr, &r.Status, &r.Error, &statsJSON,
+		&r.ProgressDone, &r.ProgressTotal, &fitJSON, &inputsJSON); err != nil {
 		return nil, err
 	}
-	r.StartedAt, _ = time.Parse(time.RFC3339, startedStr)
+	r.QueuedAt = parseTime(queuedStr)
+	r.StartedAt = parseTime(startedStr)
 	if finishedStr != nil {
-		t, _ := time.Parse(time.RFC3339, *finishedStr)
+		t := parseTime(*finishedStr)
 		r.FinishedAt = &t
 	}
 	_ = json.Unmarshal([]byte(statsJSON), &r.Stats)
+	if fitJSON != "" {
+		r.Fit = &domain.ModelFit{}
+		_ = json.Unmarshal([]byte(fitJSON), r.Fit)
+	}
+	if inputsJSON != "" {
+		r.Inputs = &domain.MarketInputs{}
+		_ = json.Unmarshal([]byte(inputsJSON), r.Inputs)
+	}
 	return &r, nil
 }
+
+// formatTime renders t as RFC 3339, or "" for the zero time so unset
+// timestamps (e.g. StartedAt of a queued run) round-trip as zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
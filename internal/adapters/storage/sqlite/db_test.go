@@ -2,6 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -135,6 +141,77 @@ func TestGetPriceRecordsLimit(t *testing.T) {
 	}
 }
 
+func seedDailyPrices(t *testing.T, s *Store, symbol string, n int) time.Time {
+	t.Helper()
+	records := make([]domain.PriceRecord, n)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range records {
+		records[i] = domain.PriceRecord{
+			Symbol:        symbol,
+			Date:          base.AddDate(0, 0, i),
+			AdjustedClose: float64(100 + i),
+		}
+	}
+	if err := s.UpsertPriceRecords(context.Background(), records); err != nil {
+		t.Fatalf("UpsertPriceRecords: %v", err)
+	}
+	return base
+}
+
+func TestGetPriceRecordsBetween(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := seedDailyPrices(t, s, "SPY", 10)
+
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		wantLen   int
+		wantFirst float64
+	}{
+		{"closed range", base.AddDate(0, 0, 2), base.AddDate(0, 0, 5), 4, 102},
+		{"open start", time.Time{}, base.AddDate(0, 0, 1), 2, 100},
+		{"open end", base.AddDate(0, 0, 8), time.Time{}, 2, 108},
+		{"fully open", time.Time{}, time.Time{}, 10, 100},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.GetPriceRecordsBetween(ctx, "SPY", tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("GetPriceRecordsBetween: %v", err)
+			}
+			if len(got) != tc.wantLen {
+				t.Fatalf("got %d records, want %d", len(got), tc.wantLen)
+			}
+			if got[0].AdjustedClose != tc.wantFirst {
+				t.Errorf("first = %v, want %v", got[0].AdjustedClose, tc.wantFirst)
+			}
+		})
+	}
+}
+
+func TestGetRecentPriceRecords(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := seedDailyPrices(t, s, "SPY", 10)
+
+	latest, err := s.GetRecentPriceRecords(ctx, "SPY", 3, time.Time{})
+	if err != nil {
+		t.Fatalf("GetRecentPriceRecords: %v", err)
+	}
+	if len(latest) != 3 || latest[0].AdjustedClose != 107 || latest[2].AdjustedClose != 109 {
+		t.Errorf("latest 3 = %+v, want closes 107..109 ascending", latest)
+	}
+
+	asOf, err := s.GetRecentPriceRecords(ctx, "SPY", 3, base.AddDate(0, 0, 4))
+	if err != nil {
+		t.Fatalf("GetRecentPriceRecords asOf: %v", err)
+	}
+	if len(asOf) != 3 || asOf[0].AdjustedClose != 102 || asOf[2].AdjustedClose != 104 {
+		t.Errorf("as-of 3 = %+v, want closes 102..104 ascending", asOf)
+	}
+}
+
 func TestExperimentRoundTrip(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -229,3 +306,358 @@ func TestRunRoundTrip(t *testing.T) {
 		t.Errorf("Stats.P50 = %v, want %v", got.Stats.P50, run.Stats.P50)
 	}
 }
+
+func TestRunBandsRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	run := domain.Run{
+		ID:           "run-001",
+		ExperimentID: "exp-001",
+		StartedAt:    time.Now().UTC().Truncate(time.Second),
+		Status:       domain.StatusComplete,
+		Bands: &domain.Bands{
+			Days:      []int{0, 5, 10},
+			Quantiles: []float64{0.05, 0.5, 0.95},
+			Values:    [][]float64{{100, 90, 80}, {100, 105, 110}, {100, 120, 140}},
+			Sample:    [][]float64{{100, 101, 102}},
+		},
+	}
+	if err := s.SaveRun(ctx, run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+
+	got, err := s.GetRun(ctx, "run-001")
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if got.Bands == nil {
+		t.Fatal("Bands = nil, want stored bands")
+	}
+	if got.Bands.Values[2][2] != 140 || got.Bands.Days[1] != 5 || len(got.Bands.Sample) != 1 {
+		t.Errorf("Bands = %+v, want round-tripped values", got.Bands)
+	}
+
+	list, err := s.ListRuns(ctx, "exp-001")
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(list) != 1 || list[0].Bands != nil {
+		t.Errorf("ListRuns should omit bands, got %+v", list)
+	}
+}
+
+func TestSaveRunPersistsQueueFields(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	queued := time.Now().UTC().Truncate(time.Second)
+	run := domain.Run{ID: "run-001", ExperimentID: "exp-001", QueuedAt: queued, Status: domain.StatusQueued, ProgressTotal: 500}
+	if err := s.SaveRun(ctx, run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	got, err := s.GetRun(ctx, "run-001")
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if !got.QueuedAt.Equal(queued) || !got.StartedAt.IsZero() || got.ProgressTotal != 500 {
+		t.Errorf("run = %+v, want QueuedAt %v, zero StartedAt, ProgressTotal 500", got, queued)
+	}
+
+	// Updating a run must not move its place in the queue.
+	run.QueuedAt = queued.Add(time.Hour)
+	run.ProgressDone = 500
+	if err := s.SaveRun(ctx, run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	got, _ = s.GetRun(ctx, "run-001")
+	if !got.QueuedAt.Equal(queued) || got.ProgressDone != 500 {
+		t.Errorf("after update QueuedAt=%v ProgressDone=%d, want %v and 500", got.QueuedAt, got.ProgressDone, queued)
+	}
+}
+
+func TestClaimNextRun(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	t0 := time.Now().UTC().Truncate(time.Second)
+	for _, r := range []domain.Run{
+		{ID: "done", QueuedAt: t0.Add(-3 * time.Minute), Status: domain.StatusComplete},
+		{ID: "busy", QueuedAt: t0.Add(-2 * time.Minute), Status: domain.StatusRunning},
+		{ID: "second", QueuedAt: t0, Status: domain.StatusQueued},
+		{ID: "first", QueuedAt: t0.Add(-time.Minute), Status: domain.StatusQueued},
+	} {
+		r.ExperimentID = "exp-001"
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatalf("SaveRun %s: %v", r.ID, err)
+		}
+	}
+
+	claimedAt := t0.Add(time.Minute)
+	for _, want := range []string{"first", "second"} {
+		got, err := s.ClaimNextRun(ctx, claimedAt)
+		if err != nil {
+			t.Fatalf("ClaimNextRun: %v", err)
+		}
+		if got == nil || got.ID != want {
+			t.Fatalf("claimed %+v, want %s", got, want)
+		}
+		if got.Status != domain.StatusRunning || !got.StartedAt.Equal(claimedAt) {
+			t.Errorf("claimed run = %s at %v, want running at %v", got.Status, got.StartedAt, claimedAt)
+		}
+	}
+	got, err := s.ClaimNextRun(ctx, claimedAt)
+	if err != nil || got != nil {
+		t.Errorf("ClaimNextRun on empty queue = %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestClaimRun(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	t0 := time.Now().UTC().Truncate(time.Second)
+	for _, r := range []domain.Run{
+		{ID: "first", QueuedAt: t0.Add(-time.Minute), Status: domain.StatusQueued},
+		{ID: "second", QueuedAt: t0, Status: domain.StatusQueued},
+	} {
+		r.ExperimentID = "exp-001"
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatalf("SaveRun %s: %v", r.ID, err)
+		}
+	}
+	claimedAt := t0.Add(time.Minute)
+	got, err := s.ClaimRun(ctx, "second", claimedAt)
+	if err != nil || got == nil || got.ID != "second" || got.Status != domain.StatusRunning || !got.StartedAt.Equal(claimedAt) {
+		t.Fatalf("ClaimRun = %+v, %v; want second running at %v", got, err, claimedAt)
+	}
+	if got, err := s.ClaimRun(ctx, "second", claimedAt); got != nil || err != nil {
+		t.Errorf("ClaimRun of a running run = %+v, %v; want nil, nil", got, err)
+	}
+	if got, _ := s.GetRun(ctx, "first"); got.Status != domain.StatusQueued {
+		t.Errorf("first = %s, want still queued", got.Status)
+	}
+}
+
+func TestRequestCancelFlagsRunningRun(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	for _, r := range []domain.Run{
+		{ID: "busy", Status: domain.StatusRunning},
+		{ID: "done", Status: domain.StatusComplete},
+	} {
+		r.ExperimentID = "exp-001"
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatalf("SaveRun %s: %v", r.ID, err)
+		}
+	}
+	if requested, err := s.UpdateRunProgress(ctx, "busy", 10); err != nil || requested {
+		t.Fatalf("UpdateRunProgress before a request = %v, %v; want false", requested, err)
+	}
+	if ok, err := s.RequestCancel(ctx, "done"); err != nil || ok {
+		t.Errorf("RequestCancel of a finished run = %v, %v; want false", ok, err)
+	}
+	if ok, err := s.RequestCancel(ctx, "busy"); err != nil || !ok {
+		t.Fatalf("RequestCancel = %v, %v; want true", ok, err)
+	}
+	if requested, err := s.UpdateRunProgress(ctx, "busy", 20); err != nil || !requested {
+		t.Errorf("UpdateRunProgress after a request = %v, %v; want true", requested, err)
+	}
+	if got, _ := s.GetRun(ctx, "busy"); got.ProgressDone != 20 {
+		t.Errorf("ProgressDone = %d, want 20", got.ProgressDone)
+	}
+}
+
+func TestReapRunningOnlyTouchesRunning(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, st := range []domain.ExperimentStatus{domain.StatusQueued, domain.StatusRunning, domain.StatusComplete} {
+		r := domain.Run{ID: string(st), ExperimentID: "exp-001", QueuedAt: time.Now().UTC(), Status: st}
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatalf("SaveRun: %v", err)
+		}
+	}
+	n, err := s.ReapRunning(ctx, time.Now().UTC(), domain.RunInterrupted)
+	if err != nil {
+		t.Fatalf("ReapRunning: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("reaped %d runs, want 1", n)
+	}
+	want := map[string]domain.ExperimentStatus{
+		"queued":   domain.StatusQueued,
+		"running":  domain.StatusFailed,
+		"complete": domain.StatusComplete,
+	}
+	for id, st := range want {
+		got, _ := s.GetRun(ctx, id)
+		if got.Status != st {
+			t.Errorf("%s: status = %s, want %s", id, got.Status, st)
+		}
+	}
+	if got, _ := s.GetRun(ctx, "running"); got.Error != domain.RunInterrupted || got.FinishedAt == nil {
+		t.Errorf("reaped run = %+v, want error %q and FinishedAt", got, domain.RunInterrupted)
+	}
+}
+
+func TestRunFitAndInputsRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	run := domain.Run{
+		ID:           "run-001",
+		ExperimentID: "exp-001",
+		Status:       domain.StatusComplete,
+		Fit: &domain.ModelFit{
+			Model:         domain.ModelGARCH,
+			LogLikelihood: 812.5,
+			Assets:        []domain.AssetFit{{Symbol: "SPY", LogLikelihood: 812.5, Params: map[string]float64{"alpha": 0.08, "beta": 0.9}}},
+		},
+		Inputs: &domain.MarketInputs{
+			Source:       domain.SourceAssumptions,
+			Assets:       []domain.AssetAssumption{{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16}},
+			Correlations: [][]float64{{1}},
+		},
+	}
+	if err := s.SaveRun(ctx, run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	list, err := s.ListRuns(ctx, "exp-001")
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(list) != 1 || !reflect.DeepEqual(list[0].Fit, run.Fit) || !reflect.DeepEqual(list[0].Inputs, run.Inputs) {
+		t.Errorf("Fit = %+v, Inputs = %+v, want %+v, %+v", list[0].Fit, list[0].Inputs, run.Fit, run.Inputs)
+	}
+
+	run.ID, run.Fit, run.Inputs = "run-002", nil, nil
+	if err := s.SaveRun(ctx, run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	if got, _ := s.GetRun(ctx, "run-002"); got.Fit != nil || got.Inputs != nil {
+		t.Errorf("Fit = %+v, Inputs = %+v, want nil for a run without them", got.Fit, got.Inputs)
+	}
+}
+
+func TestSaveRunRejectsNonFiniteFit(t *testing.T) {
+	s := newTestStore(t)
+	run := domain.Run{
+		ID:           "run-001",
+		ExperimentID: "exp-001",
+		Status:       domain.StatusComplete,
+		Fit:          &domain.ModelFit{Model: domain.ModelStudentT, LogLikelihood: math.NaN()},
+	}
+	if err := s.SaveRun(context.Background(), run); err == nil || !strings.Contains(err.Error(), "encode fit") {
+		t.Errorf("SaveRun = %v, want an encode fit error", err)
+	}
+}
+
+func TestMigrateAddsRunQueueColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The runs table as it was before runs were queued.
+	if _, err := db.Exec(`CREATE TABLE runs (
+		id TEXT PRIMARY KEY, experiment_id TEXT NOT NULL, started_at TEXT NOT NULL, finished_at TEXT,
+		status TEXT NOT NULL, error TEXT NOT NULL DEFAULT '', stats TEXT NOT NULL DEFAULT '{}');
+		INSERT INTO runs (id,experiment_id,started_at,status) VALUES ('old','exp-001','2026-01-02T03:04:05Z','complete');`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New on old schema: %v", err)
+	}
+	got, err := s.GetRun(context.Background(), "old")
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if !got.QueuedAt.Equal(got.StartedAt) || got.QueuedAt.IsZero() {
+		t.Errorf("QueuedAt = %v, want backfilled from StartedAt %v", got.QueuedAt, got.StartedAt)
+	}
+	if got.Fit != nil || got.Inputs != nil {
+		t.Errorf("Fit = %+v, Inputs = %+v, want nil for a run saved before they were recorded", got.Fit, got.Inputs)
+	}
+}
+
+func TestCancelQueuedRunOnlyTouchesQueued(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, st := range []domain.ExperimentStatus{domain.StatusQueued, domain.StatusRunning} {
+		r := domain.Run{ID: string(st), ExperimentID: "exp-001", QueuedAt: time.Now().UTC(), Status: st}
+		if err := s.SaveRun(ctx, r); err != nil {
+			t.Fatalf("SaveRun: %v", err)
+		}
+	}
+	for id, want := range map[string]bool{"queued": true, "running": false, "missing": false} {
+		ok, err := s.CancelQueuedRun(ctx, id, time.Now().UTC())
+		if err != nil {
+			t.Fatalf("CancelQueuedRun %s: %v", id, err)
+		}
+		if ok != want {
+			t.Errorf("CancelQueuedRun %s = %v, want %v", id, ok, want)
+		}
+	}
+	if got, _ := s.GetRun(ctx, "queued"); got.Status != domain.StatusCancelled || got.FinishedAt == nil {
+		t.Errorf("queued run = %s, FinishedAt %v; want cancelled and finished", got.Status, got.FinishedAt)
+	}
+	if got, _ := s.GetRun(ctx, "running"); got.Status != domain.StatusRunning {
+		t.Errorf("running run = %s, want untouched", got.Status)
+	}
+}
+
+func TestGetMissingRecordsReturnErrNotFound(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := s.GetAsset(ctx, "NOPE"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetAsset err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetExperiment(ctx, "nope"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetExperiment err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetRun(ctx, "nope"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetRun err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetPaths(ctx, "nope", 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetPaths err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRunRemovesResults(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"run-001", "run-002"} {
+		run := domain.Run{ID: id, ExperimentID: "exp-001", Status: domain.StatusComplete, Bands: &domain.Bands{Days: []int{0}}}
+		if err := s.SaveRun(ctx, run); err != nil {
+			t.Fatalf("SaveRun: %v", err)
+		}
+		if err := s.SavePaths(ctx, domain.NewPathSet(id, samplePaths(2, 3), 3, 1, domain.PrecisionFloat64)); err != nil {
+			t.Fatalf("SavePaths: %v", err)
+		}
+	}
+	if err := s.DeleteRun(ctx, "run-001"); err != nil {
+		t.Fatalf("DeleteRun: %v", err)
+	}
+	if _, err := s.GetRun(ctx, "run-001"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetRun after delete err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetPaths(ctx, "run-001", 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetPaths after delete err = %v, want ErrNotFound", err)
+	}
+	var bands int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM run_bands WHERE run_id='run-001'`).Scan(&bands); err != nil || bands != 0 {
+		t.Errorf("run_bands rows = %d (%v), want 0", bands, err)
+	}
+	if got, err := s.GetRun(ctx, "run-002"); err != nil || got.Bands == nil {
+		t.Errorf("other run = %+v, %v; want untouched", got, err)
+	}
+}
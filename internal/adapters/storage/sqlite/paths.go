@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// ──────────────────── PathRepository ─────────────────────────────────────────
+//
+// A run's paths are stored as a row of metadata in run_paths and the values
+// themselves in run_path_chunks, chunk_paths paths to a BLOB. Each BLOB is
+// columnar: each path's sampled values form one contiguous column of
+// little-endian float64 or float32 words, and columns are laid out in path
+// order. Alongside it, flows holds each path's net cash flow as one float64
+// word per path, in the same order. Chunks keep every BLOB far below SQLite's maximum length however
+// many paths a run stores, and let GetPaths read only the chunks an index
+// range touches.
+
+// pathChunkBytes is the most a chunk holds, unless a single path is larger.
+const pathChunkBytes = 1 << 20
+
+// SavePaths persists a run's paths, replacing any previously stored artifact.
+// The set's Days must be the output of domain.SampledDays for some step.
+func (s *Store) SavePaths(ctx context.Context, set domain.PathSet) error {
+	every, horizon := sampling(set.Days)
+	width := precisionWidth(set.Precision)
+	stride := len(set.Days) * width
+	perChunk := max(1, pathChunkBytes/max(1, stride))
+	for i, p := range set.Paths {
+		if len(p.Values) != len(set.Days) {
+			return fmt.Errorf("path %d has %d values, want %d", i, len(p.Values), len(set.Days))
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // Rollback is a no-op after Commit; error is intentionally ignored
+	if err := deletePaths(ctx, tx, set.RunID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO run_paths (run_id,num_paths,horizon,every,precision,chunk_paths) VALUES (?,?,?,?,?,?)`,
+		set.RunID, len(set.Paths), horizon, every, string(set.Precision), perChunk); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO run_path_chunks (run_id,chunk,data,flows) VALUES (?,?,?,?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close() //nolint:errcheck // stmt.Close in defer; any error is non-actionable here
+	buf := make([]byte, 0, min(len(set.Paths), perChunk)*stride)
+	flows := make([]byte, 0, min(len(set.Paths), perChunk)*8)
+	for start := 0; start < len(set.Paths); start += perChunk {
+		buf, flows = buf[:0], flows[:0]
+		for _, p := range set.Paths[start:min(start+perChunk, len(set.Paths))] {
+			buf = appendValues(buf, p.Values, width)
+			flows = binary.LittleEndian.AppendUint64(flows, math.Float64bits(p.NetCashFlow))
+		}
+		if _, err := stmt.ExecContext(ctx, set.RunID, start/perChunk, buf, flows); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// deletePaths removes a run's stored paths within tx.
+func deletePaths(ctx context.Context, tx *sql.Tx, runID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM run_path_chunks WHERE run_id=?`, runID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM run_paths WHERE run_id=?`, runID)
+	return err
+}
+
+// GetPaths returns up to limit stored paths for runID starting at offset.
+// A limit <= 0 returns every path from offset onward.
+func (s *Store) GetPaths(ctx context.Context, runID string, offset, limit int) (*domain.PathSet, error) {
+	var numPaths, horizon, every, perChunk int
+	var precision string
+	row := s.db.QueryRowContext(ctx,
+		`SELECT num_paths,horizon,every,precision,chunk_paths FROM run_paths WHERE run_id=?`, runID)
+	if err := row.Scan(&numPaths, &horizon, &every, &precision, &perChunk); err != nil {
+		return nil, notFound(err)
+	}
+	set := &domain.PathSet{
+		RunID:     runID,
+		Total:     numPaths,
+		Offset:    offset,
+		Days:      domain.SampledDays(horizon, every),
+		Precision: domain.PathPrecision(precision),
+	}
+	if offset >= numPaths {
+		return set, nil
+	}
+	if limit <= 0 || offset+limit > numPaths {
+		limit = numPaths - offset
+	}
+	width := precisionWidth(set.Precision)
+	stride := len(set.Days) * width
+	first, last := offset/perChunk, (offset+limit-1)/perChunk
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT chunk,data,flows FROM run_path_chunks WHERE run_id=? AND chunk BETWEEN ? AND ? ORDER BY chunk`,
+		runID, first, last)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck // rows.Close in defer; final error captured by rows.Err()
+	set.Paths = make([]domain.SimulatedPath, 0, limit)
+	for want := first; rows.Next(); want++ {
+		var chunk int
+		var data, flows []byte
+		if err := rows.Scan(&chunk, &data, &flows); err != nil {
+			return nil, err
+		}
+		// Path i of the run is path i - start of its chunk.
+		start := chunk * perChunk
+		n := min(perChunk, numPaths-start)
+		if chunk != want || len(data) != n*stride || (flows != nil && len(flows) != n*8) {
+			return nil, fmt.Errorf("path artifact for %s is truncated at chunk %d", runID, want)
+		}
+		for i := max(offset, start); i < min(offset+limit, start+n); i++ {
+			j := i - start
+			p := domain.SimulatedPath{Values: decodeValues(data[j*stride:(j+1)*stride], width)}
+			if flows != nil {
+				p.NetCashFlow = math.Float64frombits(binary.LittleEndian.Uint64(flows[j*8:]))
+			}
+			set.Paths = append(set.Paths, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(set.Paths) != limit {
+		return nil, fmt.Errorf("path artifact for %s is truncated: got %d paths, want %d", runID, len(set.Paths), limit)
+	}
+	return set, nil
+}
+
+// sampling recovers the (every, horizon) pair that domain.SampledDays expands
+// into days.
+func sampling(days []int) (every, horizon int) {
+	if len(days) == 0 {
+		return 1, 0
+	}
+	horizon = days[len(days)-1]
+	every = 1
+	if len(days) > 1 {
+		every = days[1] - days[0]
+	}
+	return every, horizon
+}
+
+func precisionWidth(p domain.PathPrecision) int {
+	if p == domain.PrecisionFloat32 {
+		return 4
+	}
+	return 8
+}
+
+func appendValues(buf []byte, vals []float64, width int) []byte {
+	for _, v := range vals {
+		if width == 4 {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+		} else {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	return buf
+}
+
+func decodeValues(b []byte, width int) []float64 {
+	vals := make([]float64, len(b)/width)
+	for i := range vals {
+		if width == 4 {
+			vals[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:])))
+		} else {
+			vals[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))
+		}
+	}
+	return vals
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func samplePaths(n, horizon int) []domain.SimulatedPath {
+	paths := make([]domain.SimulatedPath, n)
+	for i := range paths {
+		vals := make([]float64, horizon+1)
+		for d := range vals {
+			vals[d] = 100_000 + float64(i*1000+d) + 0.125
+		}
+		paths[i] = domain.SimulatedPath{Values: vals, NetCashFlow: float64(i) * 1000.5}
+	}
+	return paths
+}
+
+func TestPathsRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	set := domain.NewPathSet("run-001", samplePaths(10, 9), 9, 4, domain.PrecisionFloat64)
+
+	if err := s.SavePaths(ctx, set); err != nil {
+		t.Fatalf("SavePaths: %v", err)
+	}
+	got, err := s.GetPaths(ctx, "run-001", 3, 4)
+	if err != nil {
+		t.Fatalf("GetPaths: %v", err)
+	}
+
+	if got.Total != 10 || got.Offset != 3 || len(got.Paths) != 4 {
+		t.Fatalf("got total=%d offset=%d len=%d, want 10/3/4", got.Total, got.Offset, len(got.Paths))
+	}
+	if len(got.Days) != 4 || got.Days[3] != 9 {
+		t.Fatalf("Days = %v, want [0 4 8 9]", got.Days)
+	}
+	for i, p := range got.Paths {
+		want := set.Paths[3+i].Values
+		for j := range want {
+			if p.Values[j] != want[j] {
+				t.Fatalf("path %d value %d = %v, want %v", 3+i, j, p.Values[j], want[j])
+			}
+		}
+		if want := set.Paths[3+i].NetCashFlow; p.NetCashFlow != want {
+			t.Errorf("path %d NetCashFlow = %v, want %v", 3+i, p.NetCashFlow, want)
+		}
+	}
+}
+
+func TestPathsWithoutStoredFlows(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.SavePaths(ctx, domain.NewPathSet("run-004", samplePaths(3, 9), 9, 1, domain.PrecisionFloat64)); err != nil {
+		t.Fatalf("SavePaths: %v", err)
+	}
+	// Chunks written before flows were kept have none.
+	if _, err := s.db.ExecContext(ctx, `UPDATE run_path_chunks SET flows=NULL WHERE run_id='run-004'`); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetPaths(ctx, "run-004", 0, 0)
+	if err != nil {
+		t.Fatalf("GetPaths: %v", err)
+	}
+	if len(got.Paths) != 3 || got.Paths[2].NetCashFlow != 0 {
+		t.Errorf("paths = %+v, want three with a zero flow", got.Paths)
+	}
+}
+
+func TestPathsFloat32AndOpenRange(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	set := domain.NewPathSet("run-002", samplePaths(5, 20), 20, 1, domain.PrecisionFloat32)
+	_ = s.SavePaths(ctx, set)
+
+	got, err := s.GetPaths(ctx, "run-002", 2, 0)
+	if err != nil {
+		t.Fatalf("GetPaths: %v", err)
+	}
+
+	if len(got.Paths) != 3 {
+		t.Fatalf("limit 0 from offset 2: got %d paths, want 3", len(got.Paths))
+	}
+	if got.Precision != domain.PrecisionFloat32 {
+		t.Errorf("Precision = %q, want float32", got.Precision)
+	}
+	v, want := got.Paths[0].Values[20], set.Paths[2].Values[20]
+	if math.Abs(v-want)/want > 1e-6 {
+		t.Errorf("float32 value = %v, want ~%v", v, want)
+	}
+}
+
+func TestPathsSpanChunks(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	// Each path is half a chunk, so seven paths take four chunks.
+	const horizon = pathChunkBytes/16 - 1
+	set := domain.NewPathSet("run-003", samplePaths(7, horizon), horizon, 1, domain.PrecisionFloat64)
+	if err := s.SavePaths(ctx, set); err != nil {
+		t.Fatalf("SavePaths: %v", err)
+	}
+	var chunks int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM run_path_chunks WHERE run_id='run-003'`).Scan(&chunks); err != nil || chunks != 4 {
+		t.Fatalf("stored %d chunks (%v), want 4", chunks, err)
+	}
+
+	got, err := s.GetPaths(ctx, "run-003", 1, 4)
+	if err != nil {
+		t.Fatalf("GetPaths: %v", err)
+	}
+	if len(got.Paths) != 4 {
+		t.Fatalf("got %d paths, want 4", len(got.Paths))
+	}
+	for i, p := range got.Paths {
+		if want := set.Paths[1+i].Values[horizon]; p.Values[horizon] != want {
+			t.Errorf("path %d last value = %v, want %v", 1+i, p.Values[horizon], want)
+		}
+		if want := set.Paths[1+i].NetCashFlow; p.NetCashFlow != want {
+			t.Errorf("path %d NetCashFlow = %v, want %v", 1+i, p.NetCashFlow, want)
+		}
+	}
+
+	// Saving again replaces every chunk.
+	if err := s.SavePaths(ctx, domain.NewPathSet("run-003", samplePaths(1, 9), 9, 1, domain.PrecisionFloat64)); err != nil {
+		t.Fatalf("SavePaths: %v", err)
+	}
+	if got, err := s.GetPaths(ctx, "run-003", 0, 0); err != nil || got.Total != 1 || len(got.Paths) != 1 {
+		t.Errorf("after replacing: %+v, %v; want the one new path", got, err)
+	}
+}
+
+func TestGetPathsMissingRun(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.GetPaths(context.Background(), "nope", 0, 0); err == nil {
+		t.Fatal("expected error for run without stored paths")
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// marketInputs returns the lookback window's daily log-returns rows as the
+// model described by spec should see them, with the moments the run records.
+// With the sample estimators and no capital market assumptions rows are
+// returned unchanged. Otherwise every asset's returns are shifted and scaled,
+// and mixed when the correlations change too, so that their sample mean,
+// volatility and correlation are exactly the assumed or estimated ones. What
+// else the window holds (fat tails, volatility clustering, regimes, jumps)
+// survives for the model to fit.
+func marketInputs(rows [][]float64, exp *domain.Experiment, spec domain.ModelSpec) ([][]float64, *domain.MarketInputs, error) {
+	cfg := exp.Config
+	est := cfg.Estimator.Normalized()
+	mean, cov := sampleMoments(rows)
+	if cfg.Assumptions == nil && est.IsSample() {
+		in := historicalInputs(exp.Portfolio, mean, cov)
+		in.Estimator = est
+		return rows, in, nil
+	}
+	if !spec.TakesAssumptions {
+		if cfg.Assumptions != nil {
+			return nil, nil, fmt.Errorf("%s does not take capital market assumptions", spec.Name)
+		}
+		return nil, nil, fmt.Errorf("%s resamples the lookback window as it is and takes only the sample estimators", spec.Name)
+	}
+	sd := make([]float64, len(mean))
+	for i := range sd {
+		if sd[i] = math.Sqrt(cov[i][i]); sd[i] == 0 {
+			return nil, nil, fmt.Errorf("%s has no return variation in the lookback window to rescale", exp.Portfolio.Assets[i].Symbol)
+		}
+	}
+	e, err := estimateMoments(rows, mean, cov, est)
+	if err != nil {
+		return nil, nil, fmt.Errorf("estimate moments of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+
+	wantMean := e.mean
+	wantSD := make([]float64, len(mean))
+	for i := range wantSD {
+		wantSD[i] = math.Sqrt(e.cov[i][i])
+	}
+	target, what := correlation(e.cov), "estimated"
+	mixed := est.Cov != domain.CovSample // whether target differs from the window's correlation
+	in := historicalInputs(exp.Portfolio, e.mean, e.cov)
+	in.MeanShrinkage, in.CovShrinkage = e.meanShrinkage, e.covShrinkage
+	if cfg.Assumptions != nil {
+		a, err := cfg.Assumptions.For(exp.Portfolio)
+		if err != nil {
+			return nil, nil, err
+		}
+		// A GBM with drift log(1+r) and volatility σ has expected simple
+		// return r a year and daily log-returns of mean (log(1+r) − σ²/2)/252.
+		wantMean = make([]float64, len(mean))
+		for i, aa := range a.Assets {
+			wantMean[i] = (math.Log1p(aa.ExpectedReturn) - 0.5*aa.Volatility*aa.Volatility) / 252
+			wantSD[i] = aa.Volatility / math.Sqrt(252)
+		}
+		in = &domain.MarketInputs{Source: domain.SourceAssumptions, Assets: a.Assets}
+		if a.Correlations != nil {
+			target, what, mixed = a.Correlations, "assumed", true
+		} else {
+			in.CovShrinkage = e.covShrinkage
+		}
+	}
+	in.Estimator = est
+	in.Correlations = target
+
+	var mix [][]float64 // nil keeps the window's correlations
+	if mixed {
+		mix, in.Correlations, err = correlationMap(correlation(cov), target, cfg.RepairCovariance)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s correlations of %s: %w", what, portfolioSymbols(exp.Portfolio), err)
+		}
+	}
+	out := make([][]float64, len(rows))
+	z := make([]float64, len(mean))
+	for t, r := range rows {
+		for i := range z {
+			z[i] = (r[i] - mean[i]) / sd[i]
+		}
+		row := make([]float64, len(z))
+		for i := range row {
+			zi := z[i]
+			if mix != nil {
+				zi = 0
+				for j, m := range mix[i] {
+					zi += m * z[j]
+				}
+			}
+			row[i] = wantMean[i] + wantSD[i]*zi
+		}
+		out[t] = row
+	}
+	return out, in, nil
+}
+
+// historicalInputs describes the lookback window's moments in the annual
+// units of capital market assumptions.
+func historicalInputs(p domain.Portfolio, mean []float64, cov [][]float64) *domain.MarketInputs {
+	in := &domain.MarketInputs{
+		Source:       domain.SourceHistorical,
+		Assets:       make([]domain.AssetAssumption, len(mean)),
+		Correlations: correlation(cov),
+	}
+	for i := range mean {
+		in.Assets[i] = domain.AssetAssumption{
+			Symbol:         p.Assets[i].Symbol,
+			ExpectedReturn: math.Expm1(252*mean[i] + 126*cov[i][i]),
+			Volatility:     math.Sqrt(252 * cov[i][i]),
+		}
+	}
+	return in
+}
+
+// correlationMap returns the matrix target^½·hist^−½, which takes vectors
+// with correlation hist to vectors with correlation target, and the target
+// it used: target itself, or with repair set its nearest PSD matrix if it
+// is not positive-definite. Being symmetric, the map does not depend on the
+// order of the assets.
+func correlationMap(hist, target [][]float64, repair bool) (m, used [][]float64, err error) {
+	if _, err := cholesky(target); err != nil {
+		if !repair {
+			return nil, nil, fmt.Errorf("%w (enable repair_covariance to use the nearest PSD matrix)", err)
+		}
+		target = nearestPSD(target)
+	}
+	root, ok := symPower(target, 0.5)
+	if !ok {
+		return nil, nil, errNotPositiveDefinite
+	}
+	inv, ok := symPower(hist, -0.5)
+	if !ok {
+		return nil, nil, errors.New("the lookback window's correlation matrix is singular")
+	}
+	m = make([][]float64, len(root))
+	for i := range m {
+		m[i] = make([]float64, len(root))
+		for j := range m[i] {
+			for k := range root {
+				m[i][j] += root[i][k] * inv[k][j]
+			}
+		}
+	}
+	return m, target, nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// correlatedRows draws n days of two assets' returns with daily volatilities
+// 1% and 2% and correlation rho.
+func correlatedRows(n int, rho float64) [][]float64 {
+	rng := rand.New(rand.NewPCG(3, 5))
+	rows := make([][]float64, n)
+	for t := range rows {
+		a, b := rng.NormFloat64(), rng.NormFloat64()
+		rows[t] = []float64{0.0004 + 0.01*a, 0.02 * (rho*a + math.Sqrt(1-rho*rho)*b)}
+	}
+	return rows
+}
+
+func assumptionsExperiment(cma *domain.CapitalMarketAssumptions) *domain.Experiment {
+	return &domain.Experiment{
+		Portfolio: domain.Portfolio{Assets: []domain.PortfolioAsset{{Symbol: "EQ", Weight: 0.6}, {Symbol: "BD", Weight: 0.4}}},
+		Config:    domain.SimulationConfig{Assumptions: cma},
+	}
+}
+
+func TestMarketInputsMatchAssumptions(t *testing.T) {
+	rows := correlatedRows(2000, 0.6)
+	cma := &domain.CapitalMarketAssumptions{
+		// Listed in another order than the portfolio, with an asset it lacks.
+		Assets: []domain.AssetAssumption{
+			{Symbol: "BD", ExpectedReturn: 0.03, Volatility: 0.05},
+			{Symbol: "CASH", ExpectedReturn: 0.02, Volatility: 0.01},
+			{Symbol: "EQ", ExpectedReturn: 0.06, Volatility: 0.16},
+		},
+	}
+	spec := gbmModel{}.Spec()
+
+	got, in, err := marketInputs(rows, assumptionsExperiment(cma), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := estimateGBMParams(got)
+	if math.Abs(p.mu[0]-math.Log(1.06)) > 1e-9 || math.Abs(p.mu[1]-math.Log(1.03)) > 1e-9 {
+		t.Errorf("drift = %v, want log(1.06), log(1.03)", p.mu)
+	}
+	if math.Abs(p.cov[0][0]-0.16*0.16) > 1e-9 || math.Abs(p.cov[1][1]-0.05*0.05) > 1e-9 {
+		t.Errorf("variances = %g, %g, want 0.16², 0.05²", p.cov[0][0], p.cov[1][1])
+	}
+	_, histCov := sampleMoments(rows)
+	if c, want := correlation(p.cov)[0][1], correlation(histCov)[0][1]; math.Abs(c-want) > 1e-9 {
+		t.Errorf("correlation = %.4f, want the historical %.4f", c, want)
+	}
+	if in.Source != domain.SourceAssumptions || in.Assets[0].Symbol != "EQ" || in.Assets[1].ExpectedReturn != 0.03 {
+		t.Errorf("inputs = %+v, want the assumptions in portfolio order", in)
+	}
+
+	cma.Correlations = [][]float64{{1, 0, -0.2}, {0, 1, 0}, {-0.2, 0, 1}}
+	got, in, err = marketInputs(rows, assumptionsExperiment(cma), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cov := sampleMoments(got)
+	if c := correlation(cov)[0][1]; math.Abs(c+0.2) > 1e-9 || in.Correlations[0][1] != -0.2 {
+		t.Errorf("correlation = %.4f (recorded %g), want the assumed -0.2", c, in.Correlations[0][1])
+	}
+	if math.Abs(math.Sqrt(252*cov[0][0])-0.16) > 1e-9 {
+		t.Errorf("volatility = %.4f after mixing, want 0.16", math.Sqrt(252*cov[0][0]))
+	}
+}
+
+func TestMarketInputsHistorical(t *testing.T) {
+	rows := correlatedRows(500, 0.6)
+	got, in, err := marketInputs(rows, assumptionsExperiment(nil), bootstrapModel{}.Spec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mean, cov := sampleMoments(rows)
+	if &got[0] != &rows[0] || in.Source != domain.SourceHistorical {
+		t.Fatalf("source = %s, want the rows unchanged and historical inputs", in.Source)
+	}
+	if want := math.Exp(252*mean[0]+126*cov[0][0]) - 1; math.Abs(in.Assets[0].ExpectedReturn-want) > 1e-12 {
+		t.Errorf("expected return = %g, want %g", in.Assets[0].ExpectedReturn, want)
+	}
+	if want := math.Sqrt(252 * cov[1][1]); math.Abs(in.Assets[1].Volatility-want) > 1e-12 {
+		t.Errorf("volatility = %g, want %g", in.Assets[1].Volatility, want)
+	}
+}
+
+func TestMarketInputsRejects(t *testing.T) {
+	rows := correlatedRows(500, 0.6)
+	cma := &domain.CapitalMarketAssumptions{Assets: []domain.AssetAssumption{
+		{Symbol: "EQ", ExpectedReturn: 0.06, Volatility: 0.16},
+		{Symbol: "BD", ExpectedReturn: 0.03, Volatility: 0.05},
+	}}
+	if _, _, err := marketInputs(rows, assumptionsExperiment(cma), bootstrapModel{}.Spec()); err == nil {
+		t.Error("bootstrap took capital market assumptions")
+	}
+
+	cma.Correlations = [][]float64{{1, 1}, {1, 1}}
+	exp := assumptionsExperiment(cma)
+	if _, _, err := marketInputs(rows, exp, gbmModel{}.Spec()); err == nil || !strings.Contains(err.Error(), "repair_covariance") {
+		t.Errorf("singular assumed correlations: err = %v, want a hint to repair", err)
+	}
+	exp.Config.RepairCovariance = true
+	if _, _, err := marketInputs(rows, exp, gbmModel{}.Spec()); err != nil {
+		t.Errorf("repaired assumed correlations: %v", err)
+	}
+
+	cma.Correlations = nil
+	cma.Assets = cma.Assets[:1]
+	if _, _, err := marketInputs(rows, exp, gbmModel{}.Spec()); err == nil || !strings.Contains(err.Error(), "BD") {
+		t.Errorf("missing assumption: err = %v, want it to name BD", err)
+	}
+}
+
+func TestRunnerRecordsAssumptions(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Config.Assumptions = &domain.CapitalMarketAssumptions{Assets: []domain.AssetAssumption{
+		{Symbol: "SPY", ExpectedReturn: 0.05, Volatility: 0.15},
+	}}
+	var run domain.Run
+	if _, err := svc.simulate(ctx, exp, &run); err != nil {
+		t.Fatal(err)
+	}
+	if in := run.Inputs; in == nil || in.Source != domain.SourceAssumptions || in.Assets[0].Volatility != 0.15 {
+		t.Errorf("inputs = %+v, want the assumptions recorded", run.Inputs)
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"math/rand/v2"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// defaultBlockLength is used when SimulationConfig.BlockLength is zero: about
+// one trading month, long enough to carry short-horizon volatility clustering.
+const defaultBlockLength = 21
+
+// blockBootstrapModel resamples contiguous blocks of historical days.
+type blockBootstrapModel struct{}
+
+func (blockBootstrapModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:        domain.ModelBlockBootstrap,
+		Label:       "Block Bootstrap",
+		Description: "Replays contiguous runs of historical days, keeping short-horizon volatility clustering.",
+		Params:      domain.BlockParams{}.Spec(),
+	}
+}
+
+func (blockBootstrapModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	alloc := portfolioAllocation(exp.Portfolio)
+	return pathFunc(func(rng *rand.Rand) domain.SimulatedPath {
+		return blockPath(exp.Config, rows, alloc, rng)
+	}), nil
+}
+
+// blockPath replays contiguous runs of historical days chosen by the
+// configured block scheme.
+func blockPath(cfg domain.SimulationConfig, rows [][]float64, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	b := newBlockSampler(len(rows), cfg.Block.Length, cfg.Block.Scheme)
+	return resamplePath(cfg, rows, alloc, func() int { return b.next(rng) })
+}
+
+// blockSampler yields the sequence of historical row indices for one path.
+type blockSampler struct {
+	n      int // number of historical rows
+	length int // block length (mean length for the stationary scheme)
+	scheme domain.BlockScheme
+	pos    int // index returned by the previous call
+	left   int // rows remaining in the current fixed-length block
+	begun  bool
+}
+
+func newBlockSampler(n, length int, scheme domain.BlockScheme) *blockSampler {
+	if length <= 0 {
+		length = defaultBlockLength
+	}
+	if length > n {
+		length = n
+	}
+	if scheme == "" {
+		scheme = domain.BlockFixed
+	}
+	return &blockSampler{n: n, length: length, scheme: scheme}
+}
+
+func (b *blockSampler) next(rng *rand.Rand) int {
+	switch b.scheme {
+	case domain.BlockStationary:
+		// Each day starts a new block with probability 1/length, giving
+		// geometrically distributed block lengths with mean length.
+		if !b.begun || rng.Float64() < 1/float64(b.length) {
+			b.pos = rng.IntN(b.n)
+			b.begun = true
+		} else {
+			b.pos = (b.pos + 1) % b.n
+		}
+		return b.pos
+	case domain.BlockCircular:
+		if b.left == 0 {
+			b.pos = rng.IntN(b.n)
+			b.left = b.length
+		} else {
+			b.pos = (b.pos + 1) % b.n
+		}
+	default:
+		if b.left == 0 {
+			b.pos = rng.IntN(b.n - b.length + 1)
+			b.left = b.length
+		} else {
+			b.pos++
+		}
+	}
+	b.left--
+	return b.pos
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func TestBlockSamplerFixedStaysInRange(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{1}))
+	b := newBlockSampler(50, 10, domain.BlockFixed)
+
+	prev := -1
+	for i := range 1000 {
+		idx := b.next(rng)
+		if idx < 0 || idx >= 50 {
+			t.Fatalf("index %d out of range", idx)
+		}
+		if i%10 != 0 && idx != prev+1 {
+			t.Fatalf("step %d: index %d does not continue block at %d", i, idx, prev)
+		}
+		prev = idx
+	}
+}
+
+func TestBlockSamplerCircularWraps(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{2}))
+	b := newBlockSampler(5, 5, domain.BlockCircular)
+
+	wrapped := false
+	prev := -1
+	for i := range 500 {
+		idx := b.next(rng)
+		if i%5 != 0 {
+			if idx != (prev+1)%5 {
+				t.Fatalf("step %d: index %d does not continue block at %d", i, idx, prev)
+			}
+			if idx == 0 {
+				wrapped = true
+			}
+		}
+		prev = idx
+	}
+	if !wrapped {
+		t.Error("circular blocks never wrapped past the end of history")
+	}
+}
+
+func TestBlockSamplerStationaryMeanLength(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{3}))
+	b := newBlockSampler(10_000, 20, domain.BlockStationary)
+
+	blocks, draws := 1, 100_000
+	prev := b.next(rng)
+	for range draws - 1 {
+		idx := b.next(rng)
+		if idx != (prev+1)%10_000 {
+			blocks++
+		}
+		prev = idx
+	}
+	mean := float64(draws) / float64(blocks)
+	if math.Abs(mean-20) > 2 {
+		t.Errorf("mean block length = %v, want ~20", mean)
+	}
+}
+
+func TestBlockPathPreservesRunsOfDays(t *testing.T) {
+	// Alternating +r/-r history: any whole block of even length nets to zero,
+	// so with block length 2 and no wrap-around every second day returns to 1.
+	rows := make([][]float64, 100)
+	for i := range rows {
+		rows[i] = []float64{0.01 * float64(1-2*(i%2))}
+	}
+	cfg := domain.SimulationConfig{HorizonDays: 40, StartValue: 1, Block: domain.BlockParams{Length: 2}}
+	rng := rand.New(rand.NewChaCha8([32]byte{4}))
+
+	path := blockPath(cfg, rows, allocation{weights: []float64{1}}, rng)
+
+	for d := 2; d <= cfg.HorizonDays; d += 2 {
+		if math.Abs(path.Values[d]-1) > 1e-12 {
+			t.Fatalf("day %d = %v, want 1", d, path.Values[d])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"math"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// cashFlows is the per-run schedule of external contributions and
+// withdrawals, derived once from the SimulationConfig.
+type cashFlows struct {
+	interval     int     // trading days between flows
+	contribution float64 // fixed contribution per period, in day-0 dollars
+	withdrawal   float64 // fixed withdrawal per period, in day-0 dollars
+	rate         float64 // fraction of the balance withdrawn per period
+	inflation    float64 // annual rate indexing the fixed amounts
+}
+
+func newCashFlows(cfg domain.SimulationConfig) cashFlows {
+	f := cfg.CashFlowFrequency
+	periods := float64(f.PeriodsPerYear())
+	return cashFlows{
+		interval:     f.Interval(),
+		contribution: cfg.AnnualContribution / periods,
+		withdrawal:   cfg.AnnualWithdrawal / periods,
+		rate:         cfg.WithdrawalRate / periods,
+		inflation:    cfg.InflationRate,
+	}
+}
+
+func (c cashFlows) active() bool {
+	return c.contribution != 0 || c.withdrawal != 0 || c.rate != 0
+}
+
+// at returns the net external flow applied at the end of the given trading
+// day to a portfolio worth balance: positive for a net contribution, negative
+// for a net withdrawal. Withdrawals never take the balance below zero.
+func (c cashFlows) at(day int, balance float64) float64 {
+	if !c.active() || day%c.interval != 0 {
+		return 0
+	}
+	index := 1.0
+	if c.inflation != 0 {
+		index = math.Pow(1+c.inflation, float64(day)/252)
+	}
+	flow := (c.contribution-c.withdrawal)*index - c.rate*balance
+	if balance+flow < 0 {
+		flow = -balance
+	}
+	return flow
+}
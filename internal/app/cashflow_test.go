@@ -0,0 +1,93 @@
+package app
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func flat(lr []float64) {
+	for i := range lr {
+		lr[i] = 0
+	}
+}
+
+func TestSimulatePathContributionsCompound(t *testing.T) {
+	cfg := domain.SimulationConfig{HorizonDays: 504, StartValue: 100_000, AnnualContribution: 12_000}
+	alloc := allocation{weights: []float64{1}}
+	growth := math.Log(1.10) / 252 // 10% per year
+
+	path := simulatePath(cfg, alloc, func(lr []float64) { lr[0] = growth })
+
+	// Year 1: 110 000 + 12 000; year 2: 122 000·1.1 + 12 000.
+	want := (100_000*1.1+12_000)*1.1 + 12_000
+	if math.Abs(path.Final()-want) > 1e-6 {
+		t.Errorf("final = %v, want %v (contributions must keep compounding)", path.Final(), want)
+	}
+	if path.NetCashFlow != 24_000 {
+		t.Errorf("NetCashFlow = %v, want 24000", path.NetCashFlow)
+	}
+}
+
+func TestSimulatePathMonthlyCashFlows(t *testing.T) {
+	cfg := domain.SimulationConfig{
+		HorizonDays:        252,
+		StartValue:         1000,
+		AnnualContribution: 1200,
+		CashFlowFrequency:  domain.CashFlowMonthly,
+	}
+
+	path := simulatePath(cfg, allocation{weights: []float64{1}}, flat)
+
+	if math.Abs(path.Values[21]-1100) > 1e-9 {
+		t.Errorf("day 21 = %v, want 1100 after first monthly contribution", path.Values[21])
+	}
+	if math.Abs(path.Final()-2200) > 1e-9 {
+		t.Errorf("final = %v, want 2200", path.Final())
+	}
+}
+
+func TestSimulatePathWithdrawals(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  domain.SimulationConfig
+		want float64
+	}{
+		{"percentage of balance", domain.SimulationConfig{HorizonDays: 504, StartValue: 1000, WithdrawalRate: 0.1}, 810},
+		{"fixed amount", domain.SimulationConfig{HorizonDays: 504, StartValue: 1000, AnnualWithdrawal: 100}, 800},
+		{"inflation indexed", domain.SimulationConfig{HorizonDays: 504, StartValue: 1000, AnnualWithdrawal: 100, InflationRate: 0.1}, 1000 - 110 - 121},
+		{"cannot go below zero", domain.SimulationConfig{HorizonDays: 504, StartValue: 150, AnnualWithdrawal: 100}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := simulatePath(tc.cfg, allocation{weights: []float64{1}}, flat)
+
+			if math.Abs(path.Final()-tc.want) > 1e-9 {
+				t.Errorf("final = %v, want %v", path.Final(), tc.want)
+			}
+			if math.Abs(path.Final()-(tc.cfg.StartValue+path.NetCashFlow)) > 1e-9 {
+				t.Errorf("final %v != start + net flow %v", path.Final(), tc.cfg.StartValue+path.NetCashFlow)
+			}
+		})
+	}
+}
+
+func TestSimulatePathWithdrawalKeepsDrift(t *testing.T) {
+	// A pro-rata withdrawal must not rebalance a buy-and-hold portfolio.
+	cfg := domain.SimulationConfig{HorizonDays: 252, StartValue: 100, WithdrawalRate: 0.5}
+	alloc := allocation{weights: []float64{0.5, 0.5}}
+	d := 0
+
+	path := simulatePath(cfg, alloc, func(lr []float64) {
+		d++
+		lr[0], lr[1] = 0, 0
+		if d == 1 {
+			lr[0] = math.Log(3) // asset 0 triples on day 1: holdings 150/50
+		}
+	})
+
+	if math.Abs(path.Final()-100) > 1e-9 {
+		t.Errorf("final = %v, want 100 (half of 200)", path.Final())
+	}
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// estimates are the lookback window's daily mean and covariance by the
+// configured estimators, with the weights the shrinkage estimators put on
+// their targets.
+type estimates struct {
+	mean          []float64
+	cov           [][]float64
+	meanShrinkage float64
+	covShrinkage  float64
+}
+
+// estimateMoments estimates the daily moments of rows by the estimators p
+// names; mean and cov are the rows' sample moments. p must be normalized.
+func estimateMoments(rows [][]float64, mean []float64, cov [][]float64, p domain.EstimatorParams) (estimates, error) {
+	e := estimates{mean: mean, cov: cov}
+	switch p.Cov {
+	case domain.CovEWMA:
+		e.cov = ewmaCovariance(rows, mean, p.HalfLife)
+	case domain.CovLedoitWolf:
+		e.cov, e.covShrinkage = ledoitWolf(rows, mean, cov)
+	}
+	if p.Mean == domain.MeanBayesStein {
+		var err error
+		if e.mean, e.meanShrinkage, err = bayesStein(len(rows), mean, cov); err != nil {
+			return estimates{}, fmt.Errorf("bayes_stein: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// ewmaCovariance is the RiskMetrics exponentially weighted covariance about
+// mean: day t back from the last carries weight λ^t, λ = 2^(−1/halfLife), so
+// a day halfLife days old counts half as much as the latest.
+func ewmaCovariance(rows [][]float64, mean []float64, halfLife int) [][]float64 {
+	lambda := math.Pow(0.5, 1/float64(halfLife))
+	n := len(mean)
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+	}
+	w, total := 1.0, 0.0
+	for t := len(rows) - 1; t >= 0; t-- {
+		r := rows[t]
+		for i := 0; i < n; i++ {
+			di := r[i] - mean[i]
+			for j := 0; j <= i; j++ {
+				cov[i][j] += w * di * (r[j] - mean[j])
+			}
+		}
+		total += w
+		w *= lambda
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			cov[i][j] /= total
+			cov[j][i] = cov[i][j]
+		}
+	}
+	return cov
+}
+
+// ledoitWolf shrinks the sample covariance cov toward the constant-correlation
+// matrix — the sample variances with every pair at the average sample
+// correlation — by the intensity that minimises the expected Frobenius loss
+// (Ledoit & Wolf, "Honey, I Shrunk the Sample Covariance Matrix", 2004). It
+// returns the shrunk matrix and the intensity. Every variance must be
+// positive. With fewer than three assets the target is the sample matrix
+// itself, so there is nothing to shrink.
+func ledoitWolf(rows [][]float64, mean []float64, cov [][]float64) ([][]float64, float64) {
+	n := len(mean)
+	if n < 3 {
+		return cov, 0
+	}
+	sd := make([]float64, n)
+	for i := range sd {
+		sd[i] = math.Sqrt(cov[i][i])
+	}
+	rBar := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				rBar += cov[i][j] / (sd[i] * sd[j])
+			}
+		}
+	}
+	rBar /= float64(n * (n - 1))
+
+	// pi[i][j] estimates the asymptotic variance of √T·s_ij, and theta[i][j]
+	// its covariance with √T·s_ii.
+	pi := make([][]float64, n)
+	theta := make([][]float64, n)
+	for i := range pi {
+		pi[i] = make([]float64, n)
+		theta[i] = make([]float64, n)
+	}
+	x := make([]float64, n)
+	for _, r := range rows {
+		for i := range x {
+			x[i] = r[i] - mean[i]
+		}
+		for i := 0; i < n; i++ {
+			sq := x[i]*x[i] - cov[i][i]
+			for j := 0; j < n; j++ {
+				d := x[i]*x[j] - cov[i][j]
+				pi[i][j] += d * d
+				theta[i][j] += sq * d
+			}
+		}
+	}
+	t := float64(len(rows))
+	var piSum, rho, gamma float64
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			piSum += pi[i][j] / t
+			if i == j {
+				rho += pi[i][i] / t
+				continue
+			}
+			rho += rBar * sd[j] / sd[i] * theta[i][j] / t
+			f := rBar * sd[i] * sd[j]
+			gamma += (f - cov[i][j]) * (f - cov[i][j])
+		}
+	}
+	if gamma == 0 {
+		return cov, 0 // the sample correlations are all equal already
+	}
+	delta := math.Max(0, math.Min(1, (piSum-rho)/gamma/t))
+
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = make([]float64, n)
+		for j := range out[i] {
+			if i == j {
+				out[i][j] = cov[i][i]
+			} else {
+				out[i][j] = (1-delta)*cov[i][j] + delta*rBar*sd[i]*sd[j]
+			}
+		}
+	}
+	return out, delta
+}
+
+// bayesStein shrinks the sample means of a t-day window toward the mean of
+// its minimum-variance portfolio, the grand mean that the covariance cov
+// makes most precise (Jorion, "Bayes-Stein Estimation for Portfolio
+// Analysis", 1986). The noisier the means relative to their spread, the
+// more they are shrunk. It returns the shrunk means and the weight on the
+// grand mean; a single asset is its own grand mean and is left alone.
+func bayesStein(t int, mean []float64, cov [][]float64) ([]float64, float64, error) {
+	n := len(mean)
+	if n < 2 {
+		return mean, 0, nil
+	}
+	if t <= n+2 {
+		return nil, 0, fmt.Errorf("needs more than %d days in the lookback window for %d assets", n+2, n)
+	}
+	// Jorion's unbiased covariance, from the population one.
+	scaled := make([][]float64, n)
+	for i := range scaled {
+		scaled[i] = make([]float64, n)
+		for j := range scaled[i] {
+			scaled[i][j] = cov[i][j] * float64(t) / float64(t-n-2)
+		}
+	}
+	inv, ok := symPower(scaled, -1)
+	if !ok {
+		return nil, 0, errors.New("the lookback window's covariance matrix is singular")
+	}
+	var num, den float64
+	for i := range inv {
+		for j := range inv[i] {
+			num += inv[i][j] * mean[j]
+			den += inv[i][j]
+		}
+	}
+	grand := num / den
+	q := 0.0
+	for i := range inv {
+		for j := range inv[i] {
+			q += (mean[i] - grand) * inv[i][j] * (mean[j] - grand)
+		}
+	}
+	w := float64(n+2) / (float64(n+2) + float64(t)*q)
+	out := make([]float64, n)
+	for i, m := range mean {
+		out[i] = (1-w)*m + w*grand
+	}
+	return out, w, nil
+}
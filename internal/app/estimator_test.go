@@ -0,0 +1,130 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func TestEWMACovarianceWeighsRecentDays(t *testing.T) {
+	// A quiet year, then a month of volatility three times as high.
+	rows := make([][]float64, 273)
+	for i := range rows {
+		v := 0.01
+		if i >= 252 {
+			v = 0.03
+		}
+		if i%2 == 1 {
+			v = -v
+		}
+		rows[i] = []float64{v}
+	}
+	mean := []float64{0}
+	if got := math.Sqrt(ewmaCovariance(rows, mean, 10)[0][0]); got < 0.025 {
+		t.Errorf("10-day half-life volatility = %.4f, want near the recent 0.03", got)
+	}
+	if got := math.Sqrt(ewmaCovariance(rows, mean, 1000)[0][0]); math.Abs(got-0.0125) > 0.002 {
+		t.Errorf("1000-day half-life volatility = %.4f, want near the equal-weighted 0.0125", got)
+	}
+
+	// Three days with a one-day half-life weigh 1/7, 2/7 and 4/7.
+	got := ewmaCovariance([][]float64{{1}, {2}, {3}}, []float64{0}, 1)[0][0]
+	if want := (1.0 + 2*4 + 4*9) / 7; math.Abs(got-want) > 1e-12 {
+		t.Errorf("variance = %g, want %g", got, want)
+	}
+}
+
+func TestLedoitWolfShrinksTowardConstantCorrelation(t *testing.T) {
+	rows := correlatedRows(60, 0.6)
+	for i := range rows {
+		rows[i] = append(rows[i], 0.5*rows[i][0]+0.015*float64(i%7-3)/3)
+	}
+	mean, cov := sampleMoments(rows)
+	got, delta := ledoitWolf(rows, mean, cov)
+	// Reference value from Ledoit and Wolf's covCor.m on the same rows.
+	if math.Abs(delta-0.306560052835) > 1e-9 {
+		t.Errorf("intensity = %.12f, want 0.306560052835", delta)
+	}
+	for i := range cov {
+		if got[i][i] != cov[i][i] {
+			t.Errorf("variance %d = %g, want the sample %g", i, got[i][i], cov[i][i])
+		}
+	}
+	if _, err := cholesky(got); err != nil {
+		t.Errorf("shrunk covariance: %v", err)
+	}
+
+	// More data, less shrinkage.
+	rows = correlatedRows(5000, 0.6)
+	mean, cov = sampleMoments(rows)
+	if _, d := ledoitWolf(rows, mean, cov); d != 0 {
+		t.Errorf("two assets: intensity = %g, want 0 as their one correlation is the average", d)
+	}
+}
+
+func TestBayesSteinShrinksTowardGrandMean(t *testing.T) {
+	// Two uncorrelated assets of equal variance s: the grand mean is the
+	// average and the weight on it is 4 / (4 + (T−4)(a−b)²/2s).
+	const n, s = 100, 1e-4
+	mean := []float64{0.001, 0.0002}
+	cov := [][]float64{{s, 0}, {0, s}}
+	got, w, err := bayesStein(n, mean, cov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := mean[0] - mean[1]
+	if want := 4 / (4 + (n-4)*d*d/(2*s)); math.Abs(w-want) > 1e-12 {
+		t.Errorf("weight = %g, want %g", w, want)
+	}
+	grand := (mean[0] + mean[1]) / 2
+	if math.Abs(got[0]-((1-w)*mean[0]+w*grand)) > 1e-15 || got[0]+got[1]-2*grand > 1e-15 {
+		t.Errorf("means = %v, want shrunk toward %g", got, grand)
+	}
+
+	if got, w, _ := bayesStein(n, mean[:1], cov[:1]); got[0] != mean[0] || w != 0 {
+		t.Errorf("one asset: means %v weight %g, want it left alone", got, w)
+	}
+	if _, _, err := bayesStein(4, mean, cov); err == nil {
+		t.Error("bayesStein accepted a four-day window for two assets")
+	}
+}
+
+func TestMarketInputsUseEstimators(t *testing.T) {
+	rows := correlatedRows(500, 0.6)
+	exp := assumptionsExperiment(nil)
+	exp.Config.Estimator = domain.EstimatorParams{Mean: domain.MeanBayesStein, Cov: domain.CovLedoitWolf}
+
+	got, in, err := marketInputs(rows, exp, gbmModel{}.Spec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mean, cov := sampleMoments(rows)
+	e, err := estimateMoments(rows, mean, cov, exp.Config.Estimator.Normalized())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotMean, gotCov := sampleMoments(got)
+	for i := range gotMean {
+		if math.Abs(gotMean[i]-e.mean[i]) > 1e-12 {
+			t.Errorf("mean %d = %g, want the estimate %g", i, gotMean[i], e.mean[i])
+		}
+		for j := range gotCov[i] {
+			if math.Abs(gotCov[i][j]-e.cov[i][j]) > 1e-12 {
+				t.Errorf("cov[%d][%d] = %g, want the estimate %g", i, j, gotCov[i][j], e.cov[i][j])
+			}
+		}
+	}
+	if in.Source != domain.SourceHistorical || in.Estimator.Cov != domain.CovLedoitWolf || in.MeanShrinkage != e.meanShrinkage || in.MeanShrinkage == 0 {
+		t.Errorf("inputs = %+v, want the estimators and their shrinkage recorded", in)
+	}
+
+	exp.Config.Estimator = domain.EstimatorParams{Cov: domain.CovEWMA}
+	if _, _, err := marketInputs(rows, exp, bootstrapModel{}.Spec()); err == nil || !strings.Contains(err.Error(), "sample estimators") {
+		t.Errorf("bootstrap with ewma: err = %v", err)
+	}
+	if _, in, _ := marketInputs(rows, exp, gbmModel{}.Spec()); in.Estimator.HalfLife != domain.DefaultEWMAHalfLife {
+		t.Errorf("recorded half-life = %d, want the default", in.Estimator.HalfLife)
+	}
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// maxGARCHPersistence caps α + β + γ/2 below one so the fitted variance
+// process stays stationary and the targeted ω stays positive.
+const maxGARCHPersistence = 0.9999
+
+// garchParams holds a fitted constant-correlation GARCH model: one GARCH(1,1)
+// (or GJR) variance process per asset, with daily shocks correlated as the
+// assets' standardised residuals were over the lookback window.
+type garchParams struct {
+	mean []float64   // daily mean log-return per asset
+	gjr  bool        // whether the leverage term γ was fitted
+	fits []garchFit  // per-asset variance process
+	chol [][]float64 // lower Cholesky factor of the residual correlation
+}
+
+// garchFit is one asset's variance process,
+// σ²ₜ = ω + (α + γ·1[eₜ₋₁ < 0])·e²ₜ₋₁ + β·σ²ₜ₋₁, where eₜ is the day's
+// demeaned log-return. gamma is zero unless the GJR term was fitted.
+type garchFit struct {
+	omega, alpha, beta, gamma float64
+	next                      float64 // variance of the first simulated day
+	logLik                    float64
+}
+
+// garchModel is per-asset GARCH(1,1) or GJR variance with constant
+// conditional correlation.
+type garchModel struct{}
+
+func (garchModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelGARCH,
+		Label:            "GARCH(1,1) (volatility clustering)",
+		Description:      "Per-asset variance that clusters, fitted by maximum likelihood and simulated forward from its current level.",
+		Params:           domain.GARCHParams{}.Spec(),
+		TakesAssumptions: true,
+	}
+}
+
+func (garchModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	p, err := estimateGARCHParams(rows, exp.Config.GARCH.GJR, exp.Config.RepairCovariance)
+	if err != nil {
+		return nil, fmt.Errorf("residual correlation of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	return fitted{
+		pathFunc: func(rng *rand.Rand) domain.SimulatedPath { return garchPath(exp.Config, p, alloc, rng) },
+		fit:      p.modelFit(exp.Portfolio),
+	}, nil
+}
+
+// estimateGARCHParams fits each asset's variance process to rows[day][asset]
+// by Gaussian quasi-maximum likelihood, then correlates the assets through
+// the sample correlation of their standardised residuals eₜ/σₜ.
+func estimateGARCHParams(rows [][]float64, gjr, repair bool) (garchParams, error) {
+	mean, cov := sampleMoments(rows)
+	p := garchParams{mean: mean, gjr: gjr, fits: make([]garchFit, len(mean))}
+	resid := make([]float64, len(rows))
+	std := make([][]float64, len(rows))
+	for t := range std {
+		std[t] = make([]float64, len(mean))
+	}
+	for i := range p.fits {
+		for t, r := range rows {
+			resid[t] = r[i] - mean[i]
+		}
+		p.fits[i] = fitGARCH(resid, cov[i][i], gjr)
+		p.fits[i].filter(resid, cov[i][i], func(t int, e, h float64) {
+			if h > 0 { // an asset with no variance keeps zero residuals
+				std[t][i] = e / math.Sqrt(h)
+			}
+		})
+	}
+	_, stdCov := sampleMoments(std)
+	chol, err := factorPSD(correlation(stdCov), repair)
+	if err != nil {
+		return garchParams{}, err
+	}
+	p.chol = chol
+	return p, nil
+}
+
+// fitGARCH fits a variance process to the demeaned returns resid whose sample
+// variance is v. The unconditional variance is targeted at v, fixing
+// ω = v·(1 − α − β − γ/2), so only α, β and γ are searched; the long-run
+// volatility of the simulation then matches history as GBM's does.
+func fitGARCH(resid []float64, v float64, gjr bool) garchFit {
+	if v <= 0 {
+		return garchFit{}
+	}
+	fit := func(x []float64) garchFit {
+		f := garchFit{alpha: x[0], beta: x[1]}
+		if gjr {
+			f.gamma = x[2]
+		}
+		f.omega = v * (1 - f.persistence())
+		return f
+	}
+	logLik := func(x []float64) float64 {
+		f := fit(x)
+		if f.alpha < 0 || f.beta < 0 || f.alpha+f.gamma < 0 || f.persistence() > maxGARCHPersistence {
+			return math.Inf(-1)
+		}
+		var ll float64
+		f.filter(resid, v, func(_ int, e, h float64) {
+			ll -= 0.5 * (math.Log(2*math.Pi*h) + e*e/h)
+		})
+		return ll
+	}
+	x0, step := []float64{0.05, 0.9}, []float64{0.03, 0.03}
+	if gjr {
+		x0, step = []float64{0.03, 0.9, 0.04}, []float64{0.02, 0.03, 0.03}
+	}
+	x, ll := nelderMeadMax(logLik, x0, step, 1e-9, 2000)
+	f := fit(x)
+	f.logLik = ll
+	f.next = f.filter(resid, v, func(int, float64, float64) {})
+	return f
+}
+
+// persistence is α + β + γ/2, the rate at which a variance shock decays
+// when falls and rises are equally likely.
+func (f garchFit) persistence() float64 {
+	return f.alpha + f.beta + f.gamma/2
+}
+
+// update returns the variance of the day after one with residual e and
+// variance h.
+func (f garchFit) update(e, h float64) float64 {
+	a := f.alpha
+	if e < 0 {
+		a += f.gamma
+	}
+	return f.omega + a*e*e + f.beta*h
+}
+
+// filter runs the variance process over resid, starting from variance h0,
+// and calls fn with each day's index, residual and conditional variance. It
+// returns the variance of the day after the last.
+func (f garchFit) filter(resid []float64, h0 float64, fn func(t int, e, h float64)) float64 {
+	h := h0
+	for t, e := range resid {
+		fn(t, e, h)
+		h = f.update(e, h)
+	}
+	return h
+}
+
+// modelFit records each asset's variance process. Volatilities are
+// annualised: long_run_vol is the targeted unconditional level and
+// start_vol that of the first simulated day.
+func (p garchParams) modelFit(pf domain.Portfolio) *domain.ModelFit {
+	fit := &domain.ModelFit{Model: domain.ModelGARCH}
+	for i, f := range p.fits {
+		params := map[string]float64{
+			"omega":        f.omega,
+			"alpha":        f.alpha,
+			"beta":         f.beta,
+			"persistence":  f.persistence(),
+			"long_run_vol": math.Sqrt(252 * f.omega / (1 - f.persistence())),
+			"start_vol":    math.Sqrt(252 * f.next),
+		}
+		if p.gjr {
+			params["gamma"] = f.gamma
+		}
+		fit.Assets = append(fit.Assets, domain.AssetFit{Symbol: pf.Assets[i].Symbol, LogLikelihood: f.logLik, Params: params})
+		fit.LogLikelihood += f.logLik
+	}
+	return fit
+}
+
+// garchPath simulates every asset's variance forward from its fitted value
+// for the first day, so stress at the end of the lookback window carries into
+// the forecast before decaying towards the long-run level.
+func garchPath(cfg domain.SimulationConfig, p garchParams, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	h := make([]float64, len(p.fits))
+	for i, f := range p.fits {
+		h[i] = f.next
+	}
+	z := make([]float64, len(h))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
+		}
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += p.chol[i][j] * z[j]
+			}
+			e := math.Sqrt(h[i]) * shock
+			lr[i] = p.mean[i] + e
+			h[i] = p.fits[i].update(e, h[i])
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// garchRows simulates n days of one asset's returns from f, starting at its
+// unconditional variance.
+func garchRows(rng *rand.Rand, n int, f garchFit) [][]float64 {
+	rows := make([][]float64, n)
+	h := f.omega / (1 - f.persistence())
+	for t := range rows {
+		e := math.Sqrt(h) * rng.NormFloat64()
+		rows[t] = []float64{0.0003 + e}
+		h = f.update(e, h)
+	}
+	return rows
+}
+
+func TestFitGARCHRecoversParams(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		gjr  bool
+		want garchFit
+	}{
+		{name: "garch", want: garchFit{omega: 2e-6, alpha: 0.08, beta: 0.9}},
+		{name: "gjr", gjr: true, want: garchFit{omega: 2e-6, alpha: 0.03, beta: 0.88, gamma: 0.12}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewChaCha8([32]byte{10}))
+			p, err := estimateGARCHParams(garchRows(rng, 8000, tc.want), tc.gjr, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.fits[0]
+			if math.Abs(got.alpha-tc.want.alpha) > 0.025 || math.Abs(got.beta-tc.want.beta) > 0.03 || math.Abs(got.gamma-tc.want.gamma) > 0.05 {
+				t.Errorf("fitted α=%.3f β=%.3f γ=%.3f, want %v/%v/%v", got.alpha, got.beta, got.gamma, tc.want.alpha, tc.want.beta, tc.want.gamma)
+			}
+			if got.logLik <= 0 || got.next <= 0 {
+				t.Errorf("logLik = %v, next = %v; want both positive for daily-scale returns", got.logLik, got.next)
+			}
+		})
+	}
+}
+
+func TestFitGARCHConstantPrices(t *testing.T) {
+	p, err := estimateGARCHParams([][]float64{{0, 0.01}, {0, -0.01}, {0, 0.02}}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := p.fits[0]; f != (garchFit{}) {
+		t.Errorf("fit of a constant series = %+v, want zero", f)
+	}
+	if p.chol[1][0] != 0 || p.chol[1][1] != 1 {
+		t.Errorf("chol = %v, want the constant asset uncorrelated", p.chol)
+	}
+}
+
+func TestGARCHPathStartsFromCurrentStress(t *testing.T) {
+	f := garchFit{alpha: 0.1, beta: 0.85}
+	f.omega = 1e-4 * (1 - f.persistence())
+	calm, stressed := f, f
+	calm.next, stressed.next = 1e-4/4, 1e-4*4
+	cfg := domain.SimulationConfig{HorizonDays: 250, StartValue: 1}
+	alloc := allocation{weights: []float64{1}}
+	rng := rand.New(rand.NewChaCha8([32]byte{11}))
+
+	// variances returns the variance across paths of the first and last
+	// days' log-returns.
+	variances := func(f garchFit) (first, last float64) {
+		p := garchParams{mean: []float64{0}, fits: []garchFit{f}, chol: [][]float64{{1}}}
+		const n = 10_000
+		for range n {
+			v := garchPath(cfg, p, alloc, rng).Values
+			a, b := math.Log(v[1]/v[0]), math.Log(v[250]/v[249])
+			first += a * a / n
+			last += b * b / n
+		}
+		return first, last
+	}
+	for _, f := range []garchFit{calm, stressed} {
+		first, last := variances(f)
+		if math.Abs(first/f.next-1) > 0.08 {
+			t.Errorf("first-day variance = %g, want %g", first, f.next)
+		}
+		if math.Abs(last/1e-4-1) > 0.1 {
+			t.Errorf("variance from %g after a year = %g, want back near %g", f.next, last, 1e-4)
+		}
+	}
+}
+
+func TestGARCHModelFit(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{12}))
+	rows := garchRows(rng, 2000, garchFit{omega: 2e-6, alpha: 0.08, beta: 0.9})
+	p, err := estimateGARCHParams(rows, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fit := p.modelFit(domain.Portfolio{Assets: []domain.PortfolioAsset{{Symbol: "SPY", Weight: 1}}})
+	if fit.Model != domain.ModelGARCH || len(fit.Assets) != 1 || fit.Assets[0].Symbol != "SPY" || fit.LogLikelihood != p.fits[0].logLik {
+		t.Fatalf("fit = %+v", fit)
+	}
+	params := fit.Assets[0].Params
+	for _, k := range []string{"omega", "alpha", "beta", "gamma", "persistence", "long_run_vol", "start_vol"} {
+		if _, ok := params[k]; !ok {
+			t.Errorf("params lack %q: %v", k, params)
+		}
+	}
+	_, cov := sampleMoments(rows)
+	if want := math.Sqrt(252 * cov[0][0]); math.Abs(params["long_run_vol"]-want) > 1e-9 {
+		t.Errorf("long_run_vol = %v, want the sample volatility %v", params["long_run_vol"], want)
+	}
+}
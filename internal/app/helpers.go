@@ -4,6 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
+
+	"github.com/gjcourt/drift/internal/domain"
 )
 
 // newID generates a random prefixed ID e.g. "run_a3f9c2...". It returns an
@@ -17,3 +20,12 @@ func newID(prefix string) (string, error) {
 	}
 	return prefix + "_" + hex.EncodeToString(b), nil
 }
+
+// portfolioSymbols returns the portfolio's symbols joined for error messages.
+func portfolioSymbols(p domain.Portfolio) string {
+	syms := make([]string, len(p.Assets))
+	for i, a := range p.Assets {
+		syms[i] = a.Symbol
+	}
+	return strings.Join(syms, "/")
+}
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// hestonWindow is the number of trading days in each realized-variance
+// estimate used to calibrate the Heston model.
+const hestonWindow = 21
+
+// Calibrated mean-reversion rates are kept within these bounds, per year.
+const (
+	minHestonKappa = 0.1
+	maxHestonKappa = 50
+)
+
+// hestonParams holds a multi-asset Heston model: each asset has its own CIR
+// variance process, and the assets' price shocks are correlated as their
+// returns were over the lookback window.
+type hestonParams struct {
+	mean       []float64   // daily mean log-return per asset
+	fits       []hestonFit // per-asset variance process
+	chol       [][]float64 // lower Cholesky factor of the return correlation
+	calibrated bool        // whether fits were estimated rather than configured
+}
+
+// hestonFit is one asset's annualised variance process,
+// dv = κ(θ − v)dt + ξ√v dW, where dW has correlation ρ with the asset's
+// price shock.
+type hestonFit struct {
+	kappa, theta, xi, rho float64
+	v0                    float64 // variance of the first simulated day
+}
+
+// hestonModel is per-asset Heston stochastic volatility.
+type hestonModel struct{}
+
+func (hestonModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelHeston,
+		Label:            "Heston (stochastic volatility)",
+		Description:      "Per-asset mean-reverting variance correlated with the price, calibrated from realized variance or set by hand.",
+		Params:           domain.HestonParams{}.Spec(),
+		TakesAssumptions: true,
+	}
+}
+
+func (hestonModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	p, err := estimateHestonParams(rows, exp.Config.Heston, exp.Config.RepairCovariance)
+	if err != nil {
+		return nil, fmt.Errorf("variance of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	c := fitted{pathFunc: func(rng *rand.Rand) domain.SimulatedPath { return hestonPath(exp.Config, p, alloc, rng) }}
+	if p.calibrated {
+		c.fit = p.modelFit(exp.Portfolio)
+	}
+	return c, nil
+}
+
+// estimateHestonParams builds the Heston model for rows[day][asset]. Every
+// asset's first simulated variance is its realized variance over the last
+// hestonWindow days. With hp.Kappa set every asset takes the
+// configured process (θ defaulting to the asset's sample variance);
+// otherwise each asset's process is calibrated by calibrateHeston.
+func estimateHestonParams(rows [][]float64, hp domain.HestonParams, repair bool) (hestonParams, error) {
+	calibrate := hp.Kappa == 0
+	if minDays := 4 * hestonWindow; calibrate && len(rows) < minDays {
+		return hestonParams{}, fmt.Errorf("need at least %d days of returns to calibrate heston, have %d", minDays, len(rows))
+	}
+	mean, cov := sampleMoments(rows)
+	chol, err := factorPSD(correlation(cov), repair)
+	if err != nil {
+		return hestonParams{}, err
+	}
+	p := hestonParams{mean: mean, fits: make([]hestonFit, len(mean)), chol: chol, calibrated: calibrate}
+	resid := make([]float64, len(rows))
+	for i := range p.fits {
+		for t, r := range rows {
+			resid[t] = r[i] - mean[i]
+		}
+		rv := realizedVariance(resid, min(hestonWindow, len(resid)))
+		theta := 252 * cov[i][i]
+		if calibrate {
+			p.fits[i] = calibrateHeston(resid, rv, theta)
+		} else {
+			if hp.Theta > 0 {
+				theta = hp.Theta
+			}
+			p.fits[i] = hestonFit{kappa: hp.Kappa, theta: theta, xi: hp.Xi, rho: hp.Rho}
+		}
+		p.fits[i].v0 = rv[len(rv)-1]
+	}
+	return p, nil
+}
+
+// realizedVariance returns the annualised mean square of resid over each
+// window of w consecutive days; element t covers days t to t+w−1.
+func realizedVariance(resid []float64, w int) []float64 {
+	rv := make([]float64, len(resid)-w+1)
+	var sum float64
+	for t, e := range resid {
+		sum += e * e
+		if t >= w {
+			sum -= resid[t-w] * resid[t-w]
+		}
+		if t >= w-1 {
+			rv[t-w+1] = 252 * max(sum, 0) / float64(w)
+		}
+	}
+	return rv
+}
+
+// calibrateHeston fits one asset's variance process to the moments of its
+// realized variance rv over hestonWindow-day windows, with θ targeted at the
+// sample variance theta as GARCH's is. Each window's realized variance is the
+// window's average true variance plus sampling noise of variance
+// 2·E[v²]/hestonWindow, which is removed first. For a CIR process the
+// variance of a window average, and its covariance with the next window's,
+// are known functions of κ and Var(v) = θξ²/(2κ): their ratio gives κ and
+// then the variance gives ξ. Likewise ρ follows from the covariance of each
+// window's summed return with the change in realized variance across it.
+func calibrateHeston(resid, rv []float64, theta float64) hestonFit {
+	const w = hestonWindow
+	f := hestonFit{kappa: maxHestonKappa, theta: theta}
+	var m, sq float64
+	for _, v := range rv {
+		m += v
+		sq += v * v
+	}
+	m /= float64(len(rv))
+	sq /= float64(len(rv))
+	noise := 2 * sq / (w + 2)
+	signal := sq - m*m - noise // variance of the windows' average true variance
+
+	var lag, sumR, sumD, sumRD float64
+	n := len(rv) - w
+	for t := range n {
+		lag += (rv[t] - m) * (rv[t+w] - m)
+		var ret float64
+		for _, e := range resid[t+w : t+2*w] {
+			ret += e
+		}
+		d := rv[t+w] - rv[t]
+		sumR += ret
+		sumD += d
+		sumRD += ret * d
+	}
+	lag /= float64(n)
+	retCov := (sumRD - sumR*sumD/float64(n)) / float64(n)
+	if signal <= 0 || lag <= 0 || theta <= 0 {
+		return f // no detectable variation in the variance
+	}
+
+	// With a = κ·w/252, the window average has variance Var(v)·avgVar(a)
+	// and covariance Var(v)·(1 − e^−a)²/a² with the next window's, and its
+	// change across a window has covariance ρξθ·(w/252)·avgVar(a)/2 with the
+	// window's return.
+	avgVar := func(a float64) float64 { return 2 * (a + math.Expm1(-a)) / (a * a) }
+	ratio := func(a float64) float64 { return -math.Expm1(-a) * -math.Expm1(-a) / (a * a) / avgVar(a) }
+	b := lag / signal
+	lo, hi := minHestonKappa*w/252.0, maxHestonKappa*w/252.0
+	switch {
+	case b >= ratio(lo):
+		hi = lo
+	case b <= ratio(hi):
+		lo = hi
+	}
+	for range 100 { // ratio falls as a rises
+		mid := (lo + hi) / 2
+		if ratio(mid) > b {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	a := (lo + hi) / 2
+	f.kappa = 252 * a / w
+	f.xi = math.Sqrt(2 * f.kappa * signal / avgVar(a) / theta)
+	f.rho = max(-1, min(1, 2*retCov/(f.xi*theta*w/252*avgVar(a))))
+	return f
+}
+
+// modelFit records each calibrated asset's variance process, with its
+// annualised long-run and starting volatilities and the Feller ratio
+// 2κθ/ξ² (above one, the variance never touches zero).
+func (p hestonParams) modelFit(pf domain.Portfolio) *domain.ModelFit {
+	fit := &domain.ModelFit{Model: domain.ModelHeston}
+	for i, f := range p.fits {
+		params := map[string]float64{
+			"kappa":        f.kappa,
+			"theta":        f.theta,
+			"xi":           f.xi,
+			"rho":          f.rho,
+			"long_run_vol": math.Sqrt(f.theta),
+			"start_vol":    math.Sqrt(f.v0),
+		}
+		if f.xi > 0 {
+			params["feller"] = 2 * f.kappa * f.theta / (f.xi * f.xi)
+		}
+		fit.Assets = append(fit.Assets, domain.AssetFit{Symbol: pf.Assets[i].Symbol, Params: params})
+	}
+	return fit
+}
+
+// hestonPath steps every asset's price and variance forward a trading day at
+// a time by the full-truncation Euler scheme: the variance may go negative
+// between steps, but only its positive part drives the price, the drift and
+// the diffusion, so the simulated volatility is always real.
+func hestonPath(cfg domain.SimulationConfig, p hestonParams, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	const dt = 1.0 / 252
+	v := make([]float64, len(p.fits))
+	for i, f := range p.fits {
+		v[i] = f.v0
+	}
+	z := make([]float64, len(v))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
+		}
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += p.chol[i][j] * z[j]
+			}
+			f := p.fits[i]
+			vol := math.Sqrt(max(v[i], 0) * dt)
+			lr[i] = p.mean[i] + vol*shock
+			dw := f.rho*shock + math.Sqrt(1-f.rho*f.rho)*rng.NormFloat64()
+			v[i] += f.kappa*(f.theta-max(v[i], 0))*dt + f.xi*vol*dw
+		}
+	})
+}
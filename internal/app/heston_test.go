@@ -0,0 +1,116 @@
+package app
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// hestonRows simulates n days of one asset's log-returns from the Heston
+// process f.
+func hestonRows(rng *rand.Rand, n int, f hestonFit) [][]float64 {
+	p := hestonParams{mean: []float64{0}, fits: []hestonFit{f}, chol: [][]float64{{1}}}
+	cfg := domain.SimulationConfig{HorizonDays: n, StartValue: 1}
+	v := hestonPath(cfg, p, allocation{weights: []float64{1}}, rng).Values
+	rows := make([][]float64, n)
+	for t := range rows {
+		rows[t] = []float64{math.Log(v[t+1] / v[t])}
+	}
+	return rows
+}
+
+func TestCalibrateHestonRecoversProcess(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{20}))
+	want := hestonFit{kappa: 3, theta: 0.04, xi: 0.4, rho: -0.7, v0: 0.04}
+	p, err := estimateHestonParams(hestonRows(rng, 50_000, want), domain.HestonParams{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := p.fits[0]
+	if math.Abs(got.theta/want.theta-1) > 0.1 {
+		t.Errorf("theta = %.4f, want ~%v", got.theta, want.theta)
+	}
+	if got.kappa < 1.5 || got.kappa > 6 {
+		t.Errorf("kappa = %.2f, want ~%v", got.kappa, want.kappa)
+	}
+	if math.Abs(got.xi/want.xi-1) > 0.3 {
+		t.Errorf("xi = %.3f, want ~%v", got.xi, want.xi)
+	}
+	if math.Abs(got.rho-want.rho) > 0.2 {
+		t.Errorf("rho = %.2f, want ~%v", got.rho, want.rho)
+	}
+}
+
+func TestEstimateHestonParamsConfigured(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{21}))
+	rows := make([][]float64, 30)
+	for t := range rows {
+		rows[t] = []float64{0.01 * rng.NormFloat64(), 0.02 * rng.NormFloat64()}
+	}
+	p, err := estimateHestonParams(rows, domain.HestonParams{Kappa: 2, Xi: 0.3, Rho: -0.5}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.calibrated {
+		t.Error("configured parameters reported as calibrated")
+	}
+	_, cov := sampleMoments(rows)
+	for i, f := range p.fits {
+		if f.kappa != 2 || f.xi != 0.3 || f.rho != -0.5 || f.theta != 252*cov[i][i] {
+			t.Errorf("asset %d: fit = %+v, want the configured process around its sample variance", i, f)
+		}
+		if f.v0 <= 0 {
+			t.Errorf("asset %d: start variance %g", i, f.v0)
+		}
+	}
+
+	if _, err := estimateHestonParams(rows, domain.HestonParams{}, false); err == nil {
+		t.Error("calibrated 30 days without error")
+	}
+}
+
+func TestHestonPathVarianceStaysReal(t *testing.T) {
+	// ξ far above the Feller bound drives the Euler variance below zero;
+	// full truncation must keep every step finite.
+	p := hestonParams{
+		mean: []float64{0},
+		fits: []hestonFit{{kappa: 1, theta: 0.04, xi: 2, rho: -0.9, v0: 0.01}},
+		chol: [][]float64{{1}},
+	}
+	rng := rand.New(rand.NewChaCha8([32]byte{22}))
+	cfg := domain.SimulationConfig{HorizonDays: 2520, StartValue: 1}
+	for range 20 {
+		for d, v := range hestonPath(cfg, p, allocation{weights: []float64{1}}, rng).Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) || v <= 0 {
+				t.Fatalf("day %d value %v", d, v)
+			}
+		}
+	}
+}
+
+func TestHestonPathLongRunVariance(t *testing.T) {
+	// Starting far above θ, the variance decays towards it at rate κ.
+	f := hestonFit{kappa: 4, theta: 0.04, xi: 0.3, rho: -0.5, v0: 0.25}
+	p := hestonParams{mean: []float64{0}, fits: []hestonFit{f}, chol: [][]float64{{1}}}
+	rng := rand.New(rand.NewChaCha8([32]byte{23}))
+	cfg := domain.SimulationConfig{HorizonDays: 504, StartValue: 1}
+	const n = 5000
+	var first, last float64
+	for range n {
+		v := hestonPath(cfg, p, allocation{weights: []float64{1}}, rng).Values
+		r0, r1 := math.Log(v[1]/v[0]), math.Log(v[504]/v[503])
+		first += r0 * r0
+		last += r1 * r1
+	}
+	first *= 252.0 / n
+	last *= 252.0 / n
+	if math.Abs(first/f.v0-1) > 0.1 {
+		t.Errorf("first-day variance %.4f, want ~%v", first, f.v0)
+	}
+	// After two years E[v] = θ + (v0 − θ)e^{−2κ} ≈ θ.
+	if math.Abs(last/f.theta-1) > 0.15 {
+		t.Errorf("last-day variance %.4f, want ~%v", last, f.theta)
+	}
+}
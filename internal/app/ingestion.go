@@ -4,7 +4,9 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/gjcourt/drift/internal/domain"
 	"github.com/gjcourt/drift/internal/ports/outbound"
@@ -25,6 +27,10 @@ func (s *ingestionSvc) IngestCSV(ctx context.Context, r io.Reader, filename stri
 	if err != nil {
 		return 0, err
 	}
+	return s.IngestRecords(ctx, records)
+}
+
+func (s *ingestionSvc) IngestRecords(ctx context.Context, records []domain.PriceRecord) (int, error) {
 	symbolsSeen := map[string]bool{}
 	for _, rec := range records {
 		if !symbolsSeen[rec.Symbol] {
@@ -44,10 +50,32 @@ func (s *ingestionSvc) ListAssets(ctx context.Context) ([]domain.Asset, error) {
 	return s.assetRepo.ListAssets(ctx)
 }
 
+func (s *ingestionSvc) GetAsset(ctx context.Context, symbol string) (*domain.Asset, error) {
+	return s.assetRepo.GetAsset(ctx, symbol)
+}
+
 func (s *ingestionSvc) GetAssetPrices(ctx context.Context, symbol string, limit int) ([]domain.PriceRecord, error) {
 	return s.assetRepo.GetPriceRecords(ctx, symbol, limit)
 }
 
+func (s *ingestionSvc) GetAssetPricesBetween(ctx context.Context, symbol string, from, to time.Time) ([]domain.PriceRecord, error) {
+	return s.assetRepo.GetPriceRecordsBetween(ctx, symbol, from, to)
+}
+
 func (s *ingestionSvc) DeleteAsset(ctx context.Context, symbol string) error {
 	return s.assetRepo.DeleteAsset(ctx, symbol)
 }
+
+// ParseAssumptions reports problems found in the assumptions themselves as a
+// domain.FieldErrors; whether they cover a portfolio is checked with the
+// experiment.
+func (s *ingestionSvc) ParseAssumptions(r io.Reader) (*domain.CapitalMarketAssumptions, error) {
+	a, err := s.csvParser.ParseAssumptionsCSV(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalid, err)
+	}
+	if errs := a.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+	return &a, nil
+}
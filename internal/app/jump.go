@@ -0,0 +1,203 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// jumpThreshold is how many robust standard deviations a day's return must
+// move, in any asset, for the day to be treated as a jump when calibrating.
+const jumpThreshold = 4
+
+// jumpParams holds a Merton jump-diffusion: GBM's correlated diffusion plus
+// jumps arriving as one Poisson process for the whole portfolio, so a crash
+// hits every asset on the same day. Asset i's log-jump is mean[i] + vol[i]·ξ
+// with ξ ~ N(0, 1) shared by every asset in the jump.
+type jumpParams struct {
+	gbmParams
+	intensity float64   // expected jumps per year
+	mean, vol []float64 // per-asset log-jump mean and standard deviation
+	drift     []float64 // daily diffusion drift, net of the expected jump
+	jumpDays  int       // days detected as jumps when calibrated
+}
+
+// jumpDiffusionModel is the Merton jump-diffusion.
+type jumpDiffusionModel struct{}
+
+func (jumpDiffusionModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelJumpDiffusion,
+		Label:            "Jump-Diffusion (crash risk)",
+		Description:      "GBM plus portfolio-wide Poisson crashes, calibrated from history or set by hand.",
+		Params:           domain.JumpParams{}.Spec(),
+		TakesAssumptions: true,
+	}
+}
+
+func (jumpDiffusionModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	p, err := estimateJumpParams(rows, exp.Config.Jump, exp.Config.RepairCovariance)
+	if err != nil {
+		return nil, fmt.Errorf("covariance of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	c := fitted{pathFunc: func(rng *rand.Rand) domain.SimulatedPath { return jumpPath(exp.Config, p, alloc, rng) }}
+	if exp.Config.Jump.Intensity == 0 {
+		c.fit = p.modelFit(exp.Portfolio)
+	}
+	return c, nil
+}
+
+// estimateJumpParams builds the jump-diffusion for rows[day][asset]. With
+// jp.Intensity set every asset takes the configured jumps on top of the
+// GBM fitted to the whole window. Otherwise days moving more than
+// jumpThreshold robust standard deviations are jumps: the diffusion is fitted
+// to the other days, and each asset's jump mean and variance are its excess
+// return and excess variance on the jump days. Either way the drift is set so
+// the expected daily log-return is the sample mean, so jumps add crash risk
+// without changing the historical growth rate.
+func estimateJumpParams(rows [][]float64, jp domain.JumpParams, repair bool) (jumpParams, error) {
+	var p jumpParams
+	mean, _ := sampleMoments(rows)
+	n := len(mean)
+	if jp.Intensity > 0 {
+		p.gbmParams = estimateGBMParams(rows)
+		p.intensity = jp.Intensity
+		p.mean, p.vol = make([]float64, n), make([]float64, n)
+		for i := range p.mean {
+			p.mean[i], p.vol[i] = jp.Mean, jp.Vol
+		}
+	} else {
+		med, sd := make([]float64, n), make([]float64, n)
+		for i := range med {
+			med[i], sd[i] = robustScale(rows, i)
+		}
+		var calm, jumps [][]float64
+		for _, r := range rows {
+			if isJump(r, med, sd) {
+				jumps = append(jumps, r)
+			} else {
+				calm = append(calm, r)
+			}
+		}
+		if len(calm) == 0 {
+			calm = rows
+		}
+		p.gbmParams = estimateGBMParams(calm)
+		p.jumpDays = len(jumps)
+		p.intensity = 252 * float64(len(jumps)) / float64(len(rows))
+		p.mean, p.vol = make([]float64, n), make([]float64, n)
+		if len(jumps) > 0 {
+			calmMean, calmCov := sampleMoments(calm)
+			jumpMean, jumpCov := sampleMoments(jumps)
+			for i := range p.mean {
+				p.mean[i] = jumpMean[i] - calmMean[i]
+				p.vol[i] = math.Sqrt(max(0, jumpCov[i][i]-calmCov[i][i]))
+			}
+		}
+	}
+	if err := p.factor(repair); err != nil {
+		return jumpParams{}, err
+	}
+	p.drift = make([]float64, n)
+	for i := range p.drift {
+		p.drift[i] = mean[i] - p.intensity/252*p.mean[i]
+	}
+	return p, nil
+}
+
+// isJump reports whether any asset's return on day r lies more than
+// jumpThreshold robust standard deviations sd from its median med.
+func isJump(r, med, sd []float64) bool {
+	for i, v := range r {
+		if sd[i] > 0 && math.Abs(v-med[i]) > jumpThreshold*sd[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// robustScale returns the median of asset i's returns and 1.4826 times their
+// median absolute deviation, which estimates the standard deviation of the
+// normal days without being inflated by the jumps.
+func robustScale(rows [][]float64, i int) (median, sd float64) {
+	xs := make([]float64, len(rows))
+	for t, r := range rows {
+		xs[t] = r[i]
+	}
+	median = middle(xs)
+	for t := range xs {
+		xs[t] = math.Abs(xs[t] - median)
+	}
+	return median, 1.4826 * middle(xs)
+}
+
+// middle returns the median of xs, sorting it in place.
+func middle(xs []float64) float64 {
+	slices.Sort(xs)
+	n := len(xs)
+	if n%2 == 1 {
+		return xs[n/2]
+	}
+	return (xs[n/2-1] + xs[n/2]) / 2
+}
+
+// modelFit records the calibrated jumps: the annual intensity and number of
+// jump days, and each asset's log-jump mean and volatility.
+func (p jumpParams) modelFit(pf domain.Portfolio) *domain.ModelFit {
+	fit := &domain.ModelFit{
+		Model:  domain.ModelJumpDiffusion,
+		Params: map[string]float64{"intensity": p.intensity, "jump_days": float64(p.jumpDays)},
+	}
+	for i := range p.mean {
+		fit.Assets = append(fit.Assets, domain.AssetFit{
+			Symbol: pf.Assets[i].Symbol,
+			Params: map[string]float64{"jump_mean": p.mean[i], "jump_vol": p.vol[i]},
+		})
+	}
+	return fit
+}
+
+// jumpPath is gbmPath plus, on each day, a Poisson number of jumps whose
+// summed log-size is added to every asset's return.
+func jumpPath(cfg domain.SimulationConfig, p jumpParams, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	rate := p.intensity / 252
+	z := make([]float64, len(p.mu))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
+		}
+		jumps := poisson(rng, rate)
+		xi := 0.0
+		if jumps > 0 {
+			xi = rng.NormFloat64()
+		}
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += p.chol[i][j] * z[j]
+			}
+			lr[i] = p.drift[i] + shock
+			if jumps > 0 {
+				// The sum of k normal jumps is normal with k times the mean
+				// and variance.
+				k := float64(jumps)
+				lr[i] += k*p.mean[i] + math.Sqrt(k)*p.vol[i]*xi
+			}
+		}
+	})
+}
+
+// poisson draws from the Poisson distribution with the given mean by
+// counting uniform products (Knuth); the daily jump rates are far below one.
+func poisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	k := 0
+	for prod := rng.Float64(); prod > limit; prod *= rng.Float64() {
+		k++
+	}
+	return k
+}
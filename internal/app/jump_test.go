@@ -0,0 +1,96 @@
+package app
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func TestEstimateJumpParamsDetectsJumps(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{13}))
+	rows := make([][]float64, 2520)
+	for i := range rows {
+		z := rng.NormFloat64()
+		rows[i] = []float64{0.0004 + 0.01*z, 0.0002 + 0.005*(0.5*z+0.87*rng.NormFloat64())}
+		if i%126 == 60 { // two crashes a year, both assets down together
+			rows[i][0] += -0.08 + 0.01*rng.NormFloat64()
+			rows[i][1] += -0.04 + 0.01*rng.NormFloat64()
+		}
+	}
+	p, err := estimateJumpParams(rows, domain.JumpParams{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.jumpDays != 20 || math.Abs(p.intensity-2) > 1e-9 {
+		t.Errorf("detected %d jump days (intensity %.2f), want 20 (2/yr)", p.jumpDays, p.intensity)
+	}
+	if math.Abs(p.mean[0]+0.08) > 0.01 || math.Abs(p.mean[1]+0.04) > 0.01 {
+		t.Errorf("jump means = %v, want ~[-0.08 -0.04]", p.mean)
+	}
+	if math.Abs(p.vol[0]-0.01) > 0.006 {
+		t.Errorf("jump vol = %v, want ~0.01", p.vol[0])
+	}
+	if vol := math.Sqrt(p.cov[0][0] / 252); math.Abs(vol-0.01) > 0.001 {
+		t.Errorf("diffusion vol = %v, want ~0.01 with the jumps excluded", vol)
+	}
+}
+
+func TestEstimateJumpParamsWithoutJumps(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{14}))
+	rows := make([][]float64, 500)
+	for i := range rows {
+		rows[i] = []float64{0.01 * rng.NormFloat64()}
+	}
+	p, err := estimateJumpParams(rows, domain.JumpParams{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.jumpDays > 1 {
+		t.Errorf("detected %d jump days in normal returns", p.jumpDays)
+	}
+}
+
+func TestJumpPathKeepsMeanAndAddsCrashes(t *testing.T) {
+	rows := [][]float64{{0.01}, {-0.01}, {0.012}, {-0.008}}
+	cfg := domain.SimulationConfig{HorizonDays: 252, StartValue: 1, Jump: domain.JumpParams{Intensity: 1, Mean: -0.2, Vol: 0.05}}
+	p, err := estimateJumpParams(rows, cfg.Jump, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewChaCha8([32]byte{15}))
+	var sum float64
+	crashed := 0
+	const n = 20_000
+	for range n {
+		v := jumpPath(cfg, p, allocation{weights: []float64{1}}, rng).Values
+		sum += math.Log(v[len(v)-1])
+		for d := 1; d < len(v); d++ {
+			if v[d]/v[d-1] < 0.9 {
+				crashed++
+				break
+			}
+		}
+	}
+	if mean, want := sum/n, 252*0.001; math.Abs(mean-want) > 0.005 {
+		t.Errorf("mean annual log-return = %v, want the sample mean %v", mean, want)
+	}
+	// With one jump a year, 1 − e⁻¹ of paths see at least one.
+	if frac := float64(crashed) / n; math.Abs(frac-(1-math.Exp(-1))) > 0.02 {
+		t.Errorf("%.3f of paths had a >10%% daily fall, want ~%.3f", frac, 1-math.Exp(-1))
+	}
+}
+
+func TestPoissonMean(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{16}))
+	for _, mean := range []float64{0, 0.01, 2} {
+		total := 0
+		for range 100_000 {
+			total += poisson(rng, mean)
+		}
+		if got := float64(total) / 100_000; math.Abs(got-mean) > 0.02*max(mean, 0.5) {
+			t.Errorf("mean of Poisson(%v) = %v", mean, got)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errNotPositiveDefinite is returned by cholesky when the input matrix has a
+// non-positive pivot, i.e. it is not symmetric positive-definite.
+var errNotPositiveDefinite = errors.New("matrix is not positive-definite")
+
+// cholesky returns the lower-triangular factor L such that L·Lᵀ = a.
+// The input must be square and symmetric; only the lower triangle is read.
+// A zero-variance asset, such as one whose price never moves, has a zero
+// row and column in a; its column of L is left zero, so it takes no shock.
+func cholesky(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+	}
+	for j := 0; j < n; j++ {
+		d := a[j][j]
+		for k := 0; k < j; k++ {
+			d -= l[j][k] * l[j][k]
+		}
+		if a[j][j] == 0 && d == 0 {
+			for i := j + 1; i < n; i++ {
+				if a[i][j] != 0 {
+					return nil, fmt.Errorf("%w: asset %d has zero variance but covaries with asset %d", errNotPositiveDefinite, j, i)
+				}
+			}
+			continue
+		}
+		if d <= 0 || math.IsNaN(d) {
+			return nil, fmt.Errorf("%w: pivot %d is %g", errNotPositiveDefinite, j, d)
+		}
+		l[j][j] = math.Sqrt(d)
+		for i := j + 1; i < n; i++ {
+			s := a[i][j]
+			for k := 0; k < j; k++ {
+				s -= l[i][k] * l[j][k]
+			}
+			l[i][j] = s / l[j][j]
+		}
+	}
+	return l, nil
+}
+
+// mahalanobis returns xᵀ(L·Lᵀ)⁻¹x for the lower Cholesky factor l, solving
+// L·y = x by forward substitution into the scratch slice y. A dimension with
+// a zero diagonal entry, a zero-variance asset's, is left out: x is zero
+// there for any draw of the distribution.
+func mahalanobis(l [][]float64, x, y []float64) float64 {
+	var q float64
+	for i := range y {
+		if l[i][i] == 0 {
+			y[i] = 0
+			continue
+		}
+		s := x[i]
+		for j := 0; j < i; j++ {
+			s -= l[i][j] * y[j]
+		}
+		y[i] = s / l[i][i]
+		q += y[i] * y[i]
+	}
+	return q
+}
+
+// logDet returns ln|L| = ½·ln|L·Lᵀ| over the dimensions of the lower
+// Cholesky factor l that are not degenerate, and how many there are: the
+// dimension of the distribution l describes.
+func logDet(l [][]float64) (logDet float64, dim int) {
+	for i := range l {
+		if l[i][i] > 0 {
+			logDet += math.Log(l[i][i])
+			dim++
+		}
+	}
+	return logDet, dim
+}
+
+// nearestPSD projects a symmetric matrix onto the positive-definite cone by
+// clipping its eigenvalues to a small positive floor, then rescales the
+// result so the original diagonal (the per-asset variances) is preserved.
+func nearestPSD(a [][]float64) [][]float64 {
+	n := len(a)
+	vals, vecs := symEigen(a)
+	maxVal := 0.0
+	for _, v := range vals {
+		maxVal = math.Max(maxVal, math.Abs(v))
+	}
+	floor := math.Max(maxVal*1e-10, 1e-18)
+	for i, v := range vals {
+		if v < floor {
+			vals[i] = floor
+		}
+	}
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = make([]float64, n)
+		for j := range out[i] {
+			var s float64
+			for k := 0; k < n; k++ {
+				s += vecs[i][k] * vals[k] * vecs[j][k]
+			}
+			out[i][j] = s
+		}
+	}
+	// Restore the target diagonal so volatilities are unchanged by the repair.
+	scale := make([]float64, n)
+	for i := range scale {
+		scale[i] = 1
+		if out[i][i] > 0 && a[i][i] > 0 {
+			scale[i] = math.Sqrt(a[i][i] / out[i][i])
+		}
+	}
+	for i := range out {
+		for j := range out[i] {
+			out[i][j] *= scale[i] * scale[j]
+		}
+	}
+	return out
+}
+
+// symEigen computes the eigen-decomposition of a symmetric matrix using the
+// cyclic Jacobi method. It returns the eigenvalues and a matrix whose columns
+// are the corresponding orthonormal eigenvectors.
+func symEigen(a [][]float64) (vals []float64, vecs [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	vecs = make([][]float64, n)
+	for i := range m {
+		m[i] = append([]float64(nil), a[i]...)
+		vecs[i] = make([]float64, n)
+		vecs[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(m[p][q]) < 1e-300 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := vecs[k][p], vecs[k][q]
+					vecs[k][p] = c*vkp - s*vkq
+					vecs[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	vals = make([]float64, n)
+	for i := range vals {
+		vals[i] = m[i][i]
+	}
+	return vals, vecs
+}
+
+// symPower returns a^p for a symmetric matrix a through its
+// eigen-decomposition. ok is false if an eigenvalue is not positive, as
+// only positive-definite matrices have real negative or fractional powers.
+func symPower(a [][]float64, p float64) (out [][]float64, ok bool) {
+	n := len(a)
+	vals, vecs := symEigen(a)
+	for i, v := range vals {
+		if v <= 0 {
+			return nil, false
+		}
+		vals[i] = math.Pow(v, p)
+	}
+	out = make([][]float64, n)
+	for i := range out {
+		out[i] = make([]float64, n)
+		for j := range out[i] {
+			var s float64
+			for k := 0; k < n; k++ {
+				s += vecs[i][k] * vals[k] * vecs[j][k]
+			}
+			out[i][j] = s
+		}
+	}
+	return out, true
+}
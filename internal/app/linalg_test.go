@@ -0,0 +1,109 @@
+package app
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCholeskyReconstructs(t *testing.T) {
+	a := [][]float64{
+		{4, 2, 0.4},
+		{2, 5, 1},
+		{0.4, 1, 3},
+	}
+	l, err := cholesky(a)
+	if err != nil {
+		t.Fatalf("cholesky: %v", err)
+	}
+	for i := range a {
+		for j := range a {
+			var s float64
+			for k := range a {
+				s += l[i][k] * l[j][k]
+			}
+			if math.Abs(s-a[i][j]) > 1e-12 {
+				t.Errorf("(L·Lᵀ)[%d][%d] = %v, want %v", i, j, s, a[i][j])
+			}
+		}
+	}
+}
+
+func TestCholeskyZeroVarianceAsset(t *testing.T) {
+	a := [][]float64{
+		{4, 0, 2},
+		{0, 0, 0},
+		{2, 0, 5},
+	}
+	l, err := cholesky(a)
+	if err != nil {
+		t.Fatalf("cholesky: %v", err)
+	}
+	for i := range a {
+		for j := range a {
+			var s float64
+			for k := range a {
+				s += l[i][k] * l[j][k]
+			}
+			if math.Abs(s-a[i][j]) > 1e-12 {
+				t.Errorf("(L·Lᵀ)[%d][%d] = %v, want %v", i, j, s, a[i][j])
+			}
+		}
+	}
+
+	// The degenerate dimension adds nothing to the distance or determinant.
+	y := make([]float64, 3)
+	if q, want := mahalanobis(l, []float64{2, 0, 0}, y), 1.25; math.Abs(q-want) > 1e-12 {
+		t.Errorf("mahalanobis = %v, want %v", q, want)
+	}
+	if ld, dim := logDet(l); dim != 2 || math.Abs(ld-0.5*math.Log(16)) > 1e-12 {
+		t.Errorf("logDet = %v over %d dimensions, want ln 4 over 2", ld, dim)
+	}
+
+	// A zero variance with a nonzero covariance is no covariance matrix.
+	a[2][1], a[1][2] = 1, 1
+	if _, err := cholesky(a); !errors.Is(err, errNotPositiveDefinite) {
+		t.Errorf("err = %v, want errNotPositiveDefinite", err)
+	}
+}
+
+func TestCholeskyNotPositiveDefinite(t *testing.T) {
+	a := [][]float64{{1, 2}, {2, 1}} // eigenvalues 3 and -1
+	if _, err := cholesky(a); !errors.Is(err, errNotPositiveDefinite) {
+		t.Fatalf("err = %v, want errNotPositiveDefinite", err)
+	}
+}
+
+func TestNearestPSDPreservesDiagonal(t *testing.T) {
+	a := [][]float64{
+		{1, 0.9, -0.9},
+		{0.9, 1, 0.9},
+		{-0.9, 0.9, 1},
+	}
+	if _, err := cholesky(a); err == nil {
+		t.Fatal("test matrix should not be positive-definite")
+	}
+
+	fixed := nearestPSD(a)
+
+	if _, err := cholesky(fixed); err != nil {
+		t.Fatalf("repaired matrix still not PD: %v", err)
+	}
+	for i := range a {
+		if math.Abs(fixed[i][i]-a[i][i]) > 1e-9 {
+			t.Errorf("diag[%d] = %v, want %v", i, fixed[i][i], a[i][i])
+		}
+	}
+}
+
+func TestSymEigen(t *testing.T) {
+	vals, vecs := symEigen([][]float64{{2, 1}, {1, 2}})
+	got := []float64{math.Min(vals[0], vals[1]), math.Max(vals[0], vals[1])}
+	if math.Abs(got[0]-1) > 1e-12 || math.Abs(got[1]-3) > 1e-12 {
+		t.Errorf("eigenvalues = %v, want [1 3]", got)
+	}
+	dot := vecs[0][0]*vecs[0][1] + vecs[1][0]*vecs[1][1]
+	if math.Abs(dot) > 1e-12 {
+		t.Errorf("eigenvectors not orthogonal: dot = %v", dot)
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Model is a stochastic model the simulation service can run. Calibrate fits
+// it to an experiment's lookback window; the Calibration then generates
+// paths, and is what the service's worker pool runs.
+type Model interface {
+	// Spec describes the model and the parameters of its block of
+	// SimulationConfig.
+	Spec() domain.ModelSpec
+	// Calibrate fits the model to exp's date-aligned daily log-returns
+	// rows[day][asset], read from the lookback window of exp.Config.
+	Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error)
+}
+
+// Calibration is a model fitted to one experiment.
+type Calibration interface {
+	// Path generates one path of the experiment. It is called from several
+	// goroutines at once, each with its own rng, and must not share mutable
+	// state between calls.
+	Path(rng *rand.Rand) domain.SimulatedPath
+	// Fit returns what the model estimated from the lookback window, for
+	// the run record, or nil if there is nothing worth recording.
+	Fit() *domain.ModelFit
+}
+
+// pathFunc is a Calibration that records no fit.
+type pathFunc func(rng *rand.Rand) domain.SimulatedPath
+
+func (f pathFunc) Path(rng *rand.Rand) domain.SimulatedPath { return f(rng) }
+func (pathFunc) Fit() *domain.ModelFit                      { return nil }
+
+// fitted is a Calibration recording fit.
+type fitted struct {
+	pathFunc
+	fit *domain.ModelFit
+}
+
+func (f fitted) Fit() *domain.ModelFit { return f.fit }
+
+// ModelRegistry holds the models a simulation service can run, keyed by
+// name and listed in registration order.
+type ModelRegistry struct {
+	byName map[domain.SimulationModel]Model
+	order  []domain.SimulationModel
+}
+
+// NewModelRegistry returns a registry of models. It fails if two share a
+// name.
+func NewModelRegistry(models ...Model) (*ModelRegistry, error) {
+	r := &ModelRegistry{byName: map[domain.SimulationModel]Model{}}
+	for _, m := range models {
+		name := m.Spec().Name
+		if _, dup := r.byName[name]; dup {
+			return nil, fmt.Errorf("model %s registered twice", name)
+		}
+		r.byName[name] = m
+		r.order = append(r.order, name)
+	}
+	return r, nil
+}
+
+// DefaultModels returns a registry of every built-in model, GBM first.
+func DefaultModels() *ModelRegistry {
+	r, err := NewModelRegistry(
+		gbmModel{},
+		bootstrapModel{},
+		blockBootstrapModel{},
+		studentTModel{},
+		garchModel{},
+		jumpDiffusionModel{},
+		regimeSwitchingModel{},
+		hestonModel{},
+	)
+	if err != nil {
+		panic(err) // the built-in names are distinct constants
+	}
+	return r
+}
+
+// Get returns the model named name.
+func (r *ModelRegistry) Get(name domain.SimulationModel) (Model, bool) {
+	m, ok := r.byName[name]
+	return m, ok
+}
+
+// Specs describes every registered model, in registration order.
+func (r *ModelRegistry) Specs() []domain.ModelSpec {
+	specs := make([]domain.ModelSpec, len(r.order))
+	for i, name := range r.order {
+		specs[i] = r.byName[name].Spec()
+	}
+	return specs
+}
+
+// ValidateConfig returns a problem with cfg's "model" field if it names a
+// model the registry does not hold, or gives that model capital market
+// assumptions or estimators it does not take.
+func (r *ModelRegistry) ValidateConfig(cfg domain.SimulationConfig) domain.FieldErrors {
+	m, ok := r.Get(cfg.Model)
+	if !ok {
+		names := make([]string, len(r.order))
+		for i, name := range r.order {
+			names[i] = string(name)
+		}
+		return domain.FieldErrors{{Field: "model", Message: "must be one of " + strings.Join(names, ", ")}}
+	}
+	if m.Spec().TakesAssumptions {
+		return nil
+	}
+	var errs domain.FieldErrors
+	if cfg.Assumptions != nil {
+		errs = append(errs, domain.FieldError{Field: "model", Message: string(cfg.Model) + " does not take capital market assumptions"})
+	}
+	if !cfg.Estimator.IsSample() {
+		errs = append(errs, domain.FieldError{Field: "model", Message: string(cfg.Model) + " takes only the sample estimators"})
+	}
+	return errs
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// constModel is a test model whose every path is flat at the start value.
+type constModel struct{ name domain.SimulationModel }
+
+func (m constModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{Name: m.name, Label: "Constant"}
+}
+
+func (constModel) Calibrate(_ [][]float64, exp *domain.Experiment) (Calibration, error) {
+	alloc := portfolioAllocation(exp.Portfolio)
+	return pathFunc(func(*rand.Rand) domain.SimulatedPath {
+		return simulatePath(exp.Config, alloc, func(lr []float64) { clear(lr) })
+	}), nil
+}
+
+func TestNewModelRegistryRejectsDuplicates(t *testing.T) {
+	if _, err := NewModelRegistry(constModel{"flat"}, constModel{"flat"}); err == nil {
+		t.Error("NewModelRegistry accepted two models named flat")
+	}
+}
+
+func TestDefaultModelsCoverEveryModel(t *testing.T) {
+	want := []domain.SimulationModel{
+		domain.ModelGBM, domain.ModelBootstrap, domain.ModelBlockBootstrap, domain.ModelStudentT,
+		domain.ModelGARCH, domain.ModelJumpDiffusion, domain.ModelRegimeSwitching, domain.ModelHeston,
+	}
+	specs := DefaultModels().Specs()
+	if len(specs) != len(want) {
+		t.Fatalf("got %d models, want %d", len(specs), len(want))
+	}
+	for i, s := range specs {
+		if s.Name != want[i] || s.Label == "" || s.Description == "" {
+			t.Errorf("model %d = %s %q, want %s with a label and description", i, s.Name, s.Label, want[i])
+		}
+	}
+}
+
+func TestSimulateUsesRegisteredModels(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	reg, err := NewModelRegistry(constModel{"flat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.models = reg
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+
+	exp.Config.Model = "flat"
+	var run domain.Run
+	paths, err := svc.simulate(ctx, exp, &run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Fit != nil || len(paths) != exp.Config.NumPaths || paths[0].Values[len(paths[0].Values)-1] != exp.Config.StartValue {
+		t.Errorf("got %d paths and fit %+v, want flat paths and no fit", len(paths), run.Fit)
+	}
+
+	exp.Config.Model = domain.ModelGBM
+	if _, err := svc.simulate(ctx, exp, &run); err == nil || !strings.Contains(err.Error(), "unknown model") {
+		t.Errorf("simulate with an unregistered model: err = %v", err)
+	}
+}
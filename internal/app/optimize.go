@@ -0,0 +1,105 @@
+package app
+
+import "math"
+
+// goldenMax returns the x in [lo, hi] that maximises f, to within tol, by
+// golden-section search. f should be unimodal on the interval; otherwise the
+// result is a local maximum.
+func goldenMax(f func(float64) float64, lo, hi, tol float64) float64 {
+	const invPhi = 0.6180339887498949 // (√5 − 1) / 2
+	a, b := lo, hi
+	c := b - invPhi*(b-a)
+	d := a + invPhi*(b-a)
+	fc, fd := f(c), f(d)
+	for math.Abs(b-a) > tol {
+		if fc >= fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			fc = f(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			fd = f(d)
+		}
+	}
+	return (a + b) / 2
+}
+
+// nelderMeadMax returns the point maximising f, and f there, by the
+// Nelder–Mead simplex method started from x0 with initial steps step. f may
+// return −Inf outside its domain provided x0 is inside it. The search stops
+// once every vertex's value is within tol of the best or after maxIter steps.
+func nelderMeadMax(f func([]float64) float64, x0, step []float64, tol float64, maxIter int) ([]float64, float64) {
+	n := len(x0)
+	pts := make([][]float64, n+1)
+	vals := make([]float64, n+1)
+	for i := range pts {
+		pts[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			pts[i][i-1] += step[i-1]
+		}
+		vals[i] = f(pts[i])
+	}
+	// along returns centroid + t·(centroid − worst).
+	along := func(centroid []float64, t float64) []float64 {
+		x := make([]float64, n)
+		for j := range x {
+			x[j] = centroid[j] + t*(centroid[j]-pts[n][j])
+		}
+		return x
+	}
+	for range maxIter {
+		// Order the vertices best first.
+		for i := 1; i <= n; i++ {
+			for k := i; k > 0 && vals[k] > vals[k-1]; k-- {
+				pts[k], pts[k-1] = pts[k-1], pts[k]
+				vals[k], vals[k-1] = vals[k-1], vals[k]
+			}
+		}
+		if vals[0]-vals[n] <= tol {
+			break
+		}
+		centroid := make([]float64, n)
+		for _, p := range pts[:n] {
+			for j := range centroid {
+				centroid[j] += p[j] / float64(n)
+			}
+		}
+		xr := along(centroid, 1)
+		fr := f(xr)
+		switch {
+		case fr > vals[0]:
+			xe := along(centroid, 2)
+			if fe := f(xe); fe > fr {
+				xr, fr = xe, fe
+			}
+			pts[n], vals[n] = xr, fr
+		case fr > vals[n-1]:
+			pts[n], vals[n] = xr, fr
+		default:
+			t := -0.5 // inside contraction
+			if fr > vals[n] {
+				t = 0.5 // outside contraction
+			}
+			xc := along(centroid, t)
+			if fc := f(xc); fc > max(fr, vals[n]) {
+				pts[n], vals[n] = xc, fc
+				continue
+			}
+			// Shrink every vertex towards the best.
+			for i := 1; i <= n; i++ {
+				for j := range pts[i] {
+					pts[i][j] = pts[0][j] + 0.5*(pts[i][j]-pts[0][j])
+				}
+				vals[i] = f(pts[i])
+			}
+		}
+	}
+	best := 0
+	for i := range vals {
+		if vals[i] > vals[best] {
+			best = i
+		}
+	}
+	return pts[best], vals[best]
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGoldenMax(t *testing.T) {
+	x := goldenMax(func(x float64) float64 { return -(x - 1.3) * (x - 1.3) }, -5, 5, 1e-8)
+	if math.Abs(x-1.3) > 1e-6 {
+		t.Errorf("goldenMax = %v, want 1.3", x)
+	}
+}
+
+func TestNelderMeadMax(t *testing.T) {
+	// Negated Rosenbrock, maximised at (1, 1), with −Inf outside x ≥ −1.
+	f := func(x []float64) float64 {
+		if x[0] < -1 {
+			return math.Inf(-1)
+		}
+		a, b := 1-x[0], x[1]-x[0]*x[0]
+		return -(a*a + 100*b*b)
+	}
+	x, fx := nelderMeadMax(f, []float64{-0.5, 1.5}, []float64{0.5, 0.5}, 1e-14, 5000)
+	if math.Abs(x[0]-1) > 1e-3 || math.Abs(x[1]-1) > 1e-3 || fx < -1e-6 {
+		t.Errorf("nelderMeadMax = %v (f %v), want (1, 1)", x, fx)
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"math"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// allocation is the per-path view of a portfolio: target weights in asset
+// order and the number of trading days between rebalances (0 = never).
+type allocation struct {
+	weights   []float64
+	rebalance int
+}
+
+func portfolioAllocation(p domain.Portfolio) allocation {
+	w := make([]float64, len(p.Assets))
+	for i, a := range p.Assets {
+		w[i] = a.Weight
+	}
+	return allocation{weights: w, rebalance: p.Rebalance.Interval()}
+}
+
+// simulatePath assembles one portfolio path. For every simulated day, next
+// fills lr with each asset's log-return; simulatePath grows the per-asset
+// holdings by those returns, applies any scheduled cash flow, rebalances back
+// to the target weights on the allocation's calendar, and records the total.
+// Contributions are invested at the target weights; withdrawals are taken
+// pro rata from current holdings so they do not rebalance the portfolio.
+func simulatePath(cfg domain.SimulationConfig, alloc allocation, next func(lr []float64)) domain.SimulatedPath {
+	vals := make([]float64, cfg.HorizonDays+1)
+	vals[0] = cfg.StartValue
+	held := make([]float64, len(alloc.weights))
+	for i, w := range alloc.weights {
+		held[i] = cfg.StartValue * w
+	}
+	flows := newCashFlows(cfg)
+	var netFlow float64
+	lr := make([]float64, len(held))
+	for day := 1; day <= cfg.HorizonDays; day++ {
+		next(lr)
+		var total float64
+		for i := range held {
+			held[i] *= math.Exp(lr[i])
+			total += held[i]
+		}
+		if f := flows.at(day, total); f != 0 {
+			switch {
+			case f > 0:
+				for i, w := range alloc.weights {
+					held[i] += f * w
+				}
+			case total > 0:
+				for i := range held {
+					held[i] *= 1 + f/total
+				}
+			}
+			total += f
+			netFlow += f
+		}
+		if alloc.rebalance > 0 && day%alloc.rebalance == 0 {
+			for i, w := range alloc.weights {
+				held[i] = total * w
+			}
+		}
+		vals[day] = total
+	}
+	return domain.SimulatedPath{Values: vals, NetCashFlow: netFlow}
+}
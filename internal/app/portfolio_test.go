@@ -0,0 +1,66 @@
+package app
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// alternating returns asset 0 up 10% and asset 1 down 10% on odd days, and
+// the reverse on even days.
+func alternating(day *int) func(lr []float64) {
+	return func(lr []float64) {
+		*day++
+		up, down := math.Log(1.1), math.Log(0.9)
+		if *day%2 == 1 {
+			lr[0], lr[1] = up, down
+		} else {
+			lr[0], lr[1] = down, up
+		}
+	}
+}
+
+func TestSimulatePathRebalancing(t *testing.T) {
+	cfg := domain.SimulationConfig{HorizonDays: 2, StartValue: 100}
+	tests := []struct {
+		name      string
+		rebalance domain.RebalanceFrequency
+		want      float64
+	}{
+		// Buy and hold: each asset ends at 1.1·0.9 = 0.99 of its start.
+		{"none drifts", domain.RebalanceNone, 99},
+		// Daily: day 1 = 100, rebalanced 50/50, day 2 = 50·0.9 + 50·1.1 = 100.
+		{"daily rebalances every day", domain.RebalanceDaily, 100},
+		// Monthly never triggers inside a two-day horizon.
+		{"monthly behaves like none over two days", domain.RebalanceMonthly, 99},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := portfolioAllocation(domain.Portfolio{
+				Assets:    []domain.PortfolioAsset{{Symbol: "A", Weight: 0.5}, {Symbol: "B", Weight: 0.5}},
+				Rebalance: tc.rebalance,
+			})
+			d := 0
+
+			path := simulatePath(cfg, alloc, alternating(&d))
+
+			if math.Abs(path.Final()-tc.want) > 1e-9 {
+				t.Errorf("final = %v, want %v", path.Final(), tc.want)
+			}
+		})
+	}
+}
+
+func TestSimulatePathStartsAtStartValue(t *testing.T) {
+	cfg := domain.SimulationConfig{HorizonDays: 5, StartValue: 1234}
+	alloc := allocation{weights: []float64{0.3, 0.7}, rebalance: 1}
+
+	path := simulatePath(cfg, alloc, func(lr []float64) { lr[0], lr[1] = 0, 0 })
+
+	for d, v := range path.Values {
+		if math.Abs(v-1234) > 1e-9 {
+			t.Fatalf("day %d = %v, want 1234 with zero returns", d, v)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// progressInterval is the minimum time between progress reports from a
+// running simulation.
+const progressInterval = 250 * time.Millisecond
+
+// watchPollInterval is how often a watcher reads the run from the
+// repository, for updates the hub never sees.
+const watchPollInterval = time.Second
+
+// watchBuffer is how many undelivered progress updates a watcher may fall
+// behind by before the oldest are dropped.
+const watchBuffer = 16
+
+type progressKey struct{}
+
+// withProgress returns a context whose simulation reports paths completed to fn.
+func withProgress(ctx context.Context, fn func(done int)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom returns the progress callback carried by ctx, or a no-op.
+func progressFrom(ctx context.Context) func(done int) {
+	if fn, ok := ctx.Value(progressKey{}).(func(int)); ok {
+		return fn
+	}
+	return func(int) {}
+}
+
+// progressHub fans run progress out to watchers. It is in-memory only: the
+// runs table stays the system of record, so a watcher that misses updates
+// (or connects to another process) can always fall back to reading the run.
+type progressHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan domain.RunProgress]struct{}
+}
+
+// subscribe registers a watcher for runID. The returned channel is closed
+// after a terminal update is delivered or when stop is called.
+func (h *progressHub) subscribe(runID string) (ch chan domain.RunProgress, stop func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = map[string]map[chan domain.RunProgress]struct{}{}
+	}
+	if h.subs[runID] == nil {
+		h.subs[runID] = map[chan domain.RunProgress]struct{}{}
+	}
+	ch = make(chan domain.RunProgress, watchBuffer)
+	h.subs[runID][ch] = struct{}{}
+	return ch, func() { h.unsubscribe(runID, ch) }
+}
+
+func (h *progressHub) unsubscribe(runID string, ch chan domain.RunProgress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[runID][ch]; !ok {
+		return
+	}
+	delete(h.subs[runID], ch)
+	if len(h.subs[runID]) == 0 {
+		delete(h.subs, runID)
+	}
+	close(ch)
+}
+
+// offer delivers p to ch alone, unless ch has already been closed by a
+// terminal update (which then supersedes p).
+func (h *progressHub) offer(runID string, ch chan domain.RunProgress, p domain.RunProgress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[runID][ch]; ok {
+		deliver(ch, p)
+	}
+}
+
+// end delivers the terminal update p to ch alone and closes it, unless a
+// terminal update has already closed it.
+func (h *progressHub) end(runID string, ch chan domain.RunProgress, p domain.RunProgress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[runID][ch]; !ok {
+		return
+	}
+	deliver(ch, p)
+	delete(h.subs[runID], ch)
+	if len(h.subs[runID]) == 0 {
+		delete(h.subs, runID)
+	}
+	close(ch)
+}
+
+// publish delivers p to every watcher of its run without blocking: a watcher
+// whose buffer is full loses its oldest update. Terminal updates also close
+// and remove the run's watchers.
+func (h *progressHub) publish(p domain.RunProgress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[p.RunID] {
+		deliver(ch, p)
+		if !p.Status.Active() {
+			close(ch)
+		}
+	}
+	if !p.Status.Active() {
+		delete(h.subs, p.RunID)
+	}
+}
+
+func deliver(ch chan domain.RunProgress, p domain.RunProgress) {
+	for {
+		select {
+		case ch <- p:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
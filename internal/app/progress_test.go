@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func TestProgressHubDropsOldestWhenFull(t *testing.T) {
+	var h progressHub
+	ch, stop := h.subscribe("run")
+	defer stop()
+	for i := 1; i <= watchBuffer+5; i++ {
+		h.publish(domain.RunProgress{RunID: "run", Status: domain.StatusRunning, Done: i})
+	}
+	if got := (<-ch).Done; got != 6 {
+		t.Errorf("oldest buffered update Done = %d, want 6", got)
+	}
+}
+
+func TestProgressHubClosesOnTerminalUpdate(t *testing.T) {
+	var h progressHub
+	ch, stop := h.subscribe("run")
+	defer stop() // must be safe after the hub closed the channel
+	h.publish(domain.RunProgress{RunID: "run", Status: domain.StatusRunning, Done: 1})
+	h.publish(domain.RunProgress{RunID: "other", Status: domain.StatusComplete})
+	h.publish(domain.RunProgress{RunID: "run", Status: domain.StatusComplete, Done: 2})
+
+	var got []domain.RunProgress
+	for p := range ch {
+		got = append(got, p)
+	}
+	if len(got) != 2 || got[1].Status != domain.StatusComplete {
+		t.Errorf("received %+v, want a progress update then complete", got)
+	}
+}
+
+func TestWorkerPoolReportsFinalCount(t *testing.T) {
+	var reported []int
+	ctx := withProgress(context.Background(), func(done int) { reported = append(reported, done) })
+	cfg := domain.SimulationConfig{NumPaths: 37, HorizonDays: 1, StartValue: 1}
+	paths, err := (&simulationSvc{}).workerPool(ctx, cfg, func(*rand.Rand) domain.SimulatedPath {
+		return domain.SimulatedPath{Values: []float64{1, 1}}
+	})
+	if err != nil || len(paths) != 37 {
+		t.Fatalf("got %d paths, %v; want 37", len(paths), err)
+	}
+	if len(reported) == 0 || reported[len(reported)-1] != 37 {
+		t.Errorf("reported %v, want a final report of 37", reported)
+	}
+}
+
+func TestWatchRunStreamsUntilComplete(t *testing.T) {
+	svc, _ := newRunnerFixture(t)
+	ctx := context.Background()
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	events, err := svc.WatchRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("WatchRun: %v", err)
+	}
+	startRunner(t, NewRunner(svc))
+
+	var last domain.RunProgress
+	n := 0
+	for p := range events {
+		if n == 0 && p.Status != domain.StatusQueued {
+			t.Errorf("first update status = %s, want the queued snapshot", p.Status)
+		}
+		last = p
+		n++
+	}
+	if last.Status != domain.StatusComplete || last.Done != last.Total || last.Total != 20 {
+		t.Errorf("last update = %+v, want complete with 20/20 paths", last)
+	}
+
+	// Watching a finished run yields its final state and closes.
+	events, err = svc.WatchRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("WatchRun finished run: %v", err)
+	}
+	if p, ok := <-events; !ok || p.Status != domain.StatusComplete {
+		t.Errorf("finished run first update = %+v, %v; want complete", p, ok)
+	}
+	if _, ok := <-events; ok {
+		t.Error("channel for a finished run should be closed after one update")
+	}
+}
+
+func TestWatchRunEndsForRunExecutingInAnotherProcess(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	events, err := svc.WatchRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("WatchRun: %v", err)
+	}
+	// The run executes in a second service over the same repositories, whose
+	// progress never reaches svc's hub.
+	other := NewSimulationService(deps.Assets, deps.Runs, deps.Experiments, deps.Paths)
+	startRunner(t, NewRunner(other))
+
+	var last domain.RunProgress
+	for p := range events {
+		last = p
+	}
+	if ctx.Err() != nil {
+		t.Fatal("stream did not end after the run finished")
+	}
+	if last.Status != domain.StatusComplete || last.Done != last.Total {
+		t.Errorf("last update = %+v, want complete", last)
+	}
+}
+
+func TestProgressHubEndClosesOnce(t *testing.T) {
+	var h progressHub
+	ch, stop := h.subscribe("run")
+	defer stop()
+	h.end("run", ch, domain.RunProgress{RunID: "run", Status: domain.StatusComplete})
+	h.publish(domain.RunProgress{RunID: "run", Status: domain.StatusComplete})
+	h.end("run", ch, domain.RunProgress{RunID: "run", Status: domain.StatusComplete})
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("received %d terminal updates, want 1", n)
+	}
+}
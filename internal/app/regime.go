@@ -0,0 +1,408 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// defaultRegimes is used when SimulationConfig.RegimeStates is zero.
+const defaultRegimes = 2
+
+// regimeNames label the fitted states, which are ordered calmest first.
+var regimeNames = [][]string{2: {"bull", "bear"}, 3: {"bull", "bear", "crisis"}}
+
+// regimeParams holds a Gaussian hidden Markov model of the joint daily
+// log-returns: each state has its own mean vector and covariance, and the
+// state moves from day to day by a Markov chain. States are ordered by the
+// variance of an equally weighted portfolio, calmest first.
+type regimeParams struct {
+	mean   [][]float64   // per-state daily mean log-return per asset
+	cov    [][][]float64 // per-state daily covariance
+	chol   [][][]float64 // per-state lower Cholesky factor of cov
+	trans  [][]float64   // trans[i][j] is the chance of state j tomorrow given state i today
+	last   []float64     // state probabilities on the last lookback day
+	logLik float64
+}
+
+// regimeSwitchingModel is a Gaussian hidden Markov model of bull, bear and
+// crisis regimes.
+type regimeSwitchingModel struct{}
+
+func (regimeSwitchingModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelRegimeSwitching,
+		Label:            "Regime-Switching (bull/bear/crisis)",
+		Description:      "A two- or three-state Gaussian hidden Markov model whose regimes each have their own returns and covariance.",
+		Params:           domain.RegimeParams{}.Spec(),
+		TakesAssumptions: true,
+	}
+}
+
+func (regimeSwitchingModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	k := exp.Config.Regime.States
+	if k == 0 {
+		k = defaultRegimes
+	}
+	p, err := fitRegimes(rows, k, exp.Config.RepairCovariance)
+	if err != nil {
+		return nil, fmt.Errorf("regimes of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	start := p.stationary()
+	if exp.Config.Regime.StartCurrent {
+		start = make([]float64, k)
+		start[argmax(p.last)] = 1
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	return fitted{
+		pathFunc: func(rng *rand.Rand) domain.SimulatedPath { return regimePath(exp.Config, p, start, alloc, rng) },
+		fit:      p.modelFit(exp.Portfolio),
+	}, nil
+}
+
+// fitRegimes fits a k-state Gaussian HMM to rows[day][asset] by the
+// Baum–Welch (EM) algorithm. The states start from terciles (or halves) of
+// trailing 21-day volatility, so EM begins with calm and turbulent regimes
+// rather than a random split.
+func fitRegimes(rows [][]float64, k int, repair bool) (regimeParams, error) {
+	if minDays := 20 * k; len(rows) < minDays {
+		return regimeParams{}, fmt.Errorf("need at least %d days of returns to fit %d regimes, have %d", minDays, k, len(rows))
+	}
+	_, sample := sampleMoments(rows)
+	ridge := make([]float64, len(sample))
+	for i := range ridge {
+		// Keeps a state that collapses onto a few days from a singular covariance.
+		ridge[i] = 1e-6*sample[i][i] + 1e-18
+	}
+
+	weights := initialRegimes(rows, k)
+	p := regimeParams{trans: make([][]float64, k)}
+	for i := range p.trans {
+		p.trans[i] = make([]float64, k)
+		for j := range p.trans[i] {
+			p.trans[i][j] = 0.05 / float64(k-1)
+		}
+		p.trans[i][i] = 0.95
+	}
+	first := slices.Repeat([]float64{1 / float64(k)}, k)
+	prev := math.Inf(-1)
+	for iter := 0; ; iter++ {
+		if err := p.estimateStates(rows, weights, ridge, repair); err != nil {
+			return regimeParams{}, err
+		}
+		var transCounts [][]float64
+		weights, transCounts, p.last, p.logLik = p.smooth(rows, first)
+		if iter == 500 || p.logLik-prev <= 1e-8*math.Abs(p.logLik) {
+			break
+		}
+		prev = p.logLik
+		first = weights[0]
+		for i, row := range transCounts {
+			total := 0.0
+			for _, c := range row {
+				total += c
+			}
+			for j, c := range row {
+				if total > 0 {
+					p.trans[i][j] = c / total
+				}
+			}
+		}
+	}
+	p.sortByVolatility()
+	return p, nil
+}
+
+// initialRegimes assigns each day wholly to one of k states by the quantile
+// of its trailing 21-day volatility of the equally weighted return.
+func initialRegimes(rows [][]float64, k int) [][]float64 {
+	avg := make([]float64, len(rows))
+	for t, r := range rows {
+		for _, v := range r {
+			avg[t] += v / float64(len(r))
+		}
+	}
+	vol := make([]float64, len(rows))
+	for t := range rows {
+		window := avg[max(0, t-20) : t+1]
+		var m, ss float64
+		for _, v := range window {
+			m += v / float64(len(window))
+		}
+		for _, v := range window {
+			ss += (v - m) * (v - m)
+		}
+		vol[t] = ss / float64(len(window))
+	}
+	sorted := slices.Clone(vol)
+	slices.Sort(sorted)
+	weights := make([][]float64, len(rows))
+	for t, v := range vol {
+		weights[t] = make([]float64, k)
+		rank, _ := slices.BinarySearch(sorted, v)
+		weights[t][min(k-1, rank*k/len(rows))] = 1
+	}
+	return weights
+}
+
+// estimateStates sets every state's mean and covariance from the days
+// weighted by weights[day][state] (the M-step).
+func (p *regimeParams) estimateStates(rows, weights [][]float64, ridge []float64, repair bool) error {
+	k, d := len(weights[0]), len(rows[0])
+	p.mean, p.cov, p.chol = make([][]float64, k), make([][][]float64, k), make([][][]float64, k)
+	for s := range k {
+		mean := make([]float64, d)
+		var total float64
+		for t, r := range rows {
+			w := weights[t][s]
+			total += w
+			for i, v := range r {
+				mean[i] += w * v
+			}
+		}
+		if total == 0 {
+			return fmt.Errorf("regime %d has no days", s+1)
+		}
+		for i := range mean {
+			mean[i] /= total
+		}
+		cov := make([][]float64, d)
+		for i := range cov {
+			cov[i] = make([]float64, d)
+		}
+		for t, r := range rows {
+			w := weights[t][s] / total
+			for i := range d {
+				for j := 0; j <= i; j++ {
+					cov[i][j] += w * (r[i] - mean[i]) * (r[j] - mean[j])
+				}
+			}
+		}
+		for i := range d {
+			cov[i][i] += ridge[i]
+			for j := 0; j < i; j++ {
+				cov[j][i] = cov[i][j]
+			}
+		}
+		chol, err := factorPSD(cov, repair)
+		if err != nil {
+			return err
+		}
+		p.mean[s], p.cov[s], p.chol[s] = mean, cov, chol
+	}
+	return nil
+}
+
+// smooth runs the scaled forward–backward algorithm (the E-step) with first
+// as the first day's state distribution. It returns each day's posterior
+// state probabilities, the expected number of transitions between each pair
+// of states, the filtered state probabilities on the last day, and the
+// log-likelihood of rows.
+func (p *regimeParams) smooth(rows [][]float64, first []float64) (post, transCounts [][]float64, last []float64, logLik float64) {
+	n, k := len(rows), len(p.mean)
+	// Emission densities, each day scaled by its largest to avoid underflow;
+	// the scale goes back into the log-likelihood.
+	dens := make([][]float64, n)
+	dev, y := make([]float64, len(rows[0])), make([]float64, len(rows[0]))
+	norm := make([]float64, k)
+	for s := range k {
+		ld, dim := logDet(p.chol[s])
+		norm[s] = -ld - 0.5*float64(dim)*math.Log(2*math.Pi)
+	}
+	for t, r := range rows {
+		dens[t] = make([]float64, k)
+		for s := range k {
+			for i := range dev {
+				dev[i] = r[i] - p.mean[s][i]
+			}
+			dens[t][s] = -0.5*mahalanobis(p.chol[s], dev, y) + norm[s]
+		}
+		top := slices.Max(dens[t])
+		for s := range dens[t] {
+			dens[t][s] = math.Exp(dens[t][s] - top)
+		}
+		logLik += top
+	}
+
+	alpha, scale := make([][]float64, n), make([]float64, n)
+	for t := range rows {
+		alpha[t] = make([]float64, k)
+		for j := range k {
+			if t == 0 {
+				alpha[t][j] = first[j]
+			} else {
+				for i := range k {
+					alpha[t][j] += alpha[t-1][i] * p.trans[i][j]
+				}
+			}
+			alpha[t][j] *= dens[t][j]
+			scale[t] += alpha[t][j]
+		}
+		for j := range alpha[t] {
+			alpha[t][j] /= scale[t]
+		}
+		logLik += math.Log(scale[t])
+	}
+
+	beta := make([][]float64, n)
+	beta[n-1] = slices.Repeat([]float64{1}, k)
+	for t := n - 2; t >= 0; t-- {
+		beta[t] = make([]float64, k)
+		for i := range k {
+			for j := range k {
+				beta[t][i] += p.trans[i][j] * dens[t+1][j] * beta[t+1][j]
+			}
+			beta[t][i] /= scale[t+1]
+		}
+	}
+
+	post = make([][]float64, n)
+	transCounts = make([][]float64, k)
+	for i := range transCounts {
+		transCounts[i] = make([]float64, k)
+	}
+	for t := range rows {
+		post[t] = make([]float64, k)
+		var total float64
+		for s := range k {
+			post[t][s] = alpha[t][s] * beta[t][s]
+			total += post[t][s]
+		}
+		for s := range k {
+			post[t][s] /= total
+		}
+		if t == n-1 {
+			break
+		}
+		for i := range k {
+			for j := range k {
+				transCounts[i][j] += alpha[t][i] * p.trans[i][j] * dens[t+1][j] * beta[t+1][j] / scale[t+1]
+			}
+		}
+	}
+	return post, transCounts, alpha[n-1], logLik
+}
+
+// sortByVolatility reorders the states by the variance of an equally
+// weighted portfolio, calmest first.
+func (p *regimeParams) sortByVolatility() {
+	k := len(p.mean)
+	variance := make([]float64, k)
+	for s, cov := range p.cov {
+		for _, row := range cov {
+			for _, c := range row {
+				variance[s] += c
+			}
+		}
+	}
+	order := make([]int, k)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(variance[a], variance[b]) })
+	q := regimeParams{logLik: p.logLik, trans: make([][]float64, k), last: make([]float64, k)}
+	for to, from := range order {
+		q.mean = append(q.mean, p.mean[from])
+		q.cov = append(q.cov, p.cov[from])
+		q.chol = append(q.chol, p.chol[from])
+		q.last[to] = p.last[from]
+		q.trans[to] = make([]float64, k)
+		for j, fromJ := range order {
+			q.trans[to][j] = p.trans[from][fromJ]
+		}
+	}
+	*p = q
+}
+
+// stationary returns the long-run share of days spent in each state.
+func (p regimeParams) stationary() []float64 {
+	k := len(p.trans)
+	v := slices.Repeat([]float64{1 / float64(k)}, k)
+	for range 10_000 {
+		next := make([]float64, k)
+		for i, vi := range v {
+			for j, a := range p.trans[i] {
+				next[j] += vi * a
+			}
+		}
+		v = next
+	}
+	return v
+}
+
+// modelFit records the regimes: for each state its expected length in days,
+// long-run share of days and probability on the last lookback day, the
+// chance of moving to each other state, and each asset's annualised return
+// and volatility in it.
+func (p regimeParams) modelFit(pf domain.Portfolio) *domain.ModelFit {
+	names := regimeNames[len(p.mean)]
+	share := p.stationary()
+	fit := &domain.ModelFit{Model: domain.ModelRegimeSwitching, LogLikelihood: p.logLik, Params: map[string]float64{}}
+	for s, name := range names {
+		fit.Params[name+"_expected_days"] = 1 / (1 - p.trans[s][s])
+		fit.Params[name+"_share"] = share[s]
+		fit.Params[name+"_now"] = p.last[s]
+		for j, other := range names {
+			if j != s {
+				fit.Params[name+"_to_"+other] = p.trans[s][j]
+			}
+		}
+	}
+	for i, a := range pf.Assets {
+		params := map[string]float64{}
+		for s, name := range names {
+			params[name+"_return"] = 252 * p.mean[s][i]
+			params[name+"_vol"] = math.Sqrt(252 * p.cov[s][i][i])
+		}
+		fit.Assets = append(fit.Assets, domain.AssetFit{Symbol: a.Symbol, Params: params})
+	}
+	return fit
+}
+
+// regimePath draws the first day's state from start, then each day draws the
+// assets' returns from the current state before moving to the next state by
+// the transition matrix.
+func regimePath(cfg domain.SimulationConfig, p regimeParams, start []float64, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	state := drawIndex(rng, start)
+	z := make([]float64, len(p.mean[0]))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
+		}
+		chol := p.chol[state]
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += chol[i][j] * z[j]
+			}
+			lr[i] = p.mean[state][i] + shock
+		}
+		state = drawIndex(rng, p.trans[state])
+	})
+}
+
+// drawIndex draws i with probability probs[i].
+func drawIndex(rng *rand.Rand, probs []float64) int {
+	u := rng.Float64()
+	for i, q := range probs {
+		if u -= q; u < 0 {
+			return i
+		}
+	}
+	return len(probs) - 1
+}
+
+// argmax returns the index of the largest element of xs.
+func argmax(xs []float64) int {
+	best := 0
+	for i, x := range xs {
+		if x > xs[best] {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// regimeRows simulates n days of two assets from a two-state chain: a calm
+// state (daily vol 0.6%, drift +0.08%) and a turbulent one (vol 2.5%, drift
+// −0.15%), each persisting with the given probability. It ends in the
+// turbulent state.
+func regimeRows(rng *rand.Rand, n int, stay float64) [][]float64 {
+	rows := make([][]float64, n)
+	state := 0
+	for t := range rows {
+		if t == n-30 {
+			state = 1
+		} else if t < n-30 && rng.Float64() > stay {
+			state = 1 - state
+		}
+		mean, vol := 0.0008, 0.006
+		if state == 1 {
+			mean, vol = -0.0015, 0.025
+		}
+		z := rng.NormFloat64()
+		rows[t] = []float64{mean + vol*z, mean + vol*(0.6*z+0.8*rng.NormFloat64())}
+	}
+	return rows
+}
+
+func TestFitRegimesRecoversStates(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{17}))
+	p, err := fitRegimes(regimeRows(rng, 5000, 0.98), 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s, want := range []float64{0.006, 0.025} {
+		if vol := math.Sqrt(p.cov[s][0][0]); math.Abs(vol/want-1) > 0.1 {
+			t.Errorf("state %d vol = %.4f, want ~%v", s, vol, want)
+		}
+	}
+	if p.mean[0][0] <= p.mean[1][0] {
+		t.Errorf("calm mean %v not above turbulent mean %v", p.mean[0][0], p.mean[1][0])
+	}
+	for s := range 2 {
+		if math.Abs(p.trans[s][s]-0.98) > 0.015 {
+			t.Errorf("trans[%d][%d] = %.3f, want ~0.98", s, s, p.trans[s][s])
+		}
+	}
+	if argmax(p.last) != 1 {
+		t.Errorf("last-day state probabilities %v, want turbulent most likely", p.last)
+	}
+	if corr := p.cov[1][1][0] / math.Sqrt(p.cov[1][0][0]*p.cov[1][1][1]); math.Abs(corr-0.6) > 0.1 {
+		t.Errorf("turbulent-state correlation = %.2f, want ~0.6", corr)
+	}
+}
+
+func TestFitRegimesThreeStatesOrderedByVolatility(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{18}))
+	p, err := fitRegimes(regimeRows(rng, 3000, 0.97), 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s := 1; s < 3; s++ {
+		if p.cov[s][0][0] < p.cov[s-1][0][0] {
+			t.Errorf("state %d variance %g below state %d's %g", s, p.cov[s][0][0], s-1, p.cov[s-1][0][0])
+		}
+	}
+	fit := p.modelFit(domain.Portfolio{Assets: []domain.PortfolioAsset{{Symbol: "A"}, {Symbol: "B"}}})
+	if _, ok := fit.Params["crisis_expected_days"]; !ok || len(fit.Assets) != 2 || fit.Assets[1].Params["bull_vol"] <= 0 {
+		t.Errorf("fit = %+v", fit)
+	}
+}
+
+func TestFitRegimesNeedsHistory(t *testing.T) {
+	_, err := fitRegimes(make([][]float64, 50), 3, false)
+	if err == nil || !strings.Contains(err.Error(), "at least 60 days") {
+		t.Errorf("err = %v, want a too-little-history error", err)
+	}
+}
+
+func TestRegimePathPersists(t *testing.T) {
+	p := regimeParams{
+		mean:  [][]float64{{0}, {0}},
+		chol:  [][][]float64{{{1e-6}}, {{0.03}}},
+		trans: [][]float64{{0.99, 0.01}, {0.05, 0.95}},
+	}
+	if share := p.stationary(); math.Abs(share[0]-5.0/6) > 1e-9 {
+		t.Errorf("stationary = %v, want [5/6 1/6]", share)
+	}
+	cfg := domain.SimulationConfig{HorizonDays: 2000, StartValue: 1}
+	rng := rand.New(rand.NewChaCha8([32]byte{19}))
+	v := regimePath(cfg, p, []float64{0, 1}, allocation{weights: []float64{1}}, rng).Values
+	// Count turbulent spells by their large moves.
+	var turbulent, spells int
+	prev := true // started turbulent
+	for d := 1; d < len(v); d++ {
+		big := math.Abs(math.Log(v[d]/v[d-1])) > 1e-4
+		if big {
+			turbulent++
+			if !prev {
+				spells++
+			}
+		}
+		prev = big
+	}
+	if spells == 0 || spells > 40 {
+		t.Errorf("%d turbulent spells over 2000 days, want persistent regimes (~17)", spells)
+	}
+	if frac := float64(turbulent) / 2000; math.Abs(frac-1.0/6) > 0.1 {
+		t.Errorf("turbulent share = %.2f, want ~1/6", frac)
+	}
+}
+
+func TestRunnerRegimeSwitchingStartsInCurrentState(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Config.Model = domain.ModelRegimeSwitching
+	exp.Config.Regime.StartCurrent = true
+	if err := deps.Experiments.SaveExperiment(ctx, *exp); err != nil {
+		t.Fatal(err)
+	}
+	var run domain.Run
+	paths, err := svc.simulate(ctx, exp, &run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != exp.Config.NumPaths || run.Fit == nil || run.Fit.Model != domain.ModelRegimeSwitching {
+		t.Errorf("got %d paths and fit %+v", len(paths), run.Fit)
+	}
+}
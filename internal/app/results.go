@@ -2,6 +2,9 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/gjcourt/drift/internal/domain"
@@ -11,14 +14,39 @@ import (
 type resultsSvc struct {
 	experimentRepo outbound.ExperimentRepository
 	simulationRepo outbound.SimulationRepository
+	codec          outbound.ExperimentCodec
+	models         *ModelRegistry
 }
 
-// NewResultsService constructs a ResultsService backed by the given repositories.
-func NewResultsService(er outbound.ExperimentRepository, sr outbound.SimulationRepository) *resultsSvc {
-	return &resultsSvc{experimentRepo: er, simulationRepo: sr}
+// ResultsOption configures the service built by NewResultsService.
+type ResultsOption func(*resultsSvc)
+
+// WithResultsModels sets the models experiments may name, which should be
+// those the simulation service runs. The default is DefaultModels.
+func WithResultsModels(r *ModelRegistry) ResultsOption {
+	return func(s *resultsSvc) { s.models = r }
+}
+
+// NewResultsService constructs a ResultsService backed by the given
+// repositories and experiment config codec.
+func NewResultsService(er outbound.ExperimentRepository, sr outbound.SimulationRepository, ec outbound.ExperimentCodec, opts ...ResultsOption) *resultsSvc {
+	s := &resultsSvc{experimentRepo: er, simulationRepo: sr, codec: ec, models: DefaultModels()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// validate returns every problem with exp, including a model the service
+// cannot run or cannot give exp's assumptions and estimators.
+func (s *resultsSvc) validate(exp domain.Experiment) domain.FieldErrors {
+	return append(exp.ValidateFields(), s.models.ValidateConfig(exp.Config)...)
 }
 
 func (s *resultsSvc) CreateExperiment(ctx context.Context, exp domain.Experiment) (*domain.Experiment, error) {
+	if errs := s.validate(exp); len(errs) > 0 {
+		return nil, errs
+	}
 	if exp.ID == "" {
 		id, err := newID("exp")
 		if err != nil {
@@ -34,6 +62,56 @@ func (s *resultsSvc) CreateExperiment(ctx context.Context, exp domain.Experiment
 	return &exp, nil
 }
 
+func (s *resultsSvc) ImportExperiment(ctx context.Context, r io.Reader) (*domain.Experiment, error) {
+	exp, err := s.parseExperiment(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateExperiment(ctx, exp)
+}
+
+func (s *resultsSvc) ReplaceExperiment(ctx context.Context, id string, r io.Reader) (*domain.Experiment, error) {
+	old, err := s.experimentRepo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := s.parseExperiment(r)
+	if err != nil {
+		return nil, err
+	}
+	exp.ID = old.ID
+	exp.CreatedAt = old.CreatedAt
+	exp.UpdatedAt = time.Now().UTC()
+	if err := s.experimentRepo.SaveExperiment(ctx, exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+// parseExperiment decodes and validates a JSON experiment config. Every
+// failure wraps domain.ErrInvalid: the input, not the server, is at fault.
+// Problems with individual fields are reported together as a
+// domain.FieldErrors.
+func (s *resultsSvc) parseExperiment(r io.Reader) (domain.Experiment, error) {
+	exp, err := s.codec.ParseExperimentJSON(r)
+	if errors.Is(err, domain.ErrInvalid) {
+		return domain.Experiment{}, err
+	}
+	if err != nil {
+		return domain.Experiment{}, fmt.Errorf("%w: %w", domain.ErrInvalid, err)
+	}
+	// A codec need not validate what it decodes, so check what every
+	// experiment must satisfy here too.
+	if errs := s.validate(exp); len(errs) > 0 {
+		return domain.Experiment{}, errs
+	}
+	return exp, nil
+}
+
+func (s *resultsSvc) EncodeExperiment(exp domain.Experiment) ([]byte, error) {
+	return s.codec.EncodeExperimentJSON(exp)
+}
+
 func (s *resultsSvc) GetExperiment(ctx context.Context, id string) (*domain.Experiment, error) {
 	return s.experimentRepo.GetExperiment(ctx, id)
 }
@@ -42,6 +120,24 @@ func (s *resultsSvc) ListExperiments(ctx context.Context) ([]domain.Experiment,
 	return s.experimentRepo.ListExperiments(ctx)
 }
 
+// DeleteExperiment refuses while a run is queued or running: the runner would
+// otherwise write results for an experiment that no longer exists.
+func (s *resultsSvc) DeleteExperiment(ctx context.Context, id string) error {
+	if _, err := s.experimentRepo.GetExperiment(ctx, id); err != nil {
+		return err
+	}
+	runs, err := s.simulationRepo.ListRuns(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if run.Status.Active() {
+			return fmt.Errorf("delete experiment %s: run %s: %w", id, run.ID, domain.ErrRunActive)
+		}
+	}
+	return s.experimentRepo.DeleteExperiment(ctx, id)
+}
+
 func (s *resultsSvc) ListRuns(ctx context.Context, experimentID string) ([]domain.Run, error) {
 	return s.simulationRepo.ListRuns(ctx, experimentID)
 }
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/testdoubles"
+)
+
+// experimentBody encodes exp the way testdoubles.ExperimentCodec reads it.
+func experimentBody(t *testing.T, exp domain.Experiment) *strings.Reader {
+	t.Helper()
+	b, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.NewReader(string(b))
+}
+
+func validExperiment() domain.Experiment {
+	return domain.Experiment{
+		Name:      "60/40",
+		Portfolio: domain.Portfolio{Assets: []domain.PortfolioAsset{{Symbol: "SPY", Weight: 0.6}, {Symbol: "AGG", Weight: 0.4}}},
+		Config:    domain.SimulationConfig{Model: domain.ModelGBM, NumPaths: 10, HorizonDays: 21, LookbackDays: 252, StartValue: 1000},
+	}
+}
+
+func TestImportExperimentValidates(t *testing.T) {
+	deps := testdoubles.NewServerDeps()
+	svc := NewResultsService(deps.Experiments, deps.Runs, deps.Codec)
+	ctx := context.Background()
+
+	noAssets := validExperiment()
+	noAssets.Portfolio.Assets = nil
+	noPaths := validExperiment()
+	noPaths.Config.NumPaths = 0
+	for name, body := range map[string]*strings.Reader{
+		"malformed": strings.NewReader(`{"Name":`),
+		"no assets": experimentBody(t, noAssets),
+		"no paths":  experimentBody(t, noPaths),
+	} {
+		if _, err := svc.ImportExperiment(ctx, body); !errors.Is(err, domain.ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	// Every problem is reported, by field.
+	bad := validExperiment()
+	bad.Portfolio.Assets[1].Weight = 0.3
+	bad.Config.HorizonDays = 0
+	_, err := svc.ImportExperiment(ctx, experimentBody(t, bad))
+	var fields domain.FieldErrors
+	if !errors.As(err, &fields) {
+		t.Fatalf("err = %v, want FieldErrors", err)
+	}
+	want := domain.FieldErrors{
+		{Field: "portfolio.assets", Message: "weights must sum to 1, got 0.9"},
+		{Field: "horizon_days", Message: "must be positive"},
+	}
+	if !slices.Equal(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+
+	exp, err := svc.ImportExperiment(ctx, experimentBody(t, validExperiment()))
+	if err != nil {
+		t.Fatalf("ImportExperiment: %v", err)
+	}
+	if exp.ID == "" || exp.CreatedAt.IsZero() {
+		t.Errorf("imported experiment = %+v, want an ID and CreatedAt", exp)
+	}
+	if _, err := deps.Experiments.GetExperiment(ctx, exp.ID); err != nil {
+		t.Errorf("imported experiment not saved: %v", err)
+	}
+}
+
+func TestCreateExperimentValidates(t *testing.T) {
+	deps := testdoubles.NewServerDeps()
+	svc := NewResultsService(deps.Experiments, deps.Runs, deps.Codec)
+	ctx := context.Background()
+
+	bad := validExperiment()
+	bad.Config.Heston.Rho = 2
+	_, err := svc.CreateExperiment(ctx, bad)
+	var fields domain.FieldErrors
+	if !errors.As(err, &fields) || !errors.Is(err, domain.ErrInvalid) || fields[0].Field != "heston_rho" {
+		t.Fatalf("err = %v, want FieldErrors on heston_rho", err)
+	}
+	if exps, _ := deps.Experiments.ListExperiments(ctx); len(exps) != 0 {
+		t.Errorf("invalid experiment saved: %+v", exps)
+	}
+}
+
+func TestCreateExperimentChecksModel(t *testing.T) {
+	deps := testdoubles.NewServerDeps()
+	svc := NewResultsService(deps.Experiments, deps.Runs, deps.Codec)
+	ctx := context.Background()
+
+	unknown := validExperiment()
+	unknown.Config.Model = "foo"
+	assumed := validExperiment()
+	assumed.Config.Model = domain.ModelBootstrap
+	assumed.Config.Assumptions = &domain.CapitalMarketAssumptions{Assets: []domain.AssetAssumption{
+		{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16},
+		{Symbol: "AGG", ExpectedReturn: 0.03, Volatility: 0.05},
+	}}
+	estimated := validExperiment()
+	estimated.Config.Model = domain.ModelBlockBootstrap
+	estimated.Config.Estimator.Cov = domain.CovEWMA
+	for name, exp := range map[string]domain.Experiment{"unknown": unknown, "assumptions": assumed, "estimator": estimated} {
+		_, err := svc.CreateExperiment(ctx, exp)
+		var fields domain.FieldErrors
+		if !errors.As(err, &fields) || len(fields) != 1 || fields[0].Field != "model" {
+			t.Errorf("%s: err = %v, want one error on model", name, err)
+		}
+	}
+	if _, err := svc.ImportExperiment(ctx, experimentBody(t, unknown)); !errors.Is(err, domain.ErrInvalid) {
+		t.Errorf("import with an unknown model: err = %v, want ErrInvalid", err)
+	}
+
+	reg, err := NewModelRegistry(constModel{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc = NewResultsService(deps.Experiments, deps.Runs, deps.Codec, WithResultsModels(reg))
+	if _, err := svc.CreateExperiment(ctx, unknown); err != nil {
+		t.Errorf("model registered with WithResultsModels: %v", err)
+	}
+}
+
+func TestReplaceExperimentKeepsIdentity(t *testing.T) {
+	deps := testdoubles.NewServerDeps()
+	svc := NewResultsService(deps.Experiments, deps.Runs, deps.Codec)
+	ctx := context.Background()
+	orig, err := svc.CreateExperiment(ctx, validExperiment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := validExperiment()
+	next.ID = "ignored"
+	next.Name = "renamed"
+	got, err := svc.ReplaceExperiment(ctx, orig.ID, experimentBody(t, next))
+	if err != nil {
+		t.Fatalf("ReplaceExperiment: %v", err)
+	}
+	if got.ID != orig.ID || !got.CreatedAt.Equal(orig.CreatedAt) || got.Name != "renamed" {
+		t.Errorf("replaced = %+v, want ID %s, CreatedAt %v and the new name", got, orig.ID, orig.CreatedAt)
+	}
+	if _, err := svc.ReplaceExperiment(ctx, "missing", experimentBody(t, next)); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("replace missing err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRefusesActiveRuns(t *testing.T) {
+	sim, deps := newRunnerFixture(t)
+	results := NewResultsService(deps.Experiments, deps.Runs, deps.Codec)
+	ctx := context.Background()
+	run, err := sim.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+
+	if err := sim.DeleteRun(ctx, run.ID); !errors.Is(err, domain.ErrRunActive) {
+		t.Errorf("DeleteRun queued err = %v, want ErrRunActive", err)
+	}
+	if err := results.DeleteExperiment(ctx, "exp"); !errors.Is(err, domain.ErrRunActive) {
+		t.Errorf("DeleteExperiment err = %v, want ErrRunActive", err)
+	}
+
+	if err := sim.CancelRun(ctx, run.ID); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	if err := sim.DeleteRun(ctx, run.ID); err != nil {
+		t.Errorf("DeleteRun finished: %v", err)
+	}
+	if _, err := sim.GetRun(ctx, run.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetRun after delete err = %v, want ErrNotFound", err)
+	}
+	if err := results.DeleteExperiment(ctx, "exp"); err != nil {
+		t.Errorf("DeleteExperiment with no active runs: %v", err)
+	}
+}
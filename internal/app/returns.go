@@ -0,0 +1,170 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// alignReturns inner-joins the price histories of several assets on date and
+// returns the daily log-return matrix: rows[t][i] is asset i's log-return
+// from the (t)th to the (t+1)th common trading day. dates[t] is the date the
+// return was realised on. Dates missing from any series are dropped.
+func alignReturns(series [][]domain.PriceRecord) (dates []time.Time, rows [][]float64) {
+	if len(series) == 0 {
+		return nil, nil
+	}
+	prices := make([]map[time.Time]float64, len(series))
+	for i, recs := range series {
+		prices[i] = make(map[time.Time]float64, len(recs))
+		for _, r := range recs {
+			if r.AdjustedClose > 0 {
+				prices[i][r.Date] = r.AdjustedClose
+			}
+		}
+	}
+	var common []time.Time
+	for d := range prices[0] {
+		inAll := true
+		for _, m := range prices[1:] {
+			if _, ok := m[d]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			common = append(common, d)
+		}
+	}
+	sort.Slice(common, func(a, b int) bool { return common[a].Before(common[b]) })
+
+	for t := 1; t < len(common); t++ {
+		row := make([]float64, len(series))
+		for i, m := range prices {
+			row[i] = math.Log(m[common[t]] / m[common[t-1]])
+		}
+		dates = append(dates, common[t])
+		rows = append(rows, row)
+	}
+	return dates, rows
+}
+
+// sampleMoments returns the per-asset mean vector and the (population)
+// covariance matrix of a returns matrix laid out as rows[t][asset].
+func sampleMoments(rows [][]float64) (mean []float64, cov [][]float64) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	n := len(rows[0])
+	mean = make([]float64, n)
+	for _, r := range rows {
+		for i, v := range r {
+			mean[i] += v
+		}
+	}
+	t := float64(len(rows))
+	for i := range mean {
+		mean[i] /= t
+	}
+	cov = make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+	}
+	for _, r := range rows {
+		for i := 0; i < n; i++ {
+			di := r[i] - mean[i]
+			for j := 0; j <= i; j++ {
+				cov[i][j] += di * (r[j] - mean[j])
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			cov[i][j] /= t
+			cov[j][i] = cov[i][j]
+		}
+	}
+	return mean, cov
+}
+
+// correlation returns the correlation matrix of the covariance cov. An asset
+// with no variance is uncorrelated with the others.
+func correlation(cov [][]float64) [][]float64 {
+	corr := make([][]float64, len(cov))
+	for i := range corr {
+		corr[i] = make([]float64, len(cov))
+		for j := range corr[i] {
+			if d := math.Sqrt(cov[i][i] * cov[j][j]); i != j && d > 0 {
+				corr[i][j] = cov[i][j] / d
+			}
+		}
+		corr[i][i] = 1
+	}
+	return corr
+}
+
+// checkCalendars reports trading days that are present in some series but
+// missing from others within the range all series cover. Days outside that
+// common range (e.g. one symbol listing later) are not treated as gaps.
+func checkCalendars(symbols []string, series [][]domain.PriceRecord) error {
+	if len(series) < 2 {
+		return nil
+	}
+	var lo, hi time.Time
+	present := make([]map[time.Time]bool, len(series))
+	for i, recs := range series {
+		present[i] = make(map[time.Time]bool, len(recs))
+		first, last := time.Time{}, time.Time{}
+		for _, r := range recs {
+			present[i][r.Date] = true
+			if first.IsZero() || r.Date.Before(first) {
+				first = r.Date
+			}
+			if r.Date.After(last) {
+				last = r.Date
+			}
+		}
+		if i == 0 || first.After(lo) {
+			lo = first
+		}
+		if i == 0 || last.Before(hi) {
+			hi = last
+		}
+	}
+	if hi.Before(lo) {
+		return fmt.Errorf("calendar mismatch: %s have no overlapping dates", strings.Join(symbols, "/"))
+	}
+	union := map[time.Time]bool{}
+	for _, m := range present {
+		for d := range m {
+			if !d.Before(lo) && !d.After(hi) {
+				union[d] = true
+			}
+		}
+	}
+	var problems []string
+	for i, m := range present {
+		missing := 0
+		var firstMissing time.Time
+		for d := range union {
+			if !m[d] {
+				missing++
+				if firstMissing.IsZero() || d.Before(firstMissing) {
+					firstMissing = d
+				}
+			}
+		}
+		if missing > 0 {
+			problems = append(problems, fmt.Sprintf("%s missing %d of %d trading days (first %s)",
+				symbols[i], missing, len(union), firstMissing.Format("2006-01-02")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("calendar mismatch between %s: %s", strings.Join(symbols, "/"), strings.Join(problems, "; "))
+	}
+	return nil
+}
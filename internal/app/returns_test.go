@@ -0,0 +1,89 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+func day(n int) time.Time {
+	return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestAlignReturnsInnerJoin(t *testing.T) {
+	a := []domain.PriceRecord{
+		{Date: day(0), AdjustedClose: 100},
+		{Date: day(1), AdjustedClose: 110},
+		{Date: day(2), AdjustedClose: 121},
+	}
+	b := []domain.PriceRecord{
+		{Date: day(0), AdjustedClose: 50},
+		{Date: day(2), AdjustedClose: 40},
+	}
+
+	dates, rows := alignReturns([][]domain.PriceRecord{a, b})
+
+	if len(rows) != 1 || len(dates) != 1 {
+		t.Fatalf("got %d rows, want 1 (day 1 missing from b)", len(rows))
+	}
+	if !dates[0].Equal(day(2)) {
+		t.Errorf("date = %v, want %v", dates[0], day(2))
+	}
+	if math.Abs(rows[0][0]-math.Log(1.21)) > 1e-12 || math.Abs(rows[0][1]-math.Log(0.8)) > 1e-12 {
+		t.Errorf("row = %v, want [ln 1.21, ln 0.8]", rows[0])
+	}
+}
+
+func TestAlignReturnsUnsortedInput(t *testing.T) {
+	a := []domain.PriceRecord{
+		{Date: day(2), AdjustedClose: 120},
+		{Date: day(0), AdjustedClose: 100},
+		{Date: day(1), AdjustedClose: 110},
+	}
+
+	_, rows := alignReturns([][]domain.PriceRecord{a})
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0][0] <= 0 || rows[1][0] <= 0 {
+		t.Errorf("returns should be positive in date order, got %v", rows)
+	}
+}
+
+func TestCheckCalendars(t *testing.T) {
+	series := func(days ...int) []domain.PriceRecord {
+		recs := make([]domain.PriceRecord, len(days))
+		for i, d := range days {
+			recs[i] = domain.PriceRecord{Date: day(d), AdjustedClose: 100}
+		}
+		return recs
+	}
+	tests := []struct {
+		name    string
+		series  [][]domain.PriceRecord
+		wantErr string
+	}{
+		{"identical calendars", [][]domain.PriceRecord{series(0, 1, 2), series(0, 1, 2)}, ""},
+		{"later listing is not a gap", [][]domain.PriceRecord{series(0, 1, 2, 3), series(2, 3)}, ""},
+		{"missing day inside overlap", [][]domain.PriceRecord{series(0, 1, 2, 3), series(0, 2, 3)}, "BBB missing 1 of 4"},
+		{"no overlap", [][]domain.PriceRecord{series(0, 1), series(5, 6)}, "no overlapping dates"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkCalendars([]string{"AAA", "BBB"}, tc.series)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("err = %v, want substring %q", err, tc.wantErr)
+			}
+		})
+	}
+}
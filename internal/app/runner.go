@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Runner executes queued simulation runs in the background. The runs table is
+// the queue: Runner claims the oldest queued row, simulates it under a context
+// owned by the process rather than by the HTTP request that queued it, and
+// records the terminal state. Construct one per process and call Start.
+type Runner struct {
+	sim          *simulationSvc
+	logger       *slog.Logger
+	concurrency  int
+	pollInterval time.Duration
+	reap         bool
+
+	// runs is the parent context of every run; Interrupt cancels it.
+	runs      context.Context
+	interrupt context.CancelCauseFunc
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithConcurrency sets how many runs may execute at once. Values below 1 are
+// ignored. The default is 1.
+func WithConcurrency(n int) RunnerOption {
+	return func(r *Runner) {
+		if n >= 1 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithPollInterval sets how long the Runner waits before checking an empty
+// queue again. The default is 500ms.
+func WithPollInterval(d time.Duration) RunnerOption {
+	return func(r *Runner) {
+		if d > 0 {
+			r.pollInterval = d
+		}
+	}
+}
+
+// WithOrphanReaping sets whether Start first fails runs left running by a
+// previous process. The default is true. Turn it off in a process that may
+// share the database with a server, whose running runs are not orphans.
+func WithOrphanReaping(on bool) RunnerOption {
+	return func(r *Runner) { r.reap = on }
+}
+
+// WithLogger sets the logger used for run lifecycle events.
+func WithLogger(l *slog.Logger) RunnerOption {
+	return func(r *Runner) { r.logger = l }
+}
+
+// NewRunner constructs a Runner that executes runs queued through sim. It does
+// not start it; call Start.
+func NewRunner(sim *simulationSvc, opts ...RunnerOption) *Runner {
+	r := &Runner{
+		sim:          sim,
+		logger:       slog.Default(),
+		concurrency:  1,
+		pollInterval: 500 * time.Millisecond,
+		reap:         true,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	r.runs, r.interrupt = context.WithCancelCause(context.Background())
+	return r
+}
+
+// Start fails any run left running by a previous process (unless
+// WithOrphanReaping turned that off), then claims and executes queued runs
+// until ctx is done. Cancelling ctx only stops new
+// claims: Start returns once the runs already in flight have finished, which
+// Interrupt can hurry along.
+func (r *Runner) Start(ctx context.Context) error {
+	if r.reap {
+		if err := r.reapOrphans(ctx); err != nil {
+			return err
+		}
+	}
+	slots := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+		run, err := r.sim.simulationRepo.ClaimNextRun(ctx, time.Now().UTC())
+		if err != nil || run == nil {
+			<-slots
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error("claim run", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(r.pollInterval):
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(run domain.Run) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			r.execute(r.runs, run)
+		}(*run)
+	}
+}
+
+// ExecuteRun claims runID and executes it under ctx, returning once it has
+// finished. It starts no other run, and does nothing if runID is no longer
+// queued: another process claimed it, or it was cancelled. Use it to execute
+// a single run in a process that may share the database with a server.
+func (r *Runner) ExecuteRun(ctx context.Context, runID string) error {
+	run, err := r.sim.simulationRepo.ClaimRun(ctx, runID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("claim run: %w", err)
+	}
+	if run != nil {
+		r.execute(ctx, *run)
+	}
+	return nil
+}
+
+// Interrupt stops every in-flight run; each is recorded as failed with error
+// "interrupted". Use it when shutdown cannot wait for runs to finish.
+func (r *Runner) Interrupt() {
+	r.interrupt(errInterrupted)
+}
+
+// reapOrphans marks runs still running from a previous process as failed.
+// Nothing in this process can have claimed them yet.
+func (r *Runner) reapOrphans(ctx context.Context) error {
+	n, err := r.sim.simulationRepo.ReapRunning(ctx, time.Now().UTC(), domain.RunInterrupted)
+	if err != nil {
+		return fmt.Errorf("reap orphaned runs: %w", err)
+	}
+	if n > 0 {
+		r.logger.Warn("marked orphaned runs as failed", "count", n)
+	}
+	return nil
+}
+
+func (r *Runner) execute(ctx context.Context, run domain.Run) {
+	start := time.Now()
+	r.logger.Info("run started", "run", run.ID, "experiment", run.ExperimentID)
+	switch err := r.sim.execute(ctx, run); {
+	case errors.Is(err, errRunCancelled):
+		r.logger.Info("run cancelled", "run", run.ID, "elapsed", time.Since(start))
+	case err != nil:
+		r.logger.Error("run failed", "run", run.ID, "err", err, "elapsed", time.Since(start))
+	default:
+		r.logger.Info("run complete", "run", run.ID, "elapsed", time.Since(start))
+	}
+}
@@ -0,0 +1,473 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gjcourt/drift/internal/domain"
+	"github.com/gjcourt/drift/internal/testdoubles"
+)
+
+// newRunnerFixture returns a simulation service over in-memory fakes with one
+// GBM experiment, "exp", whose single asset has a year of daily prices.
+func newRunnerFixture(t *testing.T) (*simulationSvc, *testdoubles.ServerDeps) {
+	t.Helper()
+	deps := testdoubles.NewServerDeps()
+	ctx := context.Background()
+	recs := make([]domain.PriceRecord, 253)
+	for i := range recs {
+		recs[i] = domain.PriceRecord{
+			Symbol:        "SPY",
+			Date:          day(i),
+			AdjustedClose: 100 * math.Exp(0.0003*float64(i)+0.01*math.Sin(float64(i))),
+		}
+	}
+	if err := deps.Assets.UpsertPriceRecords(ctx, recs); err != nil {
+		t.Fatal(err)
+	}
+	seed := int64(7)
+	exp := domain.Experiment{
+		ID:        "exp",
+		Portfolio: domain.Portfolio{Assets: []domain.PortfolioAsset{{Symbol: "SPY", Weight: 1}}},
+		Config: domain.SimulationConfig{
+			Model:        domain.ModelGBM,
+			NumPaths:     20,
+			HorizonDays:  21,
+			LookbackDays: 252,
+			StartValue:   1000,
+			Seed:         &seed,
+		},
+	}
+	if err := deps.Experiments.SaveExperiment(ctx, exp); err != nil {
+		t.Fatal(err)
+	}
+	return NewSimulationService(deps.Assets, deps.Runs, deps.Experiments, deps.Paths), deps
+}
+
+// startRunner runs r until the test ends.
+func startRunner(t *testing.T, r *Runner) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	})
+}
+
+// waitForStatus polls until the run reaches a terminal status.
+func waitForStatus(t *testing.T, svc *simulationSvc, runID string) *domain.Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := svc.GetRun(context.Background(), runID)
+		if err != nil {
+			t.Fatalf("GetRun: %v", err)
+		}
+		if !run.Status.Active() {
+			return run
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("run %s still active after 5s", runID)
+	return nil
+}
+
+func TestEnqueueRunQueuesWithoutSimulating(t *testing.T) {
+	svc, _ := newRunnerFixture(t)
+	run, err := svc.EnqueueRun(context.Background(), "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	if run.Status != domain.StatusQueued || run.QueuedAt.IsZero() || !run.StartedAt.IsZero() {
+		t.Errorf("run = %+v, want queued with QueuedAt set and StartedAt zero", run)
+	}
+	if run.ProgressTotal != 20 {
+		t.Errorf("ProgressTotal = %d, want NumPaths 20", run.ProgressTotal)
+	}
+}
+
+func TestEnqueueRunReturnsActiveRun(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	for _, status := range []domain.ExperimentStatus{domain.StatusQueued, domain.StatusRunning} {
+		run.Status = status
+		_ = deps.Runs.SaveRun(ctx, *run)
+		again, err := svc.EnqueueRun(ctx, "exp")
+		if err != nil {
+			t.Fatalf("EnqueueRun with a %s run: %v", status, err)
+		}
+		if again.ID != run.ID {
+			t.Errorf("EnqueueRun with a %s run queued %s, want that run %s", status, again.ID, run.ID)
+		}
+	}
+	if runs, _ := deps.Runs.ListRuns(ctx, "exp"); len(runs) != 1 {
+		t.Errorf("%d runs stored, want 1", len(runs))
+	}
+
+	run.Status = domain.StatusComplete
+	_ = deps.Runs.SaveRun(ctx, *run)
+	next, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun after completion: %v", err)
+	}
+	if next.ID == run.ID || next.Status != domain.StatusQueued {
+		t.Errorf("EnqueueRun after completion = %s %s, want a new queued run", next.ID, next.Status)
+	}
+}
+
+func TestRunnerCompletesQueuedRuns(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	first, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+
+	got := waitForStatus(t, svc, first.ID)
+	if got.Status != domain.StatusComplete {
+		t.Fatalf("status = %s (%s), want complete", got.Status, got.Error)
+	}
+	if got.FinishedAt == nil || got.StartedAt.IsZero() || got.Bands == nil || got.Stats.P50 <= 0 {
+		t.Errorf("completed run missing results: %+v", got)
+	}
+	if got.Fit != nil {
+		t.Errorf("GBM run recorded a fit: %+v", got.Fit)
+	}
+	if got.ProgressDone != got.ProgressTotal {
+		t.Errorf("progress = %d/%d, want done", got.ProgressDone, got.ProgressTotal)
+	}
+
+	// Once the first run is terminal a new one is queued and picked up too.
+	second, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("EnqueueRun reused a finished run")
+	}
+	if got := waitForStatus(t, svc, second.ID); got.Status != domain.StatusComplete {
+		t.Errorf("second run status = %s, want complete", got.Status)
+	}
+	runs, _ := deps.Runs.ListRuns(ctx, "exp")
+	if len(runs) != 2 {
+		t.Errorf("got %d runs, want 2", len(runs))
+	}
+}
+
+// runWithConstantPriceAsset executes a model run of a 60/40 mix of SPY and
+// CASH, whose price never moves, and returns it once complete.
+func runWithConstantPriceAsset(t *testing.T, model domain.SimulationModel) *domain.Run {
+	t.Helper()
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	cash := make([]domain.PriceRecord, 253)
+	for i := range cash {
+		cash[i] = domain.PriceRecord{Symbol: "CASH", Date: day(i), AdjustedClose: 1}
+	}
+	if err := deps.Assets.UpsertPriceRecords(ctx, cash); err != nil {
+		t.Fatal(err)
+	}
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Portfolio.Assets = []domain.PortfolioAsset{{Symbol: "SPY", Weight: 0.6}, {Symbol: "CASH", Weight: 0.4}}
+	exp.Config.Model = model
+	if err := deps.Experiments.SaveExperiment(ctx, *exp); err != nil {
+		t.Fatal(err)
+	}
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	if err := NewRunner(svc).ExecuteRun(ctx, run.ID); err != nil {
+		t.Fatalf("ExecuteRun: %v", err)
+	}
+	got, _ := svc.GetRun(ctx, run.ID)
+	if got.Status != domain.StatusComplete {
+		t.Fatalf("%s run = %s %q, want complete", model, got.Status, got.Error)
+	}
+	return got
+}
+
+// wantFiniteFit checks that every number the run's fit records is finite.
+func wantFiniteFit(t *testing.T, run *domain.Run) {
+	t.Helper()
+	if run.Fit == nil {
+		t.Fatal("no fit recorded")
+	}
+	finite := func(v float64) bool { return !math.IsNaN(v) && !math.IsInf(v, 0) }
+	if !finite(run.Fit.LogLikelihood) {
+		t.Errorf("log-likelihood = %v, want finite", run.Fit.LogLikelihood)
+	}
+	for k, v := range run.Fit.Params {
+		if !finite(v) {
+			t.Errorf("%s = %v, want finite", k, v)
+		}
+	}
+}
+
+func TestRunnerSimulatesConstantPriceAsset(t *testing.T) {
+	runWithConstantPriceAsset(t, domain.ModelGBM)
+	wantFiniteFit(t, runWithConstantPriceAsset(t, domain.ModelRegimeSwitching))
+}
+
+func TestRunnerFitsStudentTWithConstantPriceAsset(t *testing.T) {
+	wantFiniteFit(t, runWithConstantPriceAsset(t, domain.ModelStudentT))
+}
+
+func TestRunnerRecordsModelFit(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Config.Model = domain.ModelGARCH
+	if err := deps.Experiments.SaveExperiment(ctx, *exp); err != nil {
+		t.Fatal(err)
+	}
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+
+	got := waitForStatus(t, svc, run.ID)
+	if got.Status != domain.StatusComplete {
+		t.Fatalf("status = %s (%s), want complete", got.Status, got.Error)
+	}
+	if got.Fit == nil || got.Fit.Model != domain.ModelGARCH || len(got.Fit.Assets) != 1 || got.Fit.Assets[0].Symbol != "SPY" {
+		t.Errorf("fit = %+v, want the GARCH fit of SPY", got.Fit)
+	}
+}
+
+func TestRunnerRecordsFailure(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	run, err := svc.EnqueueRun(context.Background(), "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	deps.Experiments.Err = errors.New("boom")
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+
+	got := waitForStatus(t, svc, run.ID)
+	if got.Status != domain.StatusFailed || got.Error == "" || got.FinishedAt == nil {
+		t.Errorf("run = %+v, want failed with error and FinishedAt", got)
+	}
+}
+
+func TestFailureIsSavedBeforeItIsPublished(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	events, err := svc.WatchRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("WatchRun: %v", err)
+	}
+	deps.Experiments.Err = errors.New("boom")
+	go func() { _ = NewRunner(svc).ExecuteRun(ctx, run.ID) }()
+
+	for p := range events {
+		if p.Status != domain.StatusFailed {
+			continue
+		}
+		if got, _ := svc.GetRun(ctx, run.ID); got.Status != domain.StatusFailed {
+			t.Errorf("published failed while the run is saved as %s", got.Status)
+		}
+		return
+	}
+	t.Fatal("no failed update published")
+}
+
+func TestRunnerReapsOrphansOnStart(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	orphan := domain.Run{ID: "run_orphan", ExperimentID: "exp", Status: domain.StatusRunning, StartedAt: time.Now().UTC()}
+	if err := deps.Runs.SaveRun(ctx, orphan); err != nil {
+		t.Fatal(err)
+	}
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+
+	got := waitForStatus(t, svc, orphan.ID)
+	if got.Status != domain.StatusFailed || got.Error != domain.RunInterrupted {
+		t.Errorf("orphan = %s %q, want failed %q", got.Status, got.Error, domain.RunInterrupted)
+	}
+}
+
+func TestRunnerWithoutReapingLeavesRunningRuns(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	other := domain.Run{ID: "run_other", ExperimentID: "exp", Status: domain.StatusRunning, StartedAt: time.Now().UTC()}
+	if err := deps.Runs.SaveRun(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	queued := domain.Run{ID: "run_queued", ExperimentID: "exp", Status: domain.StatusQueued, QueuedAt: time.Now().UTC(), ProgressTotal: 20}
+	if err := deps.Runs.SaveRun(ctx, queued); err != nil {
+		t.Fatal(err)
+	}
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond), WithOrphanReaping(false)))
+
+	if got := waitForStatus(t, svc, queued.ID); got.Status != domain.StatusComplete {
+		t.Errorf("queued run = %s, want complete", got.Status)
+	}
+	if got, _ := svc.GetRun(ctx, other.ID); got.Status != domain.StatusRunning {
+		t.Errorf("run of another process = %s, want still running", got.Status)
+	}
+}
+
+func TestExecuteRunExecutesOnlyThatRun(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	older := domain.Run{ID: "run_older", ExperimentID: "exp", Status: domain.StatusQueued, QueuedAt: time.Now().UTC().Add(-time.Minute), ProgressTotal: 20}
+	mine := domain.Run{ID: "run_mine", ExperimentID: "exp", Status: domain.StatusQueued, QueuedAt: time.Now().UTC(), ProgressTotal: 20}
+	for _, r := range []domain.Run{older, mine} {
+		if err := deps.Runs.SaveRun(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewRunner(svc)
+	if err := r.ExecuteRun(ctx, mine.ID); err != nil {
+		t.Fatalf("ExecuteRun: %v", err)
+	}
+	if got, _ := svc.GetRun(ctx, mine.ID); got.Status != domain.StatusComplete {
+		t.Errorf("requested run = %s, want complete", got.Status)
+	}
+	if got, _ := svc.GetRun(ctx, older.ID); got.Status != domain.StatusQueued {
+		t.Errorf("older queued run = %s, want left queued", got.Status)
+	}
+	// A run no longer queued is left to whoever has it.
+	if err := r.ExecuteRun(ctx, mine.ID); err != nil {
+		t.Errorf("ExecuteRun of a finished run: %v", err)
+	}
+}
+
+// waitForProgress blocks until the run has simulated at least one path.
+func waitForProgress(t *testing.T, svc *simulationSvc, runID string) {
+	t.Helper()
+	events, err := svc.WatchRun(context.Background(), runID)
+	if err != nil {
+		t.Fatalf("WatchRun: %v", err)
+	}
+	for p := range events {
+		if p.Done > 0 {
+			return
+		}
+	}
+	t.Fatal("run finished before reporting progress")
+}
+
+func TestCancelQueuedRun(t *testing.T) {
+	svc, _ := newRunnerFixture(t)
+	ctx := context.Background()
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	if err := svc.CancelRun(ctx, run.ID); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	got, _ := svc.GetRun(ctx, run.ID)
+	if got.Status != domain.StatusCancelled || got.FinishedAt == nil {
+		t.Errorf("run = %s, FinishedAt %v; want cancelled and finished", got.Status, got.FinishedAt)
+	}
+	if err := svc.CancelRun(ctx, run.ID); !errors.Is(err, domain.ErrRunNotActive) {
+		t.Errorf("second CancelRun err = %v, want ErrRunNotActive", err)
+	}
+}
+
+func TestCancelRunningRun(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		svc, deps := newRunnerFixture(t)
+		ctx := context.Background()
+		exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+		exp.Config.NumPaths = 200_000
+		exp.Config.HorizonDays = 2520
+		exp.Config.KeepPartial = keep
+		_ = deps.Experiments.SaveExperiment(ctx, *exp)
+
+		run, err := svc.EnqueueRun(ctx, "exp")
+		if err != nil {
+			t.Fatalf("EnqueueRun: %v", err)
+		}
+		startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+		waitForProgress(t, svc, run.ID)
+		if err := svc.CancelRun(ctx, run.ID); err != nil {
+			t.Fatalf("CancelRun: %v", err)
+		}
+
+		got := waitForStatus(t, svc, run.ID)
+		if got.Status != domain.StatusCancelled {
+			t.Fatalf("keep=%v: status = %s (%s), want cancelled", keep, got.Status, got.Error)
+		}
+		if got.ProgressDone == 0 || got.ProgressDone >= got.ProgressTotal {
+			t.Errorf("keep=%v: progress = %d/%d, want a partial count", keep, got.ProgressDone, got.ProgressTotal)
+		}
+		if hasResults := got.Bands != nil && got.Stats.P50 > 0; hasResults != keep {
+			t.Errorf("keep=%v: partial results recorded = %v", keep, hasResults)
+		}
+	}
+}
+
+func TestCancelRunExecutingInAnotherProcess(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Config.NumPaths = 200_000
+	exp.Config.HorizonDays = 2520
+	_ = deps.Experiments.SaveExperiment(ctx, *exp)
+	// A second service over the same repositories stands in for a process
+	// sharing the database, such as a server while drift run --wait executes.
+	other := NewSimulationService(deps.Assets, deps.Runs, deps.Experiments, deps.Paths)
+
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	startRunner(t, NewRunner(svc, WithPollInterval(time.Millisecond)))
+	waitForProgress(t, svc, run.ID)
+	if err := other.CancelRun(ctx, run.ID); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	if got := waitForStatus(t, svc, run.ID); got.Status != domain.StatusCancelled {
+		t.Errorf("status = %s (%s), want cancelled", got.Status, got.Error)
+	}
+	if _, ok := other.cancels.Load(run.ID); ok {
+		t.Error("CancelRun left an entry for a run this service never executed")
+	}
+	if err := other.CancelRun(ctx, run.ID); !errors.Is(err, domain.ErrRunNotActive) {
+		t.Errorf("second CancelRun err = %v, want ErrRunNotActive", err)
+	}
+}
+
+func TestRunnerInterruptFailsInFlightRuns(t *testing.T) {
+	svc, deps := newRunnerFixture(t)
+	ctx := context.Background()
+	exp, _ := deps.Experiments.GetExperiment(ctx, "exp")
+	exp.Config.NumPaths = 200_000
+	exp.Config.HorizonDays = 2520
+	_ = deps.Experiments.SaveExperiment(ctx, *exp)
+
+	run, err := svc.EnqueueRun(ctx, "exp")
+	if err != nil {
+		t.Fatalf("EnqueueRun: %v", err)
+	}
+	r := NewRunner(svc, WithPollInterval(time.Millisecond))
+	startRunner(t, r)
+	waitForProgress(t, svc, run.ID)
+	r.Interrupt()
+
+	got := waitForStatus(t, svc, run.ID)
+	if got.Status != domain.StatusFailed || got.Error != domain.RunInterrupted {
+		t.Errorf("run = %s %q, want failed %q", got.Status, got.Error, domain.RunInterrupted)
+	}
+}
@@ -2,8 +2,8 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
 	"math/rand/v2"
 	"runtime"
 	"sync"
@@ -17,180 +17,503 @@ type simulationSvc struct {
 	assetRepo      outbound.AssetRepository
 	simulationRepo outbound.SimulationRepository
 	experimentRepo outbound.ExperimentRepository
+	pathRepo       outbound.PathRepository
+	models         *ModelRegistry
+	progress       progressHub
+	cancels        sync.Map // run ID → context.CancelCauseFunc of runs executing here
+}
+
+var (
+	// errRunCancelled is the cancellation cause of a run stopped by CancelRun.
+	errRunCancelled = errors.New("cancelled")
+	// errInterrupted is the cancellation cause of runs stopped by shutdown.
+	errInterrupted = errors.New(domain.RunInterrupted)
+)
+
+// SimulationOption configures the service built by NewSimulationService.
+type SimulationOption func(*simulationSvc)
+
+// WithModels sets the models the service can run. The default is
+// DefaultModels.
+func WithModels(r *ModelRegistry) SimulationOption {
+	return func(s *simulationSvc) { s.models = r }
 }
 
 // NewSimulationService constructs a SimulationService backed by the given repositories.
-func NewSimulationService(ar outbound.AssetRepository, sr outbound.SimulationRepository, er outbound.ExperimentRepository) *simulationSvc {
-	return &simulationSvc{assetRepo: ar, simulationRepo: sr, experimentRepo: er}
+func NewSimulationService(ar outbound.AssetRepository, sr outbound.SimulationRepository, er outbound.ExperimentRepository, pr outbound.PathRepository, opts ...SimulationOption) *simulationSvc {
+	s := &simulationSvc{assetRepo: ar, simulationRepo: sr, experimentRepo: er, pathRepo: pr, models: DefaultModels()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListModels describes the models the service can run.
+func (s *simulationSvc) ListModels() []domain.ModelSpec {
+	return s.models.Specs()
 }
 
-func (s *simulationSvc) RunExperiment(ctx context.Context, experimentID string) (*domain.Run, error) {
+// EnqueueRun records a queued run for the experiment and returns it without
+// simulating anything; a Runner picks it up. If the experiment already has a
+// queued or running run, that run is returned instead of queueing another, so
+// a re-submitted form does not execute the experiment twice.
+func (s *simulationSvc) EnqueueRun(ctx context.Context, experimentID string) (*domain.Run, error) {
 	exp, err := s.experimentRepo.GetExperiment(ctx, experimentID)
 	if err != nil {
 		return nil, fmt.Errorf("get experiment: %w", err)
 	}
+	runs, err := s.simulationRepo.ListRuns(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	for _, r := range runs {
+		if r.Status.Active() {
+			return &r, nil
+		}
+	}
 	runID, err := newID("run")
 	if err != nil {
 		return nil, fmt.Errorf("generate run id: %w", err)
 	}
 	run := domain.Run{
-		ID:           runID,
-		ExperimentID: experimentID,
-		StartedAt:    time.Now().UTC(),
-		Status:       domain.StatusRunning,
+		ID:            runID,
+		ExperimentID:  experimentID,
+		QueuedAt:      time.Now().UTC(),
+		Status:        domain.StatusQueued,
+		ProgressTotal: exp.Config.NumPaths,
 	}
 	if err := s.simulationRepo.SaveRun(ctx, run); err != nil {
 		return nil, fmt.Errorf("save run: %w", err)
 	}
-	paths, simErr := s.simulate(ctx, exp)
-	now := time.Now().UTC()
-	run.FinishedAt = &now
-	if simErr != nil {
-		run.Status = domain.StatusFailed
-		run.Error = simErr.Error()
-		_ = s.simulationRepo.SaveRun(ctx, run)
-		return nil, simErr
+	return &run, nil
+}
+
+// WatchRun returns a channel carrying the run's current progress followed by
+// every update published while it executes. The channel is closed after the
+// terminal update, or when ctx is done. A run executing in another process
+// publishes nothing here, so its updates, terminal one included, are read
+// from the repository every watchPollInterval instead.
+func (s *simulationSvc) WatchRun(ctx context.Context, runID string) (<-chan domain.RunProgress, error) {
+	// Subscribe before reading the run so no update between the two is lost.
+	ch, stop := s.progress.subscribe(runID)
+	run, err := s.simulationRepo.GetRun(ctx, runID)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	s.progress.offer(runID, ch, run.Progress())
+	if !run.Status.Active() {
+		stop()
+		return ch, nil
+	}
+	go s.pollRun(ctx, runID, ch, stop)
+	return ch, nil
+}
+
+// pollRun feeds ch the run's progress as the repository records it until
+// the run finishes or ctx is done. Progress of a run executing here comes
+// from the hub, which is more current than the repository; only its
+// terminal update may arrive here first.
+func (s *simulationSvc) pollRun(ctx context.Context, runID string, ch chan domain.RunProgress, stop func()) {
+	defer stop()
+	tick := time.NewTicker(watchPollInterval)
+	defer tick.Stop()
+	var last domain.RunProgress
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+		run, err := s.simulationRepo.GetRun(ctx, runID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		p := run.Progress()
+		if !run.Status.Active() {
+			s.progress.end(runID, ch, p)
+			return
+		}
+		if _, here := s.cancels.Load(runID); !here && (p.Status != last.Status || p.Done != last.Done) {
+			s.progress.offer(runID, ch, p)
+			last = p
+		}
+	}
+}
+
+// CancelRun stops a queued or running run. A queued run is cancelled in
+// place. A running one is flagged in the repository, so that whichever
+// process executes it stops it at its next progress update; one executing
+// here has its context cancelled at once. Either records its own outcome
+// once the workers notice, normally within one path.
+func (s *simulationSvc) CancelRun(ctx context.Context, runID string) error {
+	cancelled, err := s.simulationRepo.CancelQueuedRun(ctx, runID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("cancel run: %w", err)
+	}
+	if cancelled {
+		run, err := s.simulationRepo.GetRun(ctx, runID)
+		if err != nil {
+			return fmt.Errorf("get run: %w", err)
+		}
+		s.progress.publish(run.Progress())
+		return nil
+	}
+	requested, err := s.simulationRepo.RequestCancel(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("cancel run: %w", err)
+	}
+	if !requested {
+		run, err := s.simulationRepo.GetRun(ctx, runID)
+		if err != nil {
+			return fmt.Errorf("get run: %w", err)
+		}
+		return fmt.Errorf("run %s is %s: %w", runID, run.Status, domain.ErrRunNotActive)
+	}
+	if v, ok := s.cancels.Load(runID); ok {
+		v.(context.CancelCauseFunc)(errRunCancelled)
+	}
+	return nil
+}
+
+// execute simulates a claimed run and records its terminal state. A failed or
+// cancelled run is recorded as such; the returned error (errRunCancelled for a
+// cancelled run) is for logging only.
+func (s *simulationSvc) execute(ctx context.Context, run domain.Run) error {
+	// Results are written under a context that survives cancellation so the
+	// outcome of a cancelled or interrupted run is always recorded.
+	store := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	s.cancels.Store(run.ID, cancel)
+	defer s.cancels.Delete(run.ID)
+	// report records progress and picks up a cancel requested of the run
+	// before it registered above, or by another process. Progress is
+	// advisory; a failed write only makes polling readers lag.
+	report := func(done int) {
+		if requested, _ := s.simulationRepo.UpdateRunProgress(store, run.ID, done); requested {
+			cancel(errRunCancelled)
+		}
+	}
+	report(run.ProgressDone)
+
+	s.progress.publish(run.Progress())
+	exp, err := s.experimentRepo.GetExperiment(ctx, run.ExperimentID)
+	if err != nil {
+		return s.finishWithError(store, ctx, run, nil, nil, fmt.Errorf("get experiment: %w", err))
+	}
+	simCtx := withProgress(ctx, func(done int) {
+		run.ProgressDone = done
+		s.progress.publish(run.Progress())
+		report(done)
+	})
+	paths, err := s.simulate(simCtx, exp, &run)
+	if err != nil {
+		return s.finishWithError(store, ctx, run, exp, paths, err)
+	}
+	if err := s.recordResults(store, &run, exp, paths); err != nil {
+		return s.fail(store, run, err)
 	}
-	run.Stats = domain.ComputeStats(paths, exp.Config.StartValue, float64(exp.Config.HorizonDays)/252.0)
 	run.Status = domain.StatusComplete
+	return s.finish(store, run)
+}
+
+// finishWithError records a run that stopped early: cancelled when CancelRun
+// asked for it, otherwise failed (as interrupted if the process stopped it).
+func (s *simulationSvc) finishWithError(store, ctx context.Context, run domain.Run, exp *domain.Experiment, paths []domain.SimulatedPath, err error) error {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errRunCancelled):
+		run.Status = domain.StatusCancelled
+		run.ProgressDone = len(paths)
+		if exp != nil && exp.Config.KeepPartial && len(paths) > 0 {
+			if err := s.recordResults(store, &run, exp, paths); err != nil {
+				return s.fail(store, run, err)
+			}
+		}
+		if err := s.finish(store, run); err != nil {
+			return err
+		}
+		return errRunCancelled
+	case errors.Is(cause, errInterrupted):
+		err = cause
+	}
+	return s.fail(store, run, err)
+}
+
+// recordResults computes the run's statistics and bands from paths and, when
+// configured, persists the paths themselves.
+func (s *simulationSvc) recordResults(ctx context.Context, run *domain.Run, exp *domain.Experiment, paths []domain.SimulatedPath) error {
+	run.Stats = domain.ComputeStats(paths, exp.Config.StartValue, float64(exp.Config.HorizonDays)/252.0)
+	bands := domain.ComputeBands(paths, exp.Config.BandQuantiles(), exp.Config.HorizonDays)
+	run.Bands = &bands
+	run.ProgressDone = len(paths)
+	if exp.Config.StorePaths {
+		set := domain.NewPathSet(run.ID, paths, exp.Config.HorizonDays, exp.Config.PathEvery, exp.Config.PathPrecision)
+		if err := s.pathRepo.SavePaths(ctx, set); err != nil {
+			return fmt.Errorf("save paths: %w", err)
+		}
+	}
+	return nil
+}
+
+// finish saves run in its terminal state and tells watchers.
+func (s *simulationSvc) finish(ctx context.Context, run domain.Run) error {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
 	if err := s.simulationRepo.SaveRun(ctx, run); err != nil {
-		return nil, err
+		return err
 	}
-	return &run, nil
+	s.progress.publish(run.Progress())
+	return nil
+}
+
+// fail records run as failed with err and returns err. Like finish, it saves
+// the run before publishing, so a watcher told the run failed finds it so.
+func (s *simulationSvc) fail(ctx context.Context, run domain.Run, err error) error {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = domain.StatusFailed
+	run.Error = err.Error()
+	if saveErr := s.simulationRepo.SaveRun(ctx, run); saveErr != nil {
+		return errors.Join(err, fmt.Errorf("save run: %w", saveErr))
+	}
+	s.progress.publish(run.Progress())
+	return err
 }
 
 func (s *simulationSvc) GetRun(ctx context.Context, runID string) (*domain.Run, error) {
 	return s.simulationRepo.GetRun(ctx, runID)
 }
 
-func (s *simulationSvc) GetRunPaths(_ context.Context, _ string) ([]domain.SimulatedPath, error) {
-	return nil, nil
+func (s *simulationSvc) GetRunPaths(ctx context.Context, runID string, offset, limit int) (*domain.PathSet, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", domain.ErrInvalid)
+	}
+	return s.pathRepo.GetPaths(ctx, runID, offset, limit)
 }
 
-func (s *simulationSvc) simulate(ctx context.Context, exp *domain.Experiment) ([]domain.SimulatedPath, error) {
-	switch exp.Config.Model {
-	case domain.ModelGBM:
-		return s.runGBM(ctx, exp)
-	case domain.ModelBootstrap, domain.ModelBlockBootstrap:
-		return s.runBootstrap(ctx, exp)
-	default:
+func (s *simulationSvc) DeleteRun(ctx context.Context, runID string) error {
+	run, err := s.simulationRepo.GetRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if run.Status.Active() {
+		return fmt.Errorf("delete run %s: %w", runID, domain.ErrRunActive)
+	}
+	return s.simulationRepo.DeleteRun(ctx, runID)
+}
+
+// simulate generates the experiment's paths with its configured model,
+// recording on run the moments the model was calibrated to and what it
+// estimated from the lookback window (nil for models that estimate nothing
+// worth recording).
+func (s *simulationSvc) simulate(ctx context.Context, exp *domain.Experiment, run *domain.Run) ([]domain.SimulatedPath, error) {
+	m, ok := s.models.Get(exp.Config.Model)
+	if !ok {
 		return nil, fmt.Errorf("unknown model: %s", exp.Config.Model)
 	}
+	rows, err := s.lookbackReturns(ctx, exp)
+	if err != nil {
+		return nil, err
+	}
+	rows, run.Inputs, err = marketInputs(rows, exp, m.Spec())
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.Calibrate(rows, exp)
+	if err != nil {
+		return nil, err
+	}
+	run.Fit = c.Fit()
+	return s.workerPool(ctx, exp.Config, c.Path)
 }
 
-type assetGBMParams struct{ mu, sigma float64 }
+// gbmParams holds the jointly estimated GBM parameters for every portfolio asset.
+type gbmParams struct {
+	mu   []float64   // annualised drift per asset
+	cov  [][]float64 // annualised covariance matrix of daily log-returns
+	chol [][]float64 // lower Cholesky factor of cov·dt; correlates the daily shocks
+}
+
+// gbmModel is Geometric Brownian Motion with the drift and covariance of
+// the returns it is calibrated to.
+type gbmModel struct{}
+
+func (gbmModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelGBM,
+		Label:            "GBM (Geometric Brownian Motion)",
+		Description:      "Correlated log-normal returns with the historical or assumed drift and covariance.",
+		TakesAssumptions: true,
+	}
+}
+
+func (gbmModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	params := estimateGBMParams(rows)
+	if err := params.factor(exp.Config.RepairCovariance); err != nil {
+		return nil, fmt.Errorf("covariance of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	return pathFunc(func(rng *rand.Rand) domain.SimulatedPath {
+		return gbmPath(exp.Config, params, alloc, rng)
+	}), nil
+}
+
+// lookbackReturns loads the lookback window of every portfolio asset and
+// returns the date-aligned daily log-return matrix (rows[day][asset]). It
+// fails if the assets' trading calendars disagree inside their common range.
+func (s *simulationSvc) lookbackReturns(ctx context.Context, exp *domain.Experiment) ([][]float64, error) {
+	series, err := s.loadLookback(ctx, exp)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]string, len(exp.Portfolio.Assets))
+	for i, pa := range exp.Portfolio.Assets {
+		symbols[i] = pa.Symbol
+	}
+	if err := checkCalendars(symbols, series); err != nil {
+		return nil, err
+	}
+	_, rows := alignReturns(series)
+	if len(rows) < 1 {
+		return nil, fmt.Errorf("need >=2 date-aligned records across %s", portfolioSymbols(exp.Portfolio))
+	}
+	return rows, nil
+}
 
-func (s *simulationSvc) runGBM(ctx context.Context, exp *domain.Experiment) ([]domain.SimulatedPath, error) {
-	params := make([]assetGBMParams, len(exp.Portfolio.Assets))
-	weights := make([]float64, len(exp.Portfolio.Assets))
+// loadLookback fetches the most recent LookbackDays+1 price records on or
+// before Config.AsOf for every portfolio asset, in portfolio order.
+func (s *simulationSvc) loadLookback(ctx context.Context, exp *domain.Experiment) ([][]domain.PriceRecord, error) {
+	var asOf time.Time
+	if exp.Config.AsOf != nil {
+		asOf = *exp.Config.AsOf
+	}
+	series := make([][]domain.PriceRecord, len(exp.Portfolio.Assets))
 	for i, pa := range exp.Portfolio.Assets {
-		recs, err := s.assetRepo.GetPriceRecords(ctx, pa.Symbol, exp.Config.LookbackDays+1)
+		recs, err := s.assetRepo.GetRecentPriceRecords(ctx, pa.Symbol, exp.Config.LookbackDays+1, asOf)
 		if err != nil {
 			return nil, fmt.Errorf("prices %s: %w", pa.Symbol, err)
 		}
 		if len(recs) < 2 {
 			return nil, fmt.Errorf("need >=2 records for %s", pa.Symbol)
 		}
-		mu, sig := estimateGBMParams(recs)
-		params[i] = assetGBMParams{mu: mu, sigma: sig}
-		weights[i] = pa.Weight
+		series[i] = recs
 	}
-	return s.workerPool(exp.Config, func(rng *rand.Rand) domain.SimulatedPath {
-		return gbmPath(exp.Config, params, weights, rng)
-	}), nil
+	return series, nil
 }
 
-func estimateGBMParams(recs []domain.PriceRecord) (mu, sigma float64) {
-	var lr []float64
-	for i := 1; i < len(recs); i++ {
-		p, c := recs[i-1].AdjustedClose, recs[i].AdjustedClose
-		if p > 0 && c > 0 {
-			lr = append(lr, math.Log(c/p))
+// estimateGBMParams derives annualised drift and covariance from a matrix of
+// date-aligned daily log-returns laid out as rows[day][asset]. The rows a
+// model calibrates on already carry the moments of the run's estimators or
+// assumptions (see marketInputs), so their sample moments are those.
+func estimateGBMParams(rows [][]float64) gbmParams {
+	mean, cov := sampleMoments(rows)
+	mu := make([]float64, len(mean))
+	for i := range cov {
+		for j := range cov[i] {
+			cov[i][j] *= 252
 		}
+		mu[i] = mean[i]*252 + 0.5*cov[i][i]
 	}
-	if len(lr) == 0 {
-		return 0, 0
-	}
-	n := float64(len(lr))
-	var sum float64
-	for _, r := range lr {
-		sum += r
+	return gbmParams{mu: mu, cov: cov}
+}
+
+// factor computes the Cholesky factor of the daily covariance. When the
+// covariance is not positive-definite it returns an error, unless repair is
+// set, in which case the matrix is first projected onto the nearest PSD matrix.
+func (p *gbmParams) factor(repair bool) error {
+	daily := make([][]float64, len(p.cov))
+	for i := range p.cov {
+		daily[i] = make([]float64, len(p.cov[i]))
+		for j := range p.cov[i] {
+			daily[i][j] = p.cov[i][j] / 252
+		}
 	}
-	mean := sum / n
-	var vsum float64
-	for _, r := range lr {
-		d := r - mean
-		vsum += d * d
+	chol, err := factorPSD(daily, repair)
+	if err != nil {
+		return err
 	}
-	dsig := math.Sqrt(vsum / n)
-	return mean*252 + 0.5*dsig*dsig*252, dsig * math.Sqrt(252)
+	p.chol = chol
+	return nil
 }
 
-func gbmPath(cfg domain.SimulationConfig, params []assetGBMParams, weights []float64, rng *rand.Rand) domain.SimulatedPath {
-	dt := 1.0 / 252.0
-	vals := make([]float64, cfg.HorizonDays+1)
-	vals[0] = cfg.StartValue
-	growth := make([]float64, len(params))
-	for i := range growth {
-		growth[i] = 1.0
+// factorPSD returns the lower Cholesky factor of the symmetric matrix a. When
+// a is not positive-definite it returns an error, unless repair is set, in
+// which case a is first projected onto the nearest PSD matrix.
+func factorPSD(a [][]float64, repair bool) ([][]float64, error) {
+	chol, err := cholesky(a)
+	if err != nil && repair {
+		chol, err = cholesky(nearestPSD(a))
 	}
-	for day := 1; day <= cfg.HorizonDays; day++ {
-		for i, p := range params {
-			growth[i] *= math.Exp((p.mu-0.5*p.sigma*p.sigma)*dt + p.sigma*math.Sqrt(dt)*rng.NormFloat64())
-		}
-		var total float64
-		for i, w := range weights {
-			total += w * growth[i]
-		}
-		vals[day] = cfg.StartValue * total
-		if cfg.AnnualContribution != 0 && day%252 == 0 {
-			vals[day] += cfg.AnnualContribution
-		}
+	if err != nil {
+		return nil, fmt.Errorf("%w (enable repair_covariance to use the nearest PSD matrix)", err)
 	}
-	return domain.SimulatedPath{Values: vals}
+	return chol, nil
 }
 
-func (s *simulationSvc) runBootstrap(ctx context.Context, exp *domain.Experiment) ([]domain.SimulatedPath, error) {
-	rs := make([][]float64, len(exp.Portfolio.Assets))
-	wts := make([]float64, len(exp.Portfolio.Assets))
-	for i, pa := range exp.Portfolio.Assets {
-		recs, err := s.assetRepo.GetPriceRecords(ctx, pa.Symbol, exp.Config.LookbackDays+1)
-		if err != nil {
-			return nil, err
+func gbmPath(cfg domain.SimulationConfig, p gbmParams, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	dt := 1.0 / 252.0
+	drift := make([]float64, len(p.mu))
+	for i := range drift {
+		drift[i] = (p.mu[i] - 0.5*p.cov[i][i]) * dt
+	}
+	z := make([]float64, len(p.mu))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
 		}
-		var rtns []float64
-		for j := 1; j < len(recs); j++ {
-			p, c := recs[j-1].AdjustedClose, recs[j].AdjustedClose
-			if p > 0 && c > 0 {
-				rtns = append(rtns, math.Log(c/p))
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += p.chol[i][j] * z[j]
 			}
+			lr[i] = drift[i] + shock
 		}
-		rs[i], wts[i] = rtns, pa.Weight
+	})
+}
+
+// bootstrapModel resamples whole historical days with replacement.
+type bootstrapModel struct{}
+
+func (bootstrapModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:        domain.ModelBootstrap,
+		Label:       "Bootstrap (Resample Returns)",
+		Description: "Resamples historical days, all assets together, with replacement.",
 	}
-	return s.workerPool(exp.Config, func(rng *rand.Rand) domain.SimulatedPath {
-		return bsPath(exp.Config, rs, wts, rng)
+}
+
+func (bootstrapModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	alloc := portfolioAllocation(exp.Portfolio)
+	return pathFunc(func(rng *rand.Rand) domain.SimulatedPath {
+		return bsPath(exp.Config, rows, alloc, rng)
 	}), nil
 }
 
-func bsPath(cfg domain.SimulationConfig, rs [][]float64, wts []float64, rng *rand.Rand) domain.SimulatedPath {
-	vals := make([]float64, cfg.HorizonDays+1)
-	vals[0] = cfg.StartValue
-	for day := 1; day <= cfg.HorizonDays; day++ {
-		var lr float64
-		for i, s := range rs {
-			if len(s) > 0 {
-				lr += wts[i] * s[rng.IntN(len(s))]
-			}
-		}
-		vals[day] = vals[day-1] * math.Exp(lr)
-		if cfg.AnnualContribution != 0 && day%252 == 0 {
-			vals[day] += cfg.AnnualContribution
+// bsPath resamples whole days from the aligned return matrix so that every
+// asset's return on a simulated day comes from the same historical day.
+func bsPath(cfg domain.SimulationConfig, rows [][]float64, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	return resamplePath(cfg, rows, alloc, func() int { return rng.IntN(len(rows)) })
+}
+
+// resamplePath builds a path by replaying the historical rows chosen by next.
+func resamplePath(cfg domain.SimulationConfig, rows [][]float64, alloc allocation, next func() int) domain.SimulatedPath {
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		if len(rows) > 0 {
+			copy(lr, rows[next()])
 		}
-	}
-	return domain.SimulatedPath{Values: vals}
+	})
 }
 
-func (s *simulationSvc) workerPool(cfg domain.SimulationConfig, gen func(*rand.Rand) domain.SimulatedPath) []domain.SimulatedPath {
+// workerPool generates cfg.NumPaths paths with gen across all CPUs, reporting
+// the running count to the progress callback carried by ctx. Workers check ctx
+// between paths; if it is cancelled the paths finished so far are returned
+// along with ctx's error.
+func (s *simulationSvc) workerPool(ctx context.Context, cfg domain.SimulationConfig, gen func(*rand.Rand) domain.SimulatedPath) ([]domain.SimulatedPath, error) {
+	report := progressFrom(ctx)
 	nw := runtime.NumCPU()
 	ch := make(chan domain.SimulatedPath, cfg.NumPaths)
 	var base uint64
@@ -211,17 +534,23 @@ func (s *simulationSvc) workerPool(cfg domain.SimulationConfig, gen func(*rand.R
 		go func(n int, seed uint64) {
 			defer wg.Done()
 			rng := rand.New(rand.NewChaCha8(seedKey(seed)))
-			for i := 0; i < n; i++ {
+			for i := 0; i < n && ctx.Err() == nil; i++ {
 				ch <- gen(rng)
 			}
 		}(cnt, seed)
 	}
 	go func() { wg.Wait(); close(ch) }()
 	paths := make([]domain.SimulatedPath, 0, cfg.NumPaths)
+	last := time.Now()
 	for p := range ch {
 		paths = append(paths, p)
+		if now := time.Now(); now.Sub(last) >= progressInterval {
+			report(len(paths))
+			last = now
+		}
 	}
-	return paths
+	report(len(paths))
+	return paths, ctx.Err()
 }
 
 func seedKey(seed uint64) [32]byte {
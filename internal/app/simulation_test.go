@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"math"
 	"math/rand/v2"
 	"testing"
@@ -9,38 +10,66 @@ import (
 )
 
 func TestEstimateGBMParamsFlat(t *testing.T) {
-	recs := make([]domain.PriceRecord, 50)
-	for i := range recs {
-		recs[i] = domain.PriceRecord{AdjustedClose: 100.0}
+	rows := make([][]float64, 50)
+	for i := range rows {
+		rows[i] = []float64{0}
 	}
-	mu, sigma := estimateGBMParams(recs)
-	if math.Abs(mu) > 1e-9 || math.Abs(sigma) > 1e-9 {
-		t.Errorf("flat prices: mu=%v sigma=%v, both want ~0", mu, sigma)
+	p := estimateGBMParams(rows)
+	if math.Abs(p.mu[0]) > 1e-9 || math.Abs(p.cov[0][0]) > 1e-9 {
+		t.Errorf("flat prices: mu=%v var=%v, both want ~0", p.mu[0], p.cov[0][0])
 	}
 }
 
-func TestEstimateGBMParamsTwoRecords(t *testing.T) {
-	recs := []domain.PriceRecord{
-		{AdjustedClose: 100},
-		{AdjustedClose: 110},
+func TestEstimateGBMParamsSingleReturn(t *testing.T) {
+	rows := [][]float64{{math.Log(110.0 / 100.0)}}
+	p := estimateGBMParams(rows)
+	if p.cov[0][0] < 0 {
+		t.Errorf("variance = %v, must be non-negative", p.cov[0][0])
 	}
-	_, sigma := estimateGBMParams(recs)
-	if sigma < 0 {
-		t.Errorf("sigma = %v, must be non-negative", sigma)
+}
+
+func TestEstimateGBMParamsCorrelation(t *testing.T) {
+	// Asset 1 is exactly twice asset 0, so correlation is 1 and cov = 2·var.
+	rows := [][]float64{{0.01, 0.02}, {-0.01, -0.02}, {0.005, 0.01}, {-0.002, -0.004}}
+	p := estimateGBMParams(rows)
+	if math.Abs(p.cov[0][1]-2*p.cov[0][0]) > 1e-12 {
+		t.Errorf("cov[0][1] = %v, want %v", p.cov[0][1], 2*p.cov[0][0])
+	}
+	if math.Abs(p.cov[0][1]-p.cov[1][0]) > 0 {
+		t.Errorf("covariance not symmetric: %v vs %v", p.cov[0][1], p.cov[1][0])
 	}
 }
 
+func TestGBMFactorRejectsSingularCovariance(t *testing.T) {
+	p := gbmParams{mu: []float64{0.05, 0.05}, cov: [][]float64{{0.04, 0.04}, {0.04, 0.04}}}
+	if err := p.factor(false); !errors.Is(err, errNotPositiveDefinite) {
+		t.Fatalf("factor(false) err = %v, want errNotPositiveDefinite", err)
+	}
+	if err := p.factor(true); err != nil {
+		t.Fatalf("factor(true) with repair: %v", err)
+	}
+}
+
+func mustGBMParams(t *testing.T, mu []float64, cov [][]float64) gbmParams {
+	t.Helper()
+	p := gbmParams{mu: mu, cov: cov}
+	if err := p.factor(false); err != nil {
+		t.Fatalf("factor: %v", err)
+	}
+	return p
+}
+
 func TestGBMPathShape(t *testing.T) {
 	cfg := domain.SimulationConfig{
 		NumPaths:    10,
 		HorizonDays: 252,
 		StartValue:  100_000,
 	}
-	params := []assetGBMParams{{mu: 0.07, sigma: 0.15}}
-	weights := []float64{1.0}
+	params := mustGBMParams(t, []float64{0.07}, [][]float64{{0.15 * 0.15}})
+	alloc := allocation{weights: []float64{1.0}}
 	rng := rand.New(rand.NewChaCha8([32]byte{}))
 
-	path := gbmPath(cfg, params, weights, rng)
+	path := gbmPath(cfg, params, alloc, rng)
 	if len(path.Values) != cfg.HorizonDays+1 {
 		t.Errorf("path length = %d, want %d", len(path.Values), cfg.HorizonDays+1)
 	}
@@ -58,15 +87,15 @@ func TestGBMPathDeterministic(t *testing.T) {
 		HorizonDays: 100,
 		StartValue:  50_000,
 	}
-	params := []assetGBMParams{{mu: 0.08, sigma: 0.20}}
-	weights := []float64{1.0}
+	params := mustGBMParams(t, []float64{0.08}, [][]float64{{0.20 * 0.20}})
+	alloc := allocation{weights: []float64{1.0}}
 
 	key := [32]byte{1, 2, 3}
 	rng1 := rand.New(rand.NewChaCha8(key))
 	rng2 := rand.New(rand.NewChaCha8(key))
 
-	p1 := gbmPath(cfg, params, weights, rng1)
-	p2 := gbmPath(cfg, params, weights, rng2)
+	p1 := gbmPath(cfg, params, alloc, rng1)
+	p2 := gbmPath(cfg, params, alloc, rng2)
 
 	for i := range p1.Values {
 		if p1.Values[i] != p2.Values[i] {
@@ -75,16 +104,36 @@ func TestGBMPathDeterministic(t *testing.T) {
 	}
 }
 
+func TestGBMPathCorrelatedAssetsMoveTogether(t *testing.T) {
+	cfg := domain.SimulationConfig{HorizonDays: 1, StartValue: 1}
+	cov := [][]float64{{0.04, 0.0396}, {0.0396, 0.04}} // rho = 0.99
+	params := mustGBMParams(t, []float64{0, 0}, cov)
+	rng := rand.New(rand.NewChaCha8([32]byte{9}))
+
+	// With one day and weights (1, -1) the path records the spread between
+	// the two assets' growth factors, which must be tiny when rho ~ 1.
+	var sumSq float64
+	for range 2000 {
+		p := gbmPath(cfg, params, allocation{weights: []float64{1, -1}}, rng)
+		sumSq += p.Final() * p.Final()
+	}
+	spreadVol := math.Sqrt(sumSq/2000) * math.Sqrt(252)
+	want := math.Sqrt(2 * (0.04 - 0.0396))
+	if math.Abs(spreadVol-want) > 0.3*want {
+		t.Errorf("annualised spread vol = %v, want ~%v", spreadVol, want)
+	}
+}
+
 func TestBsPathShape(t *testing.T) {
 	cfg := domain.SimulationConfig{
 		HorizonDays: 100,
 		StartValue:  10_000,
 	}
-	returns := [][]float64{{0.001, -0.001, 0.002, -0.002}}
-	weights := []float64{1.0}
+	returns := [][]float64{{0.001}, {-0.001}, {0.002}, {-0.002}}
+	alloc := allocation{weights: []float64{1.0}}
 	rng := rand.New(rand.NewChaCha8([32]byte{}))
 
-	path := bsPath(cfg, returns, weights, rng)
+	path := bsPath(cfg, returns, alloc, rng)
 	if len(path.Values) != cfg.HorizonDays+1 {
 		t.Errorf("path length = %d, want %d", len(path.Values), cfg.HorizonDays+1)
 	}
@@ -100,3 +149,28 @@ func TestSeedKey(t *testing.T) {
 		t.Error("different seeds must produce different keys")
 	}
 }
+
+func TestBsPathSamplesWholeDays(t *testing.T) {
+	cfg := domain.SimulationConfig{HorizonDays: 500, StartValue: 1}
+	// Both assets share the same return on every historical day, so each
+	// simulated day's portfolio log-change must equal one of those returns
+	// exactly; mixing days across assets would produce a blend instead.
+	rows := [][]float64{{0.02, 0.02}, {-0.05, -0.05}, {0.01, 0.01}}
+	alloc := allocation{weights: []float64{0.5, 0.5}}
+	rng := rand.New(rand.NewChaCha8([32]byte{7}))
+
+	path := bsPath(cfg, rows, alloc, rng)
+
+	for d := 1; d < len(path.Values); d++ {
+		lr := math.Log(path.Values[d] / path.Values[d-1])
+		matched := false
+		for _, r := range rows {
+			if math.Abs(lr-r[0]) < 1e-12 {
+				matched = true
+			}
+		}
+		if !matched {
+			t.Fatalf("day %d log-change %v is not a historical row (rows were split)", d, lr)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Bounds of the fitted degrees of freedom. Below 2 the variance is infinite;
+// by 200 a t is indistinguishable from a normal over any realistic lookback.
+const (
+	minTDegrees = 2.1
+	maxTDegrees = 200
+)
+
+// tParams holds the fitted Student-t model: GBM's moments, so the simulated
+// returns keep the historical mean and covariance, plus the tail shape.
+type tParams struct {
+	gbmParams
+	nu     float64          // degrees of freedom shared by every asset
+	logLik float64          // log-likelihood of the symmetric fit at nu
+	sd     []float64        // daily standard deviation per asset
+	skew   []twoPieceShapes // per-asset skew; nil for the symmetric model
+}
+
+// twoPieceShapes is one asset's fitted two-piece skew: positive unit-variance
+// t shocks are stretched by gamma and negative ones by 1/gamma, then shifted
+// by mean and divided by sd so the result has zero mean and unit variance.
+type twoPieceShapes struct {
+	gamma, mean, sd float64
+}
+
+// studentTModel is GBM's moments with fitted multivariate-t shocks.
+type studentTModel struct{}
+
+func (studentTModel) Spec() domain.ModelSpec {
+	return domain.ModelSpec{
+		Name:             domain.ModelStudentT,
+		Label:            "Student-t (fat tails)",
+		Description:      "GBM's mean and covariance with fat-tailed shocks whose degrees of freedom are fitted by maximum likelihood.",
+		Params:           domain.StudentTParams{}.Spec(),
+		TakesAssumptions: true,
+	}
+}
+
+func (studentTModel) Calibrate(rows [][]float64, exp *domain.Experiment) (Calibration, error) {
+	p, err := estimateTParams(rows, exp.Config.StudentT.Skewed, exp.Config.RepairCovariance)
+	if err != nil {
+		return nil, fmt.Errorf("covariance of %s: %w", portfolioSymbols(exp.Portfolio), err)
+	}
+	alloc := portfolioAllocation(exp.Portfolio)
+	return fitted{
+		pathFunc: func(rng *rand.Rand) domain.SimulatedPath { return tPath(exp.Config, p, alloc, rng) },
+		fit:      p.modelFit(exp.Portfolio),
+	}, nil
+}
+
+// modelFit records the fitted degrees of freedom and, for the skewed model,
+// each asset's skew.
+func (p tParams) modelFit(pf domain.Portfolio) *domain.ModelFit {
+	fit := &domain.ModelFit{
+		Model:         domain.ModelStudentT,
+		LogLikelihood: p.logLik,
+		Params:        map[string]float64{"nu": p.nu},
+	}
+	for i, s := range p.skew {
+		fit.Assets = append(fit.Assets, domain.AssetFit{
+			Symbol: pf.Assets[i].Symbol,
+			Params: map[string]float64{"gamma": s.gamma},
+		})
+	}
+	return fit
+}
+
+// estimateTParams fits the Student-t model to rows[day][asset]: the sample
+// mean and covariance as for GBM, then the degrees of freedom by maximum
+// likelihood, and with skewed set each asset's two-piece skew.
+func estimateTParams(rows [][]float64, skewed, repair bool) (tParams, error) {
+	p := tParams{gbmParams: estimateGBMParams(rows)}
+	if err := p.factor(repair); err != nil {
+		return tParams{}, err
+	}
+	mean, _ := sampleMoments(rows)
+	p.sd = make([]float64, len(p.cov))
+	for i := range p.sd {
+		p.sd[i] = math.Sqrt(p.cov[i][i] / 252)
+	}
+	p.nu, p.logLik = fitTDegrees(rows, mean, p.chol)
+	if skewed {
+		p.skew = make([]twoPieceShapes, len(p.sd))
+		resid := make([]float64, len(rows))
+		for i := range p.skew {
+			for t, r := range rows {
+				resid[t] = 0
+				if p.sd[i] > 0 {
+					resid[t] = (r[i] - mean[i]) / p.sd[i]
+				}
+			}
+			p.skew[i] = fitTwoPieceSkew(resid, p.nu)
+		}
+	}
+	return p, nil
+}
+
+// fitTDegrees returns the maximum-likelihood degrees of freedom of a
+// multivariate t with the rows' sample mean and covariance, and the
+// log-likelihood there. chol is the lower Cholesky factor of the daily
+// covariance. Holding the first two moments at their sample values leaves a
+// one-dimensional problem in ν, which depends on the data only through each
+// day's squared Mahalanobis distance. A zero-variance asset adds nothing to
+// either, nor to the dimension.
+func fitTDegrees(rows [][]float64, mean []float64, chol [][]float64) (nu, logLik float64) {
+	ld, dim := logDet(chol)
+	d := float64(dim)
+	q := make([]float64, len(rows))
+	dev, y := make([]float64, len(mean)), make([]float64, len(mean))
+	for t, r := range rows {
+		for i := range dev {
+			dev[i] = r[i] - mean[i]
+		}
+		q[t] = mahalanobis(chol, dev, y)
+	}
+	n := float64(len(rows))
+	profile := func(x float64) float64 {
+		nu := 2 + math.Exp(x)
+		a, _ := math.Lgamma((nu + d) / 2)
+		b, _ := math.Lgamma(nu / 2)
+		ll := n * (a - b - d/2*math.Log(nu-2))
+		for _, qt := range q {
+			ll -= (nu + d) / 2 * math.Log1p(qt/(nu-2))
+		}
+		return ll
+	}
+	x := goldenMax(profile, math.Log(minTDegrees-2), math.Log(maxTDegrees-2), 1e-4)
+	// Add back the terms that do not depend on ν: −n·d/2·ln π − n/2·ln|Σ|.
+	logLik = profile(x) - n*d/2*math.Log(math.Pi) - n*ld
+	return 2 + math.Exp(x), logLik
+}
+
+// fitTwoPieceSkew returns the maximum-likelihood two-piece skew of
+// standardised residuals with nu degrees of freedom.
+func fitTwoPieceSkew(resid []float64, nu float64) twoPieceShapes {
+	logLik := func(x float64) float64 {
+		s := newTwoPieceShapes(math.Exp(x), nu)
+		var ll float64
+		for _, e := range resid {
+			ll += s.logDensity(e, nu)
+		}
+		return ll
+	}
+	return newTwoPieceShapes(math.Exp(goldenMax(logLik, math.Log(1.0/3), math.Log(3), 1e-4)), nu)
+}
+
+// newTwoPieceShapes computes the standardising mean and sd for gamma: with
+// U a unit-variance t, E[X] = (γ − 1/γ)·E|U|/2 and E[X²] = (γ² + 1/γ²)/2.
+func newTwoPieceShapes(gamma, nu float64) twoPieceShapes {
+	a, _ := math.Lgamma((nu + 1) / 2)
+	b, _ := math.Lgamma(nu / 2)
+	absMean := 2 * math.Sqrt(nu-2) / (math.Sqrt(math.Pi) * (nu - 1)) * math.Exp(a-b)
+	m := (gamma - 1/gamma) * absMean / 2
+	return twoPieceShapes{gamma: gamma, mean: m, sd: math.Sqrt((gamma*gamma+1/(gamma*gamma))/2 - m*m)}
+}
+
+// apply maps a symmetric unit-variance shock onto the skewed one. The map is
+// increasing, so shocks drawn together across assets stay together.
+func (s twoPieceShapes) apply(u float64) float64 {
+	if u >= 0 {
+		u *= s.gamma
+	} else {
+		u /= s.gamma
+	}
+	return (u - s.mean) / s.sd
+}
+
+// logDensity is the log density at z of apply(U) for U a unit-variance t.
+func (s twoPieceShapes) logDensity(z, nu float64) float64 {
+	x := s.mean + s.sd*z
+	var u, scale float64
+	if x >= 0 {
+		u, scale = x/s.gamma, 1/s.gamma
+	} else {
+		u, scale = x*s.gamma, s.gamma
+	}
+	return math.Log(s.sd*scale) + unitTLogDensity(u, nu)
+}
+
+// unitTLogDensity is the log density of a Student-t with nu degrees of
+// freedom scaled to unit variance.
+func unitTLogDensity(u, nu float64) float64 {
+	a, _ := math.Lgamma((nu + 1) / 2)
+	b, _ := math.Lgamma(nu / 2)
+	return a - b - 0.5*math.Log(math.Pi*(nu-2)) - (nu+1)/2*math.Log1p(u*u/(nu-2))
+}
+
+// tPath is gbmPath with multivariate-t shocks: each day's correlated normal
+// vector is divided by one shared √(W/(ν−2)), W ~ χ²(ν), so the covariance is
+// unchanged but every asset takes its extreme days together.
+func tPath(cfg domain.SimulationConfig, p tParams, alloc allocation, rng *rand.Rand) domain.SimulatedPath {
+	dt := 1.0 / 252.0
+	drift := make([]float64, len(p.mu))
+	for i := range drift {
+		drift[i] = (p.mu[i] - 0.5*p.cov[i][i]) * dt
+	}
+	z := make([]float64, len(p.mu))
+	return simulatePath(cfg, alloc, func(lr []float64) {
+		for j := range z {
+			z[j] = rng.NormFloat64()
+		}
+		scale := math.Sqrt((p.nu - 2) / chiSquare(rng, p.nu))
+		for i := range lr {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += p.chol[i][j] * z[j]
+			}
+			shock *= scale
+			if p.skew != nil && p.sd[i] > 0 {
+				shock = p.skew[i].apply(shock/p.sd[i]) * p.sd[i]
+			}
+			lr[i] = drift[i] + shock
+		}
+	})
+}
+
+// chiSquare draws from the χ² distribution with nu degrees of freedom.
+func chiSquare(rng *rand.Rand, nu float64) float64 {
+	return 2 * gammaVariate(rng, nu/2)
+}
+
+// gammaVariate draws from Gamma(shape, 1) by Marsaglia and Tsang's method.
+func gammaVariate(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// Boost: Gamma(a) = Gamma(a+1)·U^(1/a).
+		return gammaVariate(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
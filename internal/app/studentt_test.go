@@ -0,0 +1,160 @@
+package app
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// tRows draws n days of two correlated unit-variance t returns, scaled by
+// 0.01, with nu degrees of freedom; twoPiece, if set, skews every draw.
+func tRows(rng *rand.Rand, n int, nu float64, twoPiece *twoPieceShapes) [][]float64 {
+	rows := make([][]float64, n)
+	for t := range rows {
+		z0, z1 := rng.NormFloat64(), rng.NormFloat64()
+		scale := math.Sqrt((nu - 2) / chiSquare(rng, nu))
+		u := []float64{z0 * scale, (0.6*z0 + 0.8*z1) * scale}
+		for i := range u {
+			if twoPiece != nil {
+				u[i] = twoPiece.apply(u[i])
+			}
+			u[i] *= 0.01
+		}
+		rows[t] = u
+	}
+	return rows
+}
+
+func TestFitTDegreesRecoversNu(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{4}))
+	for _, tc := range []struct {
+		nu     float64
+		lo, hi float64
+	}{
+		{nu: 4, lo: 3.4, hi: 4.8},
+		{nu: 10, lo: 7.5, hi: 14},
+	} {
+		p, err := estimateTParams(tRows(rng, 20_000, tc.nu, nil), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.nu < tc.lo || p.nu > tc.hi {
+			t.Errorf("nu = %.2f fitted to t(%v) data, want in [%v, %v]", p.nu, tc.nu, tc.lo, tc.hi)
+		}
+	}
+
+	normal := make([][]float64, 20_000)
+	for i := range normal {
+		normal[i] = []float64{0.01 * rng.NormFloat64()}
+	}
+	p, err := estimateTParams(normal, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.nu < 30 {
+		t.Errorf("nu = %.2f fitted to normal data, want large", p.nu)
+	}
+}
+
+func TestFitTDegreesIgnoresConstantAsset(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{5}))
+	rows := tRows(rng, 5000, 5, nil)
+	withCash := make([][]float64, len(rows))
+	for i, r := range rows {
+		withCash[i] = []float64{r[0], 0, r[1]}
+	}
+	want, err := estimateTParams(rows, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := estimateTParams(withCash, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.nu-want.nu) > 1e-9 || math.Abs(got.logLik-want.logLik) > 1e-6 {
+		t.Errorf("with a constant asset nu = %v, log-likelihood %v; want %v, %v as without it", got.nu, got.logLik, want.nu, want.logLik)
+	}
+}
+
+func TestFitTwoPieceSkewRecoversGamma(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{5}))
+	want := newTwoPieceShapes(0.75, 5)
+	p, err := estimateTParams(tRows(rng, 20_000, 5, &want), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range p.skew {
+		if math.Abs(s.gamma-0.75) > 0.05 {
+			t.Errorf("asset %d: gamma = %.3f, want ~0.75", i, s.gamma)
+		}
+	}
+	if sym, _ := estimateTParams(tRows(rng, 20_000, 5, nil), true, false); math.Abs(sym.skew[0].gamma-1) > 0.05 {
+		t.Errorf("gamma = %.3f fitted to symmetric data, want ~1", sym.skew[0].gamma)
+	}
+}
+
+func TestTwoPieceShapesAreStandardised(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{6}))
+	s := newTwoPieceShapes(0.6, 6)
+	var sum, sumSq float64
+	const n = 200_000
+	for range n {
+		u := math.Sqrt(4/chiSquare(rng, 6)) * rng.NormFloat64()
+		x := s.apply(u)
+		sum += x
+		sumSq += x * x
+	}
+	if mean := sum / n; math.Abs(mean) > 0.01 {
+		t.Errorf("mean = %v, want 0", mean)
+	}
+	if v := sumSq / n; math.Abs(v-1) > 0.03 {
+		t.Errorf("variance = %v, want 1", v)
+	}
+}
+
+func TestTPathKeepsVarianceAndFattensTails(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{7}))
+	skew := newTwoPieceShapes(0.8, 4)
+	for _, skewed := range []bool{false, true} {
+		p, err := estimateTParams(tRows(rng, 5000, 4, &skew), skewed, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := domain.SimulationConfig{HorizonDays: 1, StartValue: 1}
+		var sum, sumSq, sum4 float64
+		const n = 100_000
+		for range n {
+			lr := math.Log(tPath(cfg, p, allocation{weights: []float64{1, 0}}, rng).Final())
+			sum += lr
+			sumSq += lr * lr
+		}
+		mean := sum / n
+		variance := sumSq/n - mean*mean
+		if want := p.sd[0] * p.sd[0]; math.Abs(variance-want)/want > 0.1 {
+			t.Errorf("skewed=%v: daily variance = %g, want ~%g", skewed, variance, want)
+		}
+		rng2 := rand.New(rand.NewChaCha8([32]byte{8}))
+		for range n {
+			lr := math.Log(tPath(cfg, p, allocation{weights: []float64{1, 0}}, rng2).Final()) - mean
+			sum4 += lr * lr * lr * lr
+		}
+		if kurt := sum4 / n / (variance * variance); kurt < 4 {
+			t.Errorf("skewed=%v: kurtosis = %.2f with nu %.2f, want well above the normal's 3", skewed, kurt, p.nu)
+		}
+	}
+}
+
+func TestChiSquareMean(t *testing.T) {
+	rng := rand.New(rand.NewChaCha8([32]byte{9}))
+	for _, nu := range []float64{0.8, 2.5, 7} {
+		var sum float64
+		for range 100_000 {
+			sum += chiSquare(rng, nu)
+		}
+		if mean := sum / 100_000; math.Abs(mean-nu)/nu > 0.03 {
+			t.Errorf("mean of chi2(%v) = %v", nu, mean)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// CapitalMarketAssumptions are forward-looking annual return and risk figures
+// per asset. Models that take them are calibrated to the lookback window
+// rescaled to these moments, so they replace the historical means,
+// volatilities and, when given, correlations. They may cover more assets
+// than a portfolio holds, so one set can serve many experiments.
+type CapitalMarketAssumptions struct {
+	Assets []AssetAssumption
+	// Correlations is the correlation matrix of the assets' returns, in
+	// Assets order. nil keeps the correlations of the lookback window.
+	Correlations [][]float64
+}
+
+// AssetAssumption is one asset's expected simple return and volatility of
+// log-returns, both annual: 0.06 and 0.15 for 6% and 15%.
+type AssetAssumption struct {
+	Symbol         string
+	ExpectedReturn float64
+	Volatility     float64
+}
+
+// correlationTolerance is how far an assumed correlation matrix may be from
+// symmetric with a unit diagonal, to allow for rounding in typed-in figures.
+const correlationTolerance = 1e-6
+
+// ValidateFields returns every problem with the assumptions. Fields are named
+// "assets", "assets[i].symbol", "assets[i].expected_return",
+// "assets[i].volatility" and "correlations".
+func (a CapitalMarketAssumptions) ValidateFields() FieldErrors {
+	if len(a.Assets) == 0 {
+		return FieldErrors{{Field: "assets", Message: "must not be empty"}}
+	}
+	var errs FieldErrors
+	bad := func(field, msg string) { errs = append(errs, FieldError{Field: field, Message: msg}) }
+	seen := map[string]bool{}
+	for i, aa := range a.Assets {
+		switch {
+		case aa.Symbol == "":
+			bad(fmt.Sprintf("assets[%d].symbol", i), "is required")
+		case seen[aa.Symbol]:
+			bad(fmt.Sprintf("assets[%d].symbol", i), fmt.Sprintf("%s is listed more than once", aa.Symbol))
+		}
+		seen[aa.Symbol] = true
+		if !(aa.ExpectedReturn > -1) || math.IsInf(aa.ExpectedReturn, 0) {
+			bad(fmt.Sprintf("assets[%d].expected_return", i), "must be greater than -1")
+		}
+		if !(aa.Volatility > 0) || math.IsInf(aa.Volatility, 0) {
+			bad(fmt.Sprintf("assets[%d].volatility", i), "must be positive")
+		}
+	}
+	if a.Correlations != nil {
+		if msg := a.correlationProblem(); msg != "" {
+			bad("correlations", msg)
+		}
+	}
+	return errs
+}
+
+// correlationProblem describes what is wrong with a.Correlations, or returns
+// "" if it is a plausible correlation matrix. Whether it is positive
+// semi-definite is left to the run, which can repair it.
+func (a CapitalMarketAssumptions) correlationProblem() string {
+	n := len(a.Assets)
+	if len(a.Correlations) != n {
+		return fmt.Sprintf("must have %d rows, one per asset", n)
+	}
+	for i, row := range a.Correlations {
+		if len(row) != n {
+			return fmt.Sprintf("must have %d columns, one per asset", n)
+		}
+		for j, v := range row {
+			switch {
+			case !(v >= -1 && v <= 1):
+				return "must be between -1 and 1"
+			case i == j && math.Abs(v-1) > correlationTolerance:
+				return "must have ones on the diagonal"
+			case math.Abs(v-a.Correlations[j][i]) > correlationTolerance:
+				return "must be symmetric"
+			}
+		}
+	}
+	return ""
+}
+
+// For returns the assumptions for p's assets, in portfolio order, with the
+// matching rows and columns of Correlations. It fails if an asset has none.
+func (a CapitalMarketAssumptions) For(p Portfolio) (CapitalMarketAssumptions, error) {
+	index := make(map[string]int, len(a.Assets))
+	for i, aa := range a.Assets {
+		index[aa.Symbol] = i
+	}
+	pick := make([]int, len(p.Assets))
+	out := CapitalMarketAssumptions{Assets: make([]AssetAssumption, len(p.Assets))}
+	for i, pa := range p.Assets {
+		k, ok := index[pa.Symbol]
+		if !ok {
+			return CapitalMarketAssumptions{}, fmt.Errorf("no capital market assumption for %s", pa.Symbol)
+		}
+		pick[i] = k
+		out.Assets[i] = a.Assets[k]
+	}
+	if a.Correlations != nil {
+		out.Correlations = make([][]float64, len(pick))
+		for i, k := range pick {
+			out.Correlations[i] = make([]float64, len(pick))
+			for j, l := range pick {
+				out.Correlations[i][j] = a.Correlations[k][l]
+			}
+		}
+	}
+	return out, nil
+}
+
+// InputSource names where a run's expected returns, volatilities and
+// correlations came from.
+type InputSource string
+
+// Input sources recorded on runs.
+const (
+	SourceHistorical  InputSource = "historical"  // estimated from the lookback window
+	SourceAssumptions InputSource = "assumptions" // the experiment's capital market assumptions
+)
+
+// MarketInputs records the annual moments a run's model was calibrated to
+// and where they came from, so its results can be audited after the
+// experiment changes. Assets and Correlations are in portfolio order, in the
+// units of CapitalMarketAssumptions; Correlations is always set, from the
+// lookback window when the assumptions leave it out.
+type MarketInputs struct {
+	Source       InputSource
+	Assets       []AssetAssumption
+	Correlations [][]float64
+
+	// Estimator is how the lookback window's moments were estimated, with
+	// its defaults filled in. With assumptions it supplied only what they
+	// leave out: the correlations, if not assumed.
+	Estimator EstimatorParams
+	// MeanShrinkage and CovShrinkage are the weights, in [0, 1], that the
+	// bayes_stein and ledoit_wolf estimators put on their shrinkage targets;
+	// zero for the other estimators.
+	MeanShrinkage float64
+	CovShrinkage  float64
+}
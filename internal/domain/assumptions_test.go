@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapitalMarketAssumptionsValidate(t *testing.T) {
+	valid := func() CapitalMarketAssumptions {
+		return CapitalMarketAssumptions{
+			Assets: []AssetAssumption{
+				{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16},
+				{Symbol: "AGG", ExpectedReturn: 0.035, Volatility: 0.05},
+			},
+			Correlations: [][]float64{{1, 0.1}, {0.1, 1}},
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(a *CapitalMarketAssumptions)
+		field  string // "" means valid
+	}{
+		{"valid", func(a *CapitalMarketAssumptions) {}, ""},
+		{"no correlations", func(a *CapitalMarketAssumptions) { a.Correlations = nil }, ""},
+		{"no assets", func(a *CapitalMarketAssumptions) { a.Assets = nil }, "assets"},
+		{"duplicate symbol", func(a *CapitalMarketAssumptions) { a.Assets[1].Symbol = "SPY" }, "assets[1].symbol"},
+		{"loss of everything", func(a *CapitalMarketAssumptions) { a.Assets[0].ExpectedReturn = -1 }, "assets[0].expected_return"},
+		{"zero volatility", func(a *CapitalMarketAssumptions) { a.Assets[1].Volatility = 0 }, "assets[1].volatility"},
+		{"wrong size", func(a *CapitalMarketAssumptions) { a.Correlations = a.Correlations[:1] }, "correlations"},
+		{"ragged", func(a *CapitalMarketAssumptions) { a.Correlations[1] = []float64{0.1} }, "correlations"},
+		{"asymmetric", func(a *CapitalMarketAssumptions) { a.Correlations[1][0] = 0.2 }, "correlations"},
+		{"diagonal", func(a *CapitalMarketAssumptions) { a.Correlations[0][0] = 0.9 }, "correlations"},
+		{"out of range", func(a *CapitalMarketAssumptions) { a.Correlations[0][1], a.Correlations[1][0] = 1.5, 1.5 }, "correlations"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := valid()
+			tc.mutate(&a)
+			errs := a.ValidateFields()
+			switch {
+			case tc.field == "" && len(errs) > 0:
+				t.Errorf("ValidateFields() = %v, want none", errs)
+			case tc.field != "" && (len(errs) != 1 || errs[0].Field != tc.field):
+				t.Errorf("ValidateFields() = %v, want one error on %s", errs, tc.field)
+			}
+		})
+	}
+}
+
+func TestCapitalMarketAssumptionsFor(t *testing.T) {
+	a := CapitalMarketAssumptions{
+		Assets: []AssetAssumption{
+			{Symbol: "SPY", ExpectedReturn: 0.06, Volatility: 0.16},
+			{Symbol: "EFA", ExpectedReturn: 0.07, Volatility: 0.18},
+			{Symbol: "AGG", ExpectedReturn: 0.035, Volatility: 0.05},
+		},
+		Correlations: [][]float64{{1, 0.8, 0.1}, {0.8, 1, 0.05}, {0.1, 0.05, 1}},
+	}
+	p := Portfolio{Assets: []PortfolioAsset{{Symbol: "AGG", Weight: 0.4}, {Symbol: "SPY", Weight: 0.6}}}
+	got, err := a.For(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Assets[0].Symbol != "AGG" || got.Assets[1].Symbol != "SPY" || got.Correlations[0][1] != 0.1 || got.Correlations[1][1] != 1 {
+		t.Errorf("For = %+v, want AGG and SPY in portfolio order", got)
+	}
+
+	p.Assets = append(p.Assets, PortfolioAsset{Symbol: "GLD"})
+	if _, err := a.For(p); err == nil || !strings.Contains(err.Error(), "GLD") {
+		t.Errorf("For with an uncovered asset: err = %v", err)
+	}
+	c := SimulationConfig{Assumptions: &a}
+	if errs := c.ValidateAssumptions(p); len(errs) != 1 || !strings.Contains(errs[0].Message, "GLD") {
+		t.Errorf("ValidateAssumptions = %v, want GLD reported", errs)
+	}
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "sort"
+
+// DefaultQuantiles are the fan-chart quantiles used when a config sets none.
+var DefaultQuantiles = []float64{0.05, 0.25, 0.50, 0.75, 0.95}
+
+// Band sizing. Bands are downsampled so a multi-decade horizon still yields a
+// chart-sized payload; BandSamplePaths individual paths are kept alongside.
+const (
+	MaxBandPoints   = 250
+	BandSamplePaths = 20
+)
+
+// Bands holds per-day quantiles of portfolio value across every path of a run,
+// plus a small sample of individual paths for spaghetti lines.
+type Bands struct {
+	Days      []int       // trading-day indices, as returned by SampledDays
+	Quantiles []float64   // ascending, each in (0, 1)
+	Values    [][]float64 // Values[q][j] is Quantiles[q] on Days[j]
+	Sample    [][]float64 // Sample[k][j] is sample path k on Days[j]
+}
+
+// BandQuantiles returns the configured fan-chart quantiles in ascending order,
+// or DefaultQuantiles when none are set.
+func (c SimulationConfig) BandQuantiles() []float64 {
+	if len(c.Quantiles) == 0 {
+		return DefaultQuantiles
+	}
+	qs := append([]float64(nil), c.Quantiles...)
+	sort.Float64s(qs)
+	return qs
+}
+
+// ComputeBands derives per-day quantile bands from the full set of paths.
+// Quantiles use the same nearest-rank rule as ComputeStats, so the terminal
+// band values agree with the run's terminal percentiles.
+func ComputeBands(paths []SimulatedPath, quantiles []float64, horizonDays int) Bands {
+	every := (horizonDays + MaxBandPoints - 1) / MaxBandPoints
+	days := SampledDays(horizonDays, every)
+	b := Bands{Days: days, Quantiles: quantiles, Values: make([][]float64, len(quantiles))}
+	for q := range b.Values {
+		b.Values[q] = make([]float64, len(days))
+	}
+	if len(paths) == 0 {
+		return b
+	}
+
+	n := len(paths)
+	col := make([]float64, n)
+	for j, d := range days {
+		for i, p := range paths {
+			col[i] = p.valueAt(d)
+		}
+		sort.Float64s(col)
+		for q, level := range quantiles {
+			b.Values[q][j] = col[rank(n, level)]
+		}
+	}
+
+	// Evenly spaced indices keep the sample representative of the whole pool
+	// rather than of whichever worker produced the first paths.
+	k := min(BandSamplePaths, n)
+	b.Sample = make([][]float64, k)
+	for s := range b.Sample {
+		p := paths[s*n/k]
+		vals := make([]float64, len(days))
+		for j, d := range days {
+			vals[j] = p.valueAt(d)
+		}
+		b.Sample[s] = vals
+	}
+	return b
+}
+
+// rank returns the nearest-rank index of quantile q in a sorted slice of n.
+func rank(n int, q float64) int {
+	i := int(float64(n) * q)
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+// linearPaths returns n paths where path i grows by i per day from 100.
+func linearPaths(n, horizon int) []SimulatedPath {
+	paths := make([]SimulatedPath, n)
+	for i := range paths {
+		vals := make([]float64, horizon+1)
+		for d := range vals {
+			vals[d] = 100 + float64(i*d)
+		}
+		paths[i] = SimulatedPath{Values: vals}
+	}
+	return paths
+}
+
+func TestComputeBandsQuantiles(t *testing.T) {
+	paths := linearPaths(100, 10)
+	b := ComputeBands(paths, DefaultQuantiles, 10)
+
+	if len(b.Days) != 11 {
+		t.Fatalf("Days = %v, want all 11 days for a short horizon", b.Days)
+	}
+	for q := range b.Quantiles {
+		if b.Values[q][0] != 100 {
+			t.Errorf("quantile %v on day 0 = %v, want 100", b.Quantiles[q], b.Values[q][0])
+		}
+	}
+	// On day 10 path i is worth 100+10i, so the nearest-rank p25 is path 25.
+	if got := b.Values[1][10]; got != 350 {
+		t.Errorf("p25 on day 10 = %v, want 350", got)
+	}
+	if len(b.Sample) != BandSamplePaths || len(b.Sample[0]) != len(b.Days) {
+		t.Errorf("sample shape = %dx%d, want %dx%d", len(b.Sample), len(b.Sample[0]), BandSamplePaths, len(b.Days))
+	}
+}
+
+func TestComputeBandsMatchesTerminalStats(t *testing.T) {
+	paths := linearPaths(1000, 2520)
+	b := ComputeBands(paths, DefaultQuantiles, 2520)
+	stats := ComputeStats(paths, 100, 10)
+
+	if len(b.Days) > MaxBandPoints+1 {
+		t.Errorf("len(Days) = %d, want at most %d", len(b.Days), MaxBandPoints+1)
+	}
+	last := len(b.Days) - 1
+	if b.Days[last] != 2520 {
+		t.Fatalf("last band day = %d, want horizon", b.Days[last])
+	}
+	want := []float64{stats.P5, stats.P25, stats.P50, stats.P75, stats.P95}
+	for q, w := range want {
+		if math.Abs(b.Values[q][last]-w) > 1e-9 {
+			t.Errorf("terminal band %v = %v, want %v", b.Quantiles[q], b.Values[q][last], w)
+		}
+	}
+}
+
+func TestBandQuantilesSortsCustomList(t *testing.T) {
+	cfg := SimulationConfig{Quantiles: []float64{0.9, 0.1, 0.5}}
+	got := cfg.BandQuantiles()
+	if got[0] != 0.1 || got[2] != 0.9 {
+		t.Errorf("BandQuantiles = %v, want ascending", got)
+	}
+	if cfg.Quantiles[0] != 0.9 {
+		t.Error("BandQuantiles must not reorder the config in place")
+	}
+}
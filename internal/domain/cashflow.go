@@ -0,0 +1,29 @@
+package domain
+
+// CashFlowFrequency controls how often contributions and withdrawals are applied.
+type CashFlowFrequency string
+
+// Cash-flow frequency options.
+const (
+	CashFlowMonthly CashFlowFrequency = "monthly"
+	CashFlowAnnual  CashFlowFrequency = "annual"
+)
+
+// Interval returns the number of trading days between cash flows, assuming
+// 21 trading days per month and 252 per year. Unknown values mean annual.
+func (f CashFlowFrequency) Interval() int {
+	if f == CashFlowMonthly {
+		return 21
+	}
+	return 252
+}
+
+// PeriodsPerYear returns how many cash-flow periods fall in one year.
+func (f CashFlowFrequency) PeriodsPerYear() int {
+	return 252 / f.Interval()
+}
+
+// HasCashFlows reports whether the config schedules any contribution or withdrawal.
+func (c SimulationConfig) HasCashFlows() bool {
+	return c.AnnualContribution != 0 || c.AnnualWithdrawal != 0 || c.WithdrawalRate != 0
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotFound is wrapped by errors reporting that a requested record does not
+// exist, so callers can tell a missing record from a failed lookup.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalid is wrapped by errors reporting that caller-supplied input, such as
+// an experiment config, failed to parse or validate.
+var ErrInvalid = errors.New("invalid input")
+
+// ErrRunActive is returned when an operation needs every affected run to have
+// finished but one is still queued or running.
+var ErrRunActive = errors.New("run is queued or running")
+
+// FieldError reports one invalid field of caller-supplied input. Field names
+// it as the input does, e.g. "portfolio.assets[1].weight".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// FieldErrors reports every invalid field of an input at once, so a caller
+// can fix them in one pass. It wraps ErrInvalid.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e FieldErrors) Unwrap() error { return ErrInvalid }
+
+// Prefix returns e with prefix and a dot prepended to every field name, for
+// errors found in a nested part of a larger input.
+func (e FieldErrors) Prefix(prefix string) FieldErrors {
+	out := make(FieldErrors, len(e))
+	for i, fe := range e {
+		out[i] = FieldError{Field: prefix + "." + fe.Field, Message: fe.Message}
+	}
+	return out
+}
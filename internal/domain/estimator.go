@@ -0,0 +1,82 @@
+package domain
+
+// MeanEstimator selects how expected returns are estimated from the lookback
+// window.
+type MeanEstimator string
+
+// Supported mean estimators.
+const (
+	MeanSample     MeanEstimator = "sample"      // each asset's sample mean
+	MeanBayesStein MeanEstimator = "bayes_stein" // Jorion: shrunk toward the minimum-variance portfolio's mean
+)
+
+// CovEstimator selects how the covariance of returns is estimated from the
+// lookback window.
+type CovEstimator string
+
+// Supported covariance estimators.
+const (
+	CovSample     CovEstimator = "sample"      // equally weighted sample covariance
+	CovEWMA       CovEstimator = "ewma"        // RiskMetrics exponentially weighted, by HalfLife
+	CovLedoitWolf CovEstimator = "ledoit_wolf" // shrunk toward the constant-correlation matrix
+)
+
+// DefaultEWMAHalfLife is the EWMA half-life, in trading days, used when
+// EstimatorParams.HalfLife is zero.
+const DefaultEWMAHalfLife = 63
+
+// EstimatorParams configures how the lookback window's expected returns and
+// covariance are estimated for models that take capital market assumptions.
+// The model is calibrated to the window rescaled to the estimates, as it is
+// to assumptions; assumptions, when set, take precedence, leaving the
+// estimators only what they do not give.
+type EstimatorParams struct {
+	Mean MeanEstimator // empty means MeanSample
+	Cov  CovEstimator  // empty means CovSample
+	// HalfLife is the EWMA half-life in trading days; zero uses
+	// DefaultEWMAHalfLife. Only CovEWMA reads it.
+	HalfLife int
+}
+
+// Normalized returns p with its defaults filled in.
+func (p EstimatorParams) Normalized() EstimatorParams {
+	if p.Mean == "" {
+		p.Mean = MeanSample
+	}
+	if p.Cov == "" {
+		p.Cov = CovSample
+	}
+	if p.Cov == CovEWMA && p.HalfLife == 0 {
+		p.HalfLife = DefaultEWMAHalfLife
+	}
+	return p
+}
+
+// IsSample reports whether p estimates both moments by their sample values,
+// leaving the lookback window as it is.
+func (p EstimatorParams) IsSample() bool {
+	n := p.Normalized()
+	return n.Mean == MeanSample && n.Cov == CovSample
+}
+
+// Validate returns every problem with the estimator parameters.
+func (p EstimatorParams) Validate() FieldErrors {
+	var errs FieldErrors
+	bad := func(field, msg string) { errs = append(errs, FieldError{Field: field, Message: msg}) }
+	switch p.Mean {
+	case "", MeanSample, MeanBayesStein:
+	default:
+		bad("mean_estimator", "must be sample or bayes_stein")
+	}
+	switch p.Cov {
+	case "", CovSample, CovEWMA, CovLedoitWolf:
+	default:
+		bad("cov_estimator", "must be sample, ewma or ledoit_wolf")
+	}
+	if p.HalfLife < 0 {
+		bad("ewma_half_life", "must not be negative")
+	} else if p.HalfLife > 0 && p.Cov != CovEWMA {
+		bad("ewma_half_life", "needs cov_estimator ewma")
+	}
+	return errs
+}
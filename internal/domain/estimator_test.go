@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestEstimatorParamsValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     EstimatorParams
+		field string // "" means valid
+	}{
+		{"zero", EstimatorParams{}, ""},
+		{"shrinkage", EstimatorParams{Mean: MeanBayesStein, Cov: CovLedoitWolf}, ""},
+		{"ewma", EstimatorParams{Cov: CovEWMA, HalfLife: 21}, ""},
+		{"unknown mean", EstimatorParams{Mean: "james_stein"}, "mean_estimator"},
+		{"unknown cov", EstimatorParams{Cov: "robust"}, "cov_estimator"},
+		{"negative half-life", EstimatorParams{Cov: CovEWMA, HalfLife: -1}, "ewma_half_life"},
+		{"half-life without ewma", EstimatorParams{HalfLife: 21}, "ewma_half_life"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.p.Validate()
+			switch {
+			case tc.field == "" && len(errs) > 0:
+				t.Errorf("Validate() = %v, want none", errs)
+			case tc.field != "" && (len(errs) != 1 || errs[0].Field != tc.field):
+				t.Errorf("Validate() = %v, want one error on %s", errs, tc.field)
+			}
+		})
+	}
+}
+
+func TestEstimatorParamsNormalized(t *testing.T) {
+	if !(EstimatorParams{}).IsSample() || (EstimatorParams{Mean: MeanBayesStein}).IsSample() {
+		t.Error("IsSample should hold only for the sample estimators")
+	}
+	if got := (EstimatorParams{Cov: CovEWMA}).Normalized(); got.Mean != MeanSample || got.HalfLife != DefaultEWMAHalfLife {
+		t.Errorf("Normalized() = %+v, want sample means and the default half-life", got)
+	}
+}
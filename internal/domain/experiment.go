@@ -1,18 +1,37 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // ExperimentStatus tracks the lifecycle of an experiment run.
 type ExperimentStatus string
 
 // Lifecycle status values for ExperimentStatus.
 const (
-	StatusDraft    ExperimentStatus = "draft"
-	StatusRunning  ExperimentStatus = "running"
-	StatusComplete ExperimentStatus = "complete"
-	StatusFailed   ExperimentStatus = "failed"
+	StatusDraft     ExperimentStatus = "draft"
+	StatusQueued    ExperimentStatus = "queued"
+	StatusRunning   ExperimentStatus = "running"
+	StatusComplete  ExperimentStatus = "complete"
+	StatusFailed    ExperimentStatus = "failed"
+	StatusCancelled ExperimentStatus = "cancelled"
 )
 
+// RunInterrupted is the error recorded on a run whose process stopped before
+// the run could finish.
+const RunInterrupted = "interrupted"
+
+// ErrRunNotActive is returned when an operation needs a queued or running run
+// but the run has already finished.
+var ErrRunNotActive = errors.New("run is not queued or running")
+
+// Active reports whether a run in this status is waiting for or occupying a
+// worker, i.e. has not reached a terminal state yet.
+func (s ExperimentStatus) Active() bool {
+	return s == StatusQueued || s == StatusRunning
+}
+
 // Experiment is a named simulation configuration that can be run one or many times.
 type Experiment struct {
 	ID          string
@@ -24,13 +43,53 @@ type Experiment struct {
 	UpdatedAt   time.Time
 }
 
+// ValidateFields returns every problem with the experiment: its portfolio's,
+// with fields prefixed "portfolio.", its config's, and any portfolio asset
+// the config's capital market assumptions leave out.
+func (e Experiment) ValidateFields() FieldErrors {
+	errs := e.Portfolio.ValidateFields().Prefix("portfolio")
+	errs = append(errs, e.Config.ValidateFields()...)
+	return append(errs, e.Config.ValidateAssumptions(e.Portfolio)...)
+}
+
 // Run is a single execution of an Experiment, capturing the result snapshot.
 type Run struct {
 	ID           string
 	ExperimentID string
-	StartedAt    time.Time
+	QueuedAt     time.Time
+	StartedAt    time.Time // when a worker claimed the run; zero while queued
 	FinishedAt   *time.Time
 	Status       ExperimentStatus
 	Error        string
 	Stats        ResultStats
+	Bands        *Bands        // per-day quantile bands; nil for runs that never completed
+	Fit          *ModelFit     // parameters the model estimated; nil for models that fit none
+	Inputs       *MarketInputs // moments the model was calibrated to; nil until the run calibrates
+
+	ProgressDone  int // paths simulated so far
+	ProgressTotal int // paths the run will simulate (Config.NumPaths)
+}
+
+// RunProgress is a point-in-time view of how far a run has got. It is
+// published while the run executes and streamed to anyone watching it.
+type RunProgress struct {
+	RunID   string
+	Status  ExperimentStatus
+	Done    int           // paths simulated so far
+	Total   int           // paths the run will simulate
+	Elapsed time.Duration // time since the run was claimed; zero while queued
+	Error   string
+}
+
+// Progress returns the run's progress as of now.
+func (r Run) Progress() RunProgress {
+	p := RunProgress{RunID: r.ID, Status: r.Status, Done: r.ProgressDone, Total: r.ProgressTotal, Error: r.Error}
+	switch {
+	case r.StartedAt.IsZero():
+	case r.FinishedAt != nil:
+		p.Elapsed = r.FinishedAt.Sub(r.StartedAt)
+	default:
+		p.Elapsed = time.Since(r.StartedAt)
+	}
+	return p
 }
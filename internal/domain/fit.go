@@ -0,0 +1,22 @@
+package domain
+
+// ModelFit records the parameters a model estimated from the lookback window,
+// so a run can be inspected after the fact. Models that fit nothing (GBM, the
+// bootstraps, a jump-diffusion or Heston model with configured parameters)
+// leave Run.Fit nil.
+type ModelFit struct {
+	Model SimulationModel
+	// LogLikelihood is the maximised log-likelihood of the lookback returns;
+	// for models fitted asset by asset it is the sum of the Assets' values.
+	// It is zero for models calibrated by other means.
+	LogLikelihood float64
+	Params        map[string]float64 // parameters shared by every asset, e.g. "nu"
+	Assets        []AssetFit         // per-asset parameters, in portfolio order
+}
+
+// AssetFit is the part of a ModelFit estimated for one asset.
+type AssetFit struct {
+	Symbol        string
+	LogLikelihood float64 // zero when the asset was not fitted on its own
+	Params        map[string]float64
+}
@@ -0,0 +1,222 @@
+package domain
+
+// ModelSpec describes a simulation model for clients choosing one: its
+// identifier, a short label for menus, and the parameters of its block of
+// SimulationConfig.
+type ModelSpec struct {
+	Name        SimulationModel
+	Label       string // e.g. "GARCH(1,1) (volatility clustering)"
+	Description string
+	Params      []ParamSpec
+	// TakesAssumptions reports whether the model calibrates to the config's
+	// capital market assumptions; a model that does not rejects them.
+	TakesAssumptions bool
+}
+
+// ParamType is the JSON type of a model parameter.
+type ParamType string
+
+// Supported parameter types.
+const (
+	ParamNumber  ParamType = "number"
+	ParamInteger ParamType = "integer"
+	ParamBoolean ParamType = "boolean"
+	ParamString  ParamType = "string"
+)
+
+// ParamSpec describes one model parameter by its snake_case config name, the
+// name its validation errors use.
+type ParamSpec struct {
+	Name        string
+	Type        ParamType
+	Description string
+	Default     any      // value in effect when the parameter is omitted
+	Min, Max    *float64 // inclusive bounds of a number or integer; nil is unbounded
+	Enum        []string // allowed values of a string parameter
+}
+
+// bound returns a pointer to v for ParamSpec bounds.
+func bound(v float64) *float64 { return &v }
+
+// BlockParams configures the block bootstrap.
+type BlockParams struct {
+	// Length is the block length in trading days (the mean length for
+	// BlockStationary); zero uses the engine default.
+	Length int
+	Scheme BlockScheme // empty means BlockFixed
+}
+
+// Spec describes the block bootstrap's parameters.
+func (BlockParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "block_length", Type: ParamInteger, Default: 21, Min: bound(0),
+			Description: "Block length in trading days, the mean length for the stationary scheme; 0 uses the default."},
+		{Name: "block_scheme", Type: ParamString, Default: string(BlockFixed),
+			Enum:        []string{string(BlockFixed), string(BlockCircular), string(BlockStationary)},
+			Description: "fixed (moving blocks), circular (wrap-around) or stationary (Politis–Romano geometric lengths)."},
+	}
+}
+
+// Validate returns every problem with the block bootstrap parameters.
+func (p BlockParams) Validate() FieldErrors {
+	var errs FieldErrors
+	if p.Length < 0 {
+		errs = append(errs, FieldError{Field: "block_length", Message: "must not be negative"})
+	}
+	switch p.Scheme {
+	case "", BlockFixed, BlockCircular, BlockStationary:
+	default:
+		errs = append(errs, FieldError{Field: "block_scheme", Message: "must be fixed, circular or stationary"})
+	}
+	return errs
+}
+
+// StudentTParams configures the Student-t model.
+type StudentTParams struct {
+	// Skewed fits a per-asset skew as well as the fat tails.
+	Skewed bool
+}
+
+// Spec describes the Student-t model's parameters.
+func (StudentTParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "skewed_t", Type: ParamBoolean, Default: false,
+			Description: "Fit a per-asset two-piece skew as well as the fat tails."},
+	}
+}
+
+// Validate returns every problem with the Student-t parameters; there are
+// none to find.
+func (StudentTParams) Validate() FieldErrors { return nil }
+
+// GARCHParams configures the GARCH model.
+type GARCHParams struct {
+	// GJR adds the Glosten–Jagannathan–Runkle leverage term, so falls raise
+	// volatility more than rises of the same size.
+	GJR bool
+}
+
+// Spec describes the GARCH model's parameters.
+func (GARCHParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "gjr", Type: ParamBoolean, Default: false,
+			Description: "Add the GJR leverage term, so falls raise volatility more than rises."},
+	}
+}
+
+// Validate returns every problem with the GARCH parameters; there are none
+// to find.
+func (GARCHParams) Validate() FieldErrors { return nil }
+
+// JumpParams configures the jump-diffusion model. A zero Intensity calibrates
+// the jumps from the lookback window; otherwise every asset jumps Intensity
+// times a year on average, by a normal log-jump with mean Mean and standard
+// deviation Vol.
+type JumpParams struct {
+	Intensity float64
+	Mean      float64
+	Vol       float64
+}
+
+// Spec describes the jump-diffusion model's parameters.
+func (JumpParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "jump_intensity", Type: ParamNumber, Default: 0.0, Min: bound(0),
+			Description: "Expected jumps per year; 0 calibrates all three parameters from history."},
+		{Name: "jump_mean", Type: ParamNumber, Default: 0.0,
+			Description: "Mean log-jump, e.g. -0.1; needs jump_intensity."},
+		{Name: "jump_vol", Type: ParamNumber, Default: 0.0, Min: bound(0),
+			Description: "Standard deviation of the log-jump; needs jump_intensity."},
+	}
+}
+
+// Validate returns every problem with the jump-diffusion parameters.
+func (p JumpParams) Validate() FieldErrors {
+	var errs FieldErrors
+	if p.Intensity < 0 {
+		errs = append(errs, FieldError{Field: "jump_intensity", Message: "must not be negative"})
+	}
+	if p.Vol < 0 {
+		errs = append(errs, FieldError{Field: "jump_vol", Message: "must not be negative"})
+	}
+	if p.Intensity == 0 && (p.Mean != 0 || p.Vol != 0) {
+		errs = append(errs, FieldError{Field: "jump_intensity", Message: "must be set when jump_mean or jump_vol is"})
+	}
+	return errs
+}
+
+// RegimeParams configures the regime-switching model.
+type RegimeParams struct {
+	// States is the number of hidden states, 2 or 3; zero means 2.
+	States int
+	// StartCurrent starts every path in the state most likely on the last
+	// lookback day instead of drawing it from the long-run state shares.
+	StartCurrent bool
+}
+
+// Spec describes the regime-switching model's parameters.
+func (RegimeParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "regime_states", Type: ParamInteger, Default: 2, Min: bound(2), Max: bound(3),
+			Description: "Number of regimes: 2 (bull/bear) or 3 (bull/bear/crisis)."},
+		{Name: "regime_start_current", Type: ParamBoolean, Default: false,
+			Description: "Start every path in the regime the lookback window most likely ended in."},
+	}
+}
+
+// Validate returns every problem with the regime-switching parameters.
+func (p RegimeParams) Validate() FieldErrors {
+	switch p.States {
+	case 0, 2, 3:
+		return nil
+	}
+	return FieldErrors{{Field: "regime_states", Message: "must be 2 or 3"}}
+}
+
+// HestonParams configures the Heston model. A zero Kappa calibrates every
+// asset's variance process from the lookback window; otherwise every asset's
+// annualised variance reverts to Theta (zero means the asset's sample
+// variance) at rate Kappa per year, with volatility of variance Xi and
+// correlation Rho between the variance and price shocks.
+type HestonParams struct {
+	Kappa float64
+	Theta float64
+	Xi    float64
+	Rho   float64
+}
+
+// Spec describes the Heston model's parameters.
+func (HestonParams) Spec() []ParamSpec {
+	return []ParamSpec{
+		{Name: "heston_kappa", Type: ParamNumber, Default: 0.0, Min: bound(0),
+			Description: "Variance mean reversion per year; 0 calibrates every parameter from history."},
+		{Name: "heston_theta", Type: ParamNumber, Default: 0.0, Min: bound(0),
+			Description: "Long-run annualised variance, e.g. 0.04; 0 uses each asset's sample variance. Needs heston_kappa."},
+		{Name: "heston_xi", Type: ParamNumber, Default: 0.0, Min: bound(0),
+			Description: "Volatility of variance; needs heston_kappa."},
+		{Name: "heston_rho", Type: ParamNumber, Default: 0.0, Min: bound(-1), Max: bound(1),
+			Description: "Correlation between the variance and price shocks; needs heston_kappa."},
+	}
+}
+
+// Validate returns every problem with the Heston parameters.
+func (p HestonParams) Validate() FieldErrors {
+	var errs FieldErrors
+	bad := func(field, msg string) { errs = append(errs, FieldError{Field: field, Message: msg}) }
+	if p.Kappa < 0 {
+		bad("heston_kappa", "must not be negative")
+	}
+	if p.Theta < 0 {
+		bad("heston_theta", "must not be negative")
+	}
+	if p.Xi < 0 {
+		bad("heston_xi", "must not be negative")
+	}
+	if p.Rho < -1 || p.Rho > 1 {
+		bad("heston_rho", "must be between -1 and 1")
+	}
+	if p.Kappa == 0 && (p.Theta != 0 || p.Xi != 0 || p.Rho != 0) {
+		bad("heston_kappa", "must be set when heston_theta, heston_xi or heston_rho is")
+	}
+	return errs
+}
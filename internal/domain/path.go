@@ -4,6 +4,10 @@ package domain
 // from day 0 (= StartValue) through day HorizonDays.
 type SimulatedPath struct {
 	Values []float64 // length = HorizonDays + 1
+
+	// NetCashFlow is the sum of all contributions minus withdrawals applied
+	// along the path; zero when the config schedules no cash flows.
+	NetCashFlow float64
 }
 
 // Final returns the terminal portfolio value.
@@ -34,3 +38,21 @@ func (p SimulatedPath) MaxDrawdown() float64 {
 	}
 	return maxDD
 }
+
+// Depleted reports whether the path ran out of money at any point.
+func (p SimulatedPath) Depleted() bool {
+	for i := 1; i < len(p.Values); i++ {
+		if p.Values[i] <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// valueAt returns the value on trading day d, or 0 when the path is shorter.
+func (p SimulatedPath) valueAt(d int) float64 {
+	if d < len(p.Values) {
+		return p.Values[d]
+	}
+	return 0
+}
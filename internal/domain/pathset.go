@@ -0,0 +1,56 @@
+package domain
+
+// PathPrecision selects the floating-point width used to persist path values.
+type PathPrecision string
+
+// Path precision options.
+const (
+	PrecisionFloat64 PathPrecision = "float64"
+	PrecisionFloat32 PathPrecision = "float32"
+)
+
+// PathSet is a window of the paths persisted for a run. Every path in Paths
+// holds one value per entry of Days, the trading-day indices that were kept
+// after downsampling.
+type PathSet struct {
+	RunID     string
+	Total     int // number of paths stored for the run, regardless of the window
+	Offset    int // index of Paths[0] within the run
+	Days      []int
+	Precision PathPrecision
+	Paths     []SimulatedPath
+}
+
+// SampledDays returns the trading-day indices kept when a path of
+// horizonDays+1 values is downsampled to every Nth day. Day 0 and the
+// terminal day are always kept; every <= 1 keeps all days.
+func SampledDays(horizonDays, every int) []int {
+	if every < 1 {
+		every = 1
+	}
+	days := make([]int, 0, horizonDays/every+2)
+	for d := 0; d <= horizonDays; d += every {
+		days = append(days, d)
+	}
+	if days[len(days)-1] != horizonDays {
+		days = append(days, horizonDays)
+	}
+	return days
+}
+
+// NewPathSet downsamples full-resolution paths to every Nth day for storage.
+func NewPathSet(runID string, paths []SimulatedPath, horizonDays, every int, precision PathPrecision) PathSet {
+	if precision == "" {
+		precision = PrecisionFloat64
+	}
+	days := SampledDays(horizonDays, every)
+	out := make([]SimulatedPath, len(paths))
+	for i, p := range paths {
+		vals := make([]float64, len(days))
+		for j, d := range days {
+			vals[j] = p.valueAt(d)
+		}
+		out[i] = SimulatedPath{Values: vals, NetCashFlow: p.NetCashFlow}
+	}
+	return PathSet{RunID: runID, Total: len(paths), Days: days, Precision: precision, Paths: out}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSampledDays(t *testing.T) {
+	tests := []struct {
+		name         string
+		horizon, nth int
+		want         []int
+	}{
+		{"every day", 3, 1, []int{0, 1, 2, 3}},
+		{"zero means every day", 2, 0, []int{0, 1, 2}},
+		{"divides evenly", 10, 5, []int{0, 5, 10}},
+		{"terminal day appended", 10, 4, []int{0, 4, 8, 10}},
+		{"step beyond horizon", 3, 10, []int{0, 3}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SampledDays(tc.horizon, tc.nth); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("SampledDays(%d, %d) = %v, want %v", tc.horizon, tc.nth, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewPathSetDownsamples(t *testing.T) {
+	paths := []SimulatedPath{{Values: []float64{100, 101, 102, 103, 104}}}
+
+	set := NewPathSet("run_1", paths, 4, 3, "")
+
+	if set.Precision != PrecisionFloat64 {
+		t.Errorf("Precision = %q, want float64 default", set.Precision)
+	}
+	if !reflect.DeepEqual(set.Days, []int{0, 3, 4}) {
+		t.Fatalf("Days = %v, want [0 3 4]", set.Days)
+	}
+	if !reflect.DeepEqual(set.Paths[0].Values, []float64{100, 103, 104}) {
+		t.Errorf("Values = %v, want [100 103 104]", set.Paths[0].Values)
+	}
+}
@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"fmt"
+	"math"
+)
+
 // Portfolio aggregates a set of weighted assets that are simulated together.
 type Portfolio struct {
 	Assets    []PortfolioAsset
@@ -18,11 +23,44 @@ type RebalanceFrequency string
 // Rebalance frequency options.
 const (
 	RebalanceNone      RebalanceFrequency = "none"
+	RebalanceDaily     RebalanceFrequency = "daily"
 	RebalanceMonthly   RebalanceFrequency = "monthly"
 	RebalanceQuarterly RebalanceFrequency = "quarterly"
 	RebalanceAnnual    RebalanceFrequency = "annual"
 )
 
+// ParseRebalanceFrequency maps a user-supplied name to a RebalanceFrequency.
+// "yearly" is accepted as an alias for "annual" and "" means RebalanceNone.
+// ok is false for unrecognised names.
+func ParseRebalanceFrequency(s string) (f RebalanceFrequency, ok bool) {
+	switch RebalanceFrequency(s) {
+	case "", RebalanceNone:
+		return RebalanceNone, true
+	case "yearly":
+		return RebalanceAnnual, true
+	case RebalanceDaily, RebalanceMonthly, RebalanceQuarterly, RebalanceAnnual:
+		return RebalanceFrequency(s), true
+	}
+	return "", false
+}
+
+// Interval returns the number of trading days between rebalances, assuming
+// 21 trading days per month and 252 per year. Zero means never rebalance.
+func (f RebalanceFrequency) Interval() int {
+	switch f {
+	case RebalanceDaily:
+		return 1
+	case RebalanceMonthly:
+		return 21
+	case RebalanceQuarterly:
+		return 63
+	case RebalanceAnnual:
+		return 252
+	default:
+		return 0
+	}
+}
+
 // TotalWeight returns the sum of all asset weights (should equal 1.0 for a valid portfolio).
 func (p Portfolio) TotalWeight() float64 {
 	total := 0.0
@@ -31,3 +69,35 @@ func (p Portfolio) TotalWeight() float64 {
 	}
 	return total
 }
+
+// WeightTolerance is how far a portfolio's weights may sum from 1.0, to allow
+// for floating-point rounding.
+const WeightTolerance = 1e-6
+
+// ValidateFields returns every problem with the portfolio: it must hold at
+// least one asset, each with a symbol, a positive weight and no duplicate,
+// and the weights must sum to 1.0. Fields are named "assets" and
+// "assets[i].symbol" or "assets[i].weight".
+func (p Portfolio) ValidateFields() FieldErrors {
+	if len(p.Assets) == 0 {
+		return FieldErrors{{Field: "assets", Message: "must not be empty"}}
+	}
+	var errs FieldErrors
+	seen := map[string]bool{}
+	for i, a := range p.Assets {
+		switch {
+		case a.Symbol == "":
+			errs = append(errs, FieldError{Field: fmt.Sprintf("assets[%d].symbol", i), Message: "is required"})
+		case seen[a.Symbol]:
+			errs = append(errs, FieldError{Field: fmt.Sprintf("assets[%d].symbol", i), Message: fmt.Sprintf("%s is listed more than once", a.Symbol)})
+		}
+		seen[a.Symbol] = true
+		if a.Weight <= 0 {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("assets[%d].weight", i), Message: "must be positive"})
+		}
+	}
+	if total := p.TotalWeight(); math.Abs(total-1) > WeightTolerance {
+		errs = append(errs, FieldError{Field: "assets", Message: fmt.Sprintf("weights must sum to 1, got %.6g", total)})
+	}
+	return errs
+}
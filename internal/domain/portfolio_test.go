@@ -2,6 +2,7 @@ package domain
 
 import (
 	"math"
+	"slices"
 	"testing"
 )
 
@@ -36,3 +37,74 @@ func TestTotalWeight(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRebalanceFrequency(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   RebalanceFrequency
+		wantOK bool
+	}{
+		{"", RebalanceNone, true},
+		{"none", RebalanceNone, true},
+		{"daily", RebalanceDaily, true},
+		{"monthly", RebalanceMonthly, true},
+		{"quarterly", RebalanceQuarterly, true},
+		{"annual", RebalanceAnnual, true},
+		{"yearly", RebalanceAnnual, true},
+		{"weekly", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, ok := ParseRebalanceFrequency(tc.in)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("ParseRebalanceFrequency(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestRebalanceInterval(t *testing.T) {
+	tests := []struct {
+		f    RebalanceFrequency
+		want int
+	}{
+		{RebalanceNone, 0},
+		{RebalanceDaily, 1},
+		{RebalanceMonthly, 21},
+		{RebalanceQuarterly, 63},
+		{RebalanceAnnual, 252},
+	}
+	for _, tc := range tests {
+		if got := tc.f.Interval(); got != tc.want {
+			t.Errorf("%q.Interval() = %d, want %d", tc.f, got, tc.want)
+		}
+	}
+}
+
+func TestPortfolioValidateFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []PortfolioAsset
+		want   []string
+	}{
+		{"valid", []PortfolioAsset{{Symbol: "SPY", Weight: 0.1}, {Symbol: "AGG", Weight: 0.2}, {Symbol: "GLD", Weight: 0.7}}, nil},
+		{"empty", nil, []string{"assets: must not be empty"}},
+		{"short of one", []PortfolioAsset{{Symbol: "SPY", Weight: 0.6}, {Symbol: "AGG", Weight: 0.3}}, []string{"assets: weights must sum to 1, got 0.9"}},
+		{"bad entries", []PortfolioAsset{{Symbol: "SPY", Weight: 1}, {Symbol: "", Weight: 0.5}, {Symbol: "SPY", Weight: -0.5}}, []string{
+			"assets[1].symbol: is required",
+			"assets[2].symbol: SPY is listed more than once",
+			"assets[2].weight: must be positive",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, fe := range (Portfolio{Assets: tc.assets}).ValidateFields() {
+				got = append(got, fe.Error())
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("ValidateFields() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -22,20 +22,27 @@ type SimulationResult struct {
 
 // ResultStats contains aggregated percentile statistics over all paths.
 type ResultStats struct {
-	P5                float64
-	P25               float64
-	P50               float64
-	P75               float64
-	P95               float64
-	Mean              float64
-	StdDev            float64
-	ProbabilityOfLoss float64
-	MedianMaxDrawdown float64
-	P95MaxDrawdown    float64
-	MedianCAGR        float64
+	P5                     float64
+	P25                    float64
+	P50                    float64
+	P75                    float64
+	P95                    float64
+	Mean                   float64
+	StdDev                 float64
+	ProbabilityOfLoss      float64
+	ProbabilityOfDepletion float64 // fraction of paths whose balance hit zero
+	MedianMaxDrawdown      float64
+	P95MaxDrawdown         float64
+	// MedianCAGR annualises the median terminal value over startValue. It is
+	// not adjusted for cash flows: contributions raise it and withdrawals
+	// lower it, so with either it is not the portfolio's rate of return.
+	MedianCAGR float64
 }
 
 // ComputeStats derives ResultStats from the completed set of simulated paths.
+// A path counts as a loss when its terminal value is below the capital put in:
+// startValue plus the path's net cash flow. MedianCAGR, unlike the loss
+// test, ignores cash flows.
 func ComputeStats(paths []SimulatedPath, startValue, horizonYears float64) ResultStats {
 	if len(paths) == 0 {
 		return ResultStats{}
@@ -43,16 +50,19 @@ func ComputeStats(paths []SimulatedPath, startValue, horizonYears float64) Resul
 	finals := make([]float64, len(paths))
 	drawdowns := make([]float64, len(paths))
 	sum := 0.0
-	losses := 0
+	losses, depleted := 0, 0
 
 	for i, p := range paths {
 		f := p.Final()
 		finals[i] = f
 		drawdowns[i] = p.MaxDrawdown()
 		sum += f
-		if f < startValue {
+		if f < startValue+p.NetCashFlow {
 			losses++
 		}
+		if p.Depleted() {
+			depleted++
+		}
 	}
 
 	sort.Float64s(finals)
@@ -75,16 +85,17 @@ func ComputeStats(paths []SimulatedPath, startValue, horizonYears float64) Resul
 	}
 
 	return ResultStats{
-		P5:                finals[int(float64(n)*0.05)],
-		P25:               finals[int(float64(n)*0.25)],
-		P50:               p50,
-		P75:               finals[int(float64(n)*0.75)],
-		P95:               finals[int(float64(n)*0.95)],
-		Mean:              mean,
-		StdDev:            math.Sqrt(variance),
-		ProbabilityOfLoss: float64(losses) / float64(n),
-		MedianMaxDrawdown: drawdowns[n/2],
-		P95MaxDrawdown:    drawdowns[int(float64(n)*0.95)],
-		MedianCAGR:        medianCAGR,
+		P5:                     finals[int(float64(n)*0.05)],
+		P25:                    finals[int(float64(n)*0.25)],
+		P50:                    p50,
+		P75:                    finals[int(float64(n)*0.75)],
+		P95:                    finals[int(float64(n)*0.95)],
+		Mean:                   mean,
+		StdDev:                 math.Sqrt(variance),
+		ProbabilityOfLoss:      float64(losses) / float64(n),
+		ProbabilityOfDepletion: float64(depleted) / float64(n),
+		MedianMaxDrawdown:      drawdowns[n/2],
+		P95MaxDrawdown:         drawdowns[int(float64(n)*0.95)],
+		MedianCAGR:             medianCAGR,
 	}
 }
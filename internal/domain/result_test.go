@@ -60,3 +60,38 @@ func TestComputeStatsCAGR(t *testing.T) {
 		t.Errorf("MedianCAGR = %v, want ~%v", stats.MedianCAGR, expectedCAGR)
 	}
 }
+
+func TestComputeStatsCAGRIgnoresCashFlows(t *testing.T) {
+	// Contributing 100k doubles the balance with no investment return; the
+	// CAGR still reports that growth.
+	paths := []SimulatedPath{{Values: []float64{100_000, 200_000}, NetCashFlow: 100_000}}
+	stats := ComputeStats(paths, 100_000, 5)
+	if want := math.Pow(2, 1.0/5) - 1; math.Abs(stats.MedianCAGR-want) > 1e-9 {
+		t.Errorf("MedianCAGR = %v, want the unadjusted %v", stats.MedianCAGR, want)
+	}
+}
+
+func TestComputeStatsLossAccountsForCashFlows(t *testing.T) {
+	// Ending at 110k after contributing 20k on a 100k start is a loss.
+	paths := []SimulatedPath{
+		{Values: []float64{100_000, 110_000}, NetCashFlow: 20_000},
+		{Values: []float64{100_000, 130_000}, NetCashFlow: 20_000},
+	}
+	stats := ComputeStats(paths, 100_000, 1)
+	if math.Abs(stats.ProbabilityOfLoss-0.5) > 1e-9 {
+		t.Errorf("ProbabilityOfLoss = %v, want 0.5", stats.ProbabilityOfLoss)
+	}
+}
+
+func TestComputeStatsDepletion(t *testing.T) {
+	paths := []SimulatedPath{
+		{Values: []float64{100, 0, 0}, NetCashFlow: -100},
+		{Values: []float64{100, 50, 20}, NetCashFlow: -80},
+		{Values: []float64{100, 90, 80}, NetCashFlow: -20},
+		{Values: []float64{100, 95, 90}, NetCashFlow: -10},
+	}
+	stats := ComputeStats(paths, 100, 1)
+	if math.Abs(stats.ProbabilityOfDepletion-0.25) > 1e-9 {
+		t.Errorf("ProbabilityOfDepletion = %v, want 0.25", stats.ProbabilityOfDepletion)
+	}
+}
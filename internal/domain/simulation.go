@@ -1,13 +1,30 @@
 package domain
 
+import "time"
+
 // SimulationModel identifies the stochastic model used to generate paths.
 type SimulationModel string
 
 // Supported simulation model identifiers.
 const (
-	ModelGBM            SimulationModel = "gbm"
-	ModelBootstrap      SimulationModel = "bootstrap"
-	ModelBlockBootstrap SimulationModel = "block_bootstrap"
+	ModelGBM             SimulationModel = "gbm"
+	ModelBootstrap       SimulationModel = "bootstrap"
+	ModelBlockBootstrap  SimulationModel = "block_bootstrap"
+	ModelStudentT        SimulationModel = "student_t"
+	ModelGARCH           SimulationModel = "garch"
+	ModelJumpDiffusion   SimulationModel = "jump_diffusion"
+	ModelRegimeSwitching SimulationModel = "regime_switching"
+	ModelHeston          SimulationModel = "heston"
+)
+
+// BlockScheme selects how the block bootstrap draws contiguous runs of historical days.
+type BlockScheme string
+
+// Supported block bootstrap schemes.
+const (
+	BlockFixed      BlockScheme = "fixed"      // moving blocks of exactly BlockLength days, no wrap-around
+	BlockCircular   BlockScheme = "circular"   // fixed-length blocks that wrap from the end of history to the start
+	BlockStationary BlockScheme = "stationary" // Politis–Romano: geometric lengths with mean BlockLength, wrapping
 )
 
 // SimulationConfig holds all parameters that define a single simulation run.
@@ -19,24 +36,138 @@ type SimulationConfig struct {
 	StartValue   float64
 	Seed         *int64 // nil means non-deterministic
 
-	// Optional cash-flow parameters
+	// AsOf calibrates the model on the LookbackDays trading days ending on
+	// this date, as if the simulation were run then. nil means the latest data.
+	AsOf *time.Time
+
+	// RepairCovariance projects a non-positive-definite covariance matrix onto
+	// the nearest PSD matrix instead of failing the run.
+	RepairCovariance bool
+
+	// Assumptions replace the moments estimated from the lookback window for
+	// models that take them. nil estimates everything from history.
+	Assumptions *CapitalMarketAssumptions
+
+	// Estimator selects how those moments are estimated from the lookback
+	// window. The zero value uses the sample mean and covariance.
+	Estimator EstimatorParams
+
+	// KeepPartial keeps the statistics and bands of the paths simulated so
+	// far when the run is cancelled; otherwise a cancelled run has no results.
+	KeepPartial bool
+
+	// Quantiles lists the per-day fan-chart bands to compute, each in (0, 1).
+	// Empty means DefaultQuantiles.
+	Quantiles []float64
+
+	// Path persistence. When StorePaths is set every path is kept with the run
+	// as a compact artifact, optionally quantised to float32 and downsampled
+	// to every PathEvery-th day (0 or 1 keeps all days).
+	StorePaths    bool
+	PathPrecision PathPrecision
+	PathEvery     int
+
+	// Per-model parameter blocks, each read only by its own model and
+	// validated with the rest of the config.
+	Block    BlockParams
+	StudentT StudentTParams
+	GARCH    GARCHParams
+	Jump     JumpParams
+	Regime   RegimeParams
+	Heston   HestonParams
+
+	// Optional cash-flow parameters. Fixed amounts are per year in today's
+	// dollars and are split evenly across the periods of CashFlowFrequency.
 	AnnualContribution float64
-	WithdrawalRate     float64
+	AnnualWithdrawal   float64
+	WithdrawalRate     float64           // fraction of the current balance withdrawn per year
+	CashFlowFrequency  CashFlowFrequency // empty means CashFlowAnnual
+	InflationRate      float64           // annual rate indexing the fixed amounts; 0 keeps them nominal
 }
 
 // Validate returns an error string if the config is invalid, or empty string if valid.
+// The string describes the first of ValidateFields' problems.
 func (c SimulationConfig) Validate() string {
+	if errs := c.ValidateFields(); len(errs) > 0 {
+		return errs[0].Error()
+	}
+	return ""
+}
+
+// ValidateFields returns every problem with the config. Fields are named by
+// their snake_case config names, e.g. "num_paths".
+func (c SimulationConfig) ValidateFields() FieldErrors {
+	var errs FieldErrors
+	bad := func(field, msg string) { errs = append(errs, FieldError{Field: field, Message: msg}) }
 	if c.NumPaths <= 0 {
-		return "num_paths must be positive"
+		bad("num_paths", "must be positive")
 	}
 	if c.HorizonDays <= 0 {
-		return "horizon_days must be positive"
+		bad("horizon_days", "must be positive")
 	}
 	if c.LookbackDays <= 0 {
-		return "lookback_days must be positive"
+		bad("lookback_days", "must be positive")
 	}
 	if c.StartValue <= 0 {
-		return "start_value must be positive"
+		bad("start_value", "must be positive")
 	}
-	return ""
+	for _, q := range c.Quantiles {
+		if q <= 0 || q >= 1 {
+			bad("quantiles", "must be between 0 and 1 (exclusive)")
+			break
+		}
+	}
+	if c.PathEvery < 0 {
+		bad("path_every", "must not be negative")
+	}
+	switch c.PathPrecision {
+	case "", PrecisionFloat64, PrecisionFloat32:
+	default:
+		bad("path_precision", "must be float64 or float32")
+	}
+	if c.AnnualWithdrawal < 0 {
+		bad("annual_withdrawal", "must not be negative")
+	}
+	if c.WithdrawalRate < 0 || c.WithdrawalRate > 1 {
+		bad("withdrawal_rate", "must be between 0 and 1")
+	}
+	if c.InflationRate <= -1 {
+		bad("inflation_rate", "must be greater than -1")
+	}
+	switch c.CashFlowFrequency {
+	case "", CashFlowAnnual, CashFlowMonthly:
+	default:
+		bad("cash_flow_frequency", "must be monthly or annual")
+	}
+	errs = append(errs, c.Block.Validate()...)
+	errs = append(errs, c.StudentT.Validate()...)
+	errs = append(errs, c.GARCH.Validate()...)
+	errs = append(errs, c.Jump.Validate()...)
+	errs = append(errs, c.Regime.Validate()...)
+	errs = append(errs, c.Heston.Validate()...)
+	errs = append(errs, c.Estimator.Validate()...)
+	if c.Assumptions != nil {
+		errs = append(errs, c.Assumptions.ValidateFields().Prefix("assumptions")...)
+	}
+	return errs
+}
+
+// ValidateAssumptions returns a problem for each asset of p that the config's
+// capital market assumptions, if any, leave out. Fields are named as by
+// ValidateFields.
+func (c SimulationConfig) ValidateAssumptions(p Portfolio) FieldErrors {
+	if c.Assumptions == nil {
+		return nil
+	}
+	have := map[string]bool{}
+	for _, a := range c.Assumptions.Assets {
+		have[a.Symbol] = true
+	}
+	var errs FieldErrors
+	for _, pa := range p.Assets {
+		if pa.Symbol != "" && !have[pa.Symbol] {
+			errs = append(errs, FieldError{Field: "assumptions.assets", Message: "has no assumption for " + pa.Symbol})
+		}
+	}
+	return errs
 }
@@ -24,6 +24,32 @@ func TestSimulationConfigValidate(t *testing.T) {
 		{"zero lookback_days", func(c SimulationConfig) SimulationConfig { c.LookbackDays = 0; return c }, true},
 		{"zero start_value", func(c SimulationConfig) SimulationConfig { c.StartValue = 0; return c }, true},
 		{"negative start_value", func(c SimulationConfig) SimulationConfig { c.StartValue = -1; return c }, true},
+		{"negative annual_withdrawal", func(c SimulationConfig) SimulationConfig { c.AnnualWithdrawal = -1; return c }, true},
+		{"withdrawal_rate above one", func(c SimulationConfig) SimulationConfig { c.WithdrawalRate = 1.5; return c }, true},
+		{"inflation_rate at minus one", func(c SimulationConfig) SimulationConfig { c.InflationRate = -1; return c }, true},
+		{"monthly cash flows", func(c SimulationConfig) SimulationConfig { c.CashFlowFrequency = CashFlowMonthly; return c }, false},
+		{"unknown cash flow frequency", func(c SimulationConfig) SimulationConfig { c.CashFlowFrequency = "weekly"; return c }, true},
+		{"negative block_length", func(c SimulationConfig) SimulationConfig { c.Block.Length = -1; return c }, true},
+		{"stationary block scheme", func(c SimulationConfig) SimulationConfig { c.Block.Scheme = BlockStationary; return c }, false},
+		{"custom quantiles", func(c SimulationConfig) SimulationConfig { c.Quantiles = []float64{0.1, 0.5, 0.9}; return c }, false},
+		{"quantile of one", func(c SimulationConfig) SimulationConfig { c.Quantiles = []float64{0.5, 1}; return c }, true},
+		{"unknown block scheme", func(c SimulationConfig) SimulationConfig { c.Block.Scheme = "overlapping"; return c }, true},
+		{"configured jumps", func(c SimulationConfig) SimulationConfig {
+			c.Jump.Intensity, c.Jump.Mean, c.Jump.Vol = 0.5, -0.1, 0.05
+			return c
+		}, false},
+		{"negative jump_intensity", func(c SimulationConfig) SimulationConfig { c.Jump.Intensity = -1; return c }, true},
+		{"negative jump_vol", func(c SimulationConfig) SimulationConfig { c.Jump.Intensity, c.Jump.Vol = 1, -0.1; return c }, true},
+		{"jump_mean without intensity", func(c SimulationConfig) SimulationConfig { c.Jump.Mean = -0.1; return c }, true},
+		{"three regimes", func(c SimulationConfig) SimulationConfig { c.Regime.States = 3; return c }, false},
+		{"four regimes", func(c SimulationConfig) SimulationConfig { c.Regime.States = 4; return c }, true},
+		{"configured heston", func(c SimulationConfig) SimulationConfig {
+			c.Heston.Kappa, c.Heston.Theta, c.Heston.Xi, c.Heston.Rho = 2, 0.04, 0.3, -0.7
+			return c
+		}, false},
+		{"heston_rho out of range", func(c SimulationConfig) SimulationConfig { c.Heston.Kappa, c.Heston.Rho = 2, -1.5; return c }, true},
+		{"negative heston_xi", func(c SimulationConfig) SimulationConfig { c.Heston.Kappa, c.Heston.Xi = 2, -0.1; return c }, true},
+		{"heston_xi without kappa", func(c SimulationConfig) SimulationConfig { c.Heston.Xi = 0.3; return c }, true},
 	}
 
 	for _, tc := range tests {
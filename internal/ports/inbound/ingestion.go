@@ -5,6 +5,7 @@ package inbound
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/gjcourt/drift/internal/domain"
 )
@@ -12,7 +13,17 @@ import (
 // DataIngestionService is the inbound port for loading historical price data.
 type DataIngestionService interface {
 	IngestCSV(ctx context.Context, r io.Reader, filename string) (int, error)
+	// IngestRecords stores already-parsed price records, creating an asset for
+	// any symbol not seen before, and returns how many records it stored.
+	IngestRecords(ctx context.Context, records []domain.PriceRecord) (int, error)
 	ListAssets(ctx context.Context) ([]domain.Asset, error)
+	GetAsset(ctx context.Context, symbol string) (*domain.Asset, error)
 	GetAssetPrices(ctx context.Context, symbol string, limit int) ([]domain.PriceRecord, error)
+	// GetAssetPricesBetween returns records with from <= date <= to in
+	// ascending date order; a zero from or to leaves that end open.
+	GetAssetPricesBetween(ctx context.Context, symbol string, from, to time.Time) ([]domain.PriceRecord, error)
 	DeleteAsset(ctx context.Context, symbol string) error
+	// ParseAssumptions reads capital market assumptions from CSV. Input that
+	// fails to parse or validate returns an error wrapping domain.ErrInvalid.
+	ParseAssumptions(r io.Reader) (*domain.CapitalMarketAssumptions, error)
 }
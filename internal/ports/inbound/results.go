@@ -2,15 +2,31 @@ package inbound
 
 import (
 	"context"
+	"io"
 
 	"github.com/gjcourt/drift/internal/domain"
 )
 
 // ResultsService is the inbound port for querying simulation results and experiments.
 type ResultsService interface {
+	// CreateExperiment saves a new experiment. One that fails validation is
+	// rejected with a domain.FieldErrors, which wraps domain.ErrInvalid.
 	CreateExperiment(ctx context.Context, exp domain.Experiment) (*domain.Experiment, error)
+	// ImportExperiment creates an experiment from a JSON experiment config.
+	// Configs that fail to parse or validate return an error wrapping
+	// domain.ErrInvalid.
+	ImportExperiment(ctx context.Context, r io.Reader) (*domain.Experiment, error)
+	// ReplaceExperiment overwrites the experiment's definition with a JSON
+	// experiment config, keeping its ID and creation time.
+	ReplaceExperiment(ctx context.Context, id string, r io.Reader) (*domain.Experiment, error)
+	// EncodeExperiment renders exp as a JSON experiment config, the format
+	// ImportExperiment reads.
+	EncodeExperiment(exp domain.Experiment) ([]byte, error)
 	GetExperiment(ctx context.Context, id string) (*domain.Experiment, error)
 	ListExperiments(ctx context.Context) ([]domain.Experiment, error)
+	// DeleteExperiment removes an experiment and its runs. It returns an error
+	// wrapping domain.ErrRunActive while any of its runs is queued or running.
+	DeleteExperiment(ctx context.Context, id string) error
 	ListRuns(ctx context.Context, experimentID string) ([]domain.Run, error)
 	GetRunStats(ctx context.Context, runID string) (*domain.ResultStats, error)
 }
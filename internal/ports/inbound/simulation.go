@@ -8,7 +8,25 @@ import (
 
 // SimulationService is the inbound port for running Monte Carlo simulations.
 type SimulationService interface {
-	RunExperiment(ctx context.Context, experimentID string) (*domain.Run, error)
+	// EnqueueRun queues a run of the experiment and returns it immediately;
+	// the simulation itself executes in the background. An experiment with a
+	// run already queued or running gets that run back and nothing is queued,
+	// so a re-submitted request does not execute the experiment twice.
+	EnqueueRun(ctx context.Context, experimentID string) (*domain.Run, error)
 	GetRun(ctx context.Context, runID string) (*domain.Run, error)
-	GetRunPaths(ctx context.Context, runID string) ([]domain.SimulatedPath, error)
+	// CancelRun stops a queued or running run. It returns an error wrapping
+	// domain.ErrRunNotActive if the run has already finished.
+	CancelRun(ctx context.Context, runID string) error
+	// DeleteRun removes a finished run and its results. It returns an error
+	// wrapping domain.ErrRunActive if the run is still queued or running.
+	DeleteRun(ctx context.Context, runID string) error
+	// WatchRun streams the run's progress: its current state first, then each
+	// update until the run finishes or ctx is done, when the channel closes.
+	WatchRun(ctx context.Context, runID string) (<-chan domain.RunProgress, error)
+	// GetRunPaths returns up to limit persisted paths of a run starting at
+	// path index offset; limit <= 0 means all remaining paths.
+	GetRunPaths(ctx context.Context, runID string, offset, limit int) (*domain.PathSet, error)
+	// ListModels describes the simulation models that can be run, in the
+	// order clients should offer them.
+	ListModels() []domain.ModelSpec
 }
@@ -6,7 +6,11 @@ import (
 	"github.com/gjcourt/drift/internal/domain"
 )
 
-// CSVParser parses a CSV price-data stream into domain price records.
+// CSVParser parses CSV streams into domain objects.
 type CSVParser interface {
+	// ParseCSV parses price data into price records.
 	ParseCSV(r io.Reader, filename string) ([]domain.PriceRecord, error)
+	// ParseAssumptionsCSV parses capital market assumptions without
+	// validating them.
+	ParseAssumptionsCSV(r io.Reader) (domain.CapitalMarketAssumptions, error)
 }
@@ -0,0 +1,14 @@
+package outbound
+
+import (
+	"io"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// ExperimentCodec converts experiments to and from the versioned JSON
+// experiment config format.
+type ExperimentCodec interface {
+	ParseExperimentJSON(r io.Reader) (domain.Experiment, error)
+	EncodeExperimentJSON(exp domain.Experiment) ([]byte, error)
+}
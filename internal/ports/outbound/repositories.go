@@ -1,9 +1,11 @@
 // Package outbound defines the repository and infrastructure interfaces used by the app layer.
-// Storage adapters (e.g. SQLite) implement these interfaces.
+// Storage adapters (e.g. SQLite) implement these interfaces. Lookups of a
+// single record that does not exist return an error wrapping domain.ErrNotFound.
 package outbound
 
 import (
 	"context"
+	"time"
 
 	"github.com/gjcourt/drift/internal/domain"
 )
@@ -16,6 +18,12 @@ type AssetRepository interface {
 	DeleteAsset(ctx context.Context, symbol string) error
 	UpsertPriceRecords(ctx context.Context, records []domain.PriceRecord) error
 	GetPriceRecords(ctx context.Context, symbol string, limit int) ([]domain.PriceRecord, error)
+	// GetPriceRecordsBetween returns records with from <= date <= to in ascending
+	// date order. A zero from or to leaves that end of the range open.
+	GetPriceRecordsBetween(ctx context.Context, symbol string, from, to time.Time) ([]domain.PriceRecord, error)
+	// GetRecentPriceRecords returns the last n records on or before asOf, in
+	// ascending date order. A zero asOf means the latest available record.
+	GetRecentPriceRecords(ctx context.Context, symbol string, n int, asOf time.Time) ([]domain.PriceRecord, error)
 }
 
 // ExperimentRepository is the outbound port for persisting experiment configurations.
@@ -31,4 +39,34 @@ type SimulationRepository interface {
 	SaveRun(ctx context.Context, run domain.Run) error
 	GetRun(ctx context.Context, runID string) (*domain.Run, error)
 	ListRuns(ctx context.Context, experimentID string) ([]domain.Run, error)
+	// UpdateRunProgress records how many paths a running run has simulated
+	// and reports whether RequestCancel has asked it to stop.
+	UpdateRunProgress(ctx context.Context, runID string, done int) (cancelRequested bool, err error)
+	// RequestCancel asks the process executing a running run to stop it; the
+	// executor learns of it on its next UpdateRunProgress. It reports whether
+	// the run was running.
+	RequestCancel(ctx context.Context, runID string) (bool, error)
+	// ClaimNextRun atomically moves the oldest queued run to running with
+	// StartedAt set to claimedAt and returns it, or nil when nothing is queued.
+	ClaimNextRun(ctx context.Context, claimedAt time.Time) (*domain.Run, error)
+	// ClaimRun atomically moves runID to running with StartedAt set to
+	// claimedAt and returns it, or nil when the run is no longer queued.
+	ClaimRun(ctx context.Context, runID string, claimedAt time.Time) (*domain.Run, error)
+	// CancelQueuedRun marks the run cancelled if it is still queued and
+	// reports whether it did; a run already claimed is left untouched.
+	CancelQueuedRun(ctx context.Context, runID string, finishedAt time.Time) (bool, error)
+	// ReapRunning marks every run still in the running state as failed with
+	// the given error and returns how many rows it touched.
+	ReapRunning(ctx context.Context, finishedAt time.Time, reason string) (int, error)
+	// DeleteRun removes a run together with its bands and stored paths.
+	DeleteRun(ctx context.Context, runID string) error
+}
+
+// PathRepository is the outbound port for persisting the simulated paths of a
+// run as a single artifact and reading them back by index range.
+type PathRepository interface {
+	SavePaths(ctx context.Context, set domain.PathSet) error
+	// GetPaths returns up to limit paths starting at offset; limit <= 0 means
+	// all remaining paths.
+	GetPaths(ctx context.Context, runID string, offset, limit int) (*domain.PathSet, error)
 }
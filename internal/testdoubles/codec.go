@@ -0,0 +1,24 @@
+package testdoubles
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// ExperimentCodec is an outbound.ExperimentCodec that reads and writes
+// domain.Experiment as plain JSON, standing in for the versioned config format.
+type ExperimentCodec struct{}
+
+// ParseExperimentJSON decodes a JSON-encoded domain.Experiment.
+func (ExperimentCodec) ParseExperimentJSON(r io.Reader) (domain.Experiment, error) {
+	var exp domain.Experiment
+	err := json.NewDecoder(r).Decode(&exp)
+	return exp, err
+}
+
+// EncodeExperimentJSON encodes exp as JSON.
+func (ExperimentCodec) EncodeExperimentJSON(exp domain.Experiment) ([]byte, error) {
+	return json.Marshal(exp)
+}
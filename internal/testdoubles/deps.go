@@ -1,14 +1,44 @@
 package testdoubles
 
+import (
+	"github.com/gjcourt/drift/internal/adapters/storage/memory"
+	"github.com/gjcourt/drift/internal/ports/outbound"
+)
+
 // ServerDeps aggregates all outbound-port fakes for unit tests.
 // Add one field per outbound port as migration progresses.
 // Current outbound ports are in internal/ports/outbound/:
 //   - outbound.AssetRepository
 //   - outbound.ExperimentRepository
 //   - outbound.SimulationRepository
-type ServerDeps struct{}
+//   - outbound.PathRepository
+//   - outbound.ExperimentCodec
+//
+// The repository fields share one memory.Store, so a run saved through Runs
+// is gone once its experiment is deleted through Experiments, as in SQLite.
+type ServerDeps struct {
+	Assets      *memory.Store
+	Experiments *Experiments
+	Runs        *memory.Store
+	Paths       *memory.Store
+	Codec       ExperimentCodec
+}
 
-// NewServerDeps returns a ServerDeps with all fakes initialised to safe zero-value defaults.
+// NewServerDeps returns a ServerDeps over an empty in-memory store.
 func NewServerDeps() *ServerDeps {
-	return &ServerDeps{}
+	store := memory.New()
+	return &ServerDeps{
+		Assets:      store,
+		Experiments: &Experiments{Store: store},
+		Runs:        store,
+		Paths:       store,
+	}
 }
+
+var (
+	_ outbound.AssetRepository      = (*memory.Store)(nil)
+	_ outbound.ExperimentRepository = (*Experiments)(nil)
+	_ outbound.SimulationRepository = (*memory.Store)(nil)
+	_ outbound.PathRepository       = (*memory.Store)(nil)
+	_ outbound.ExperimentCodec      = ExperimentCodec{}
+)
@@ -0,0 +1,25 @@
+package testdoubles
+
+import (
+	"context"
+
+	"github.com/gjcourt/drift/internal/adapters/storage/memory"
+	"github.com/gjcourt/drift/internal/domain"
+)
+
+// Experiments is the shared in-memory store seen through its
+// outbound.ExperimentRepository, with a hook for failing lookups.
+type Experiments struct {
+	*memory.Store
+
+	// Err, when set, is returned by GetExperiment.
+	Err error
+}
+
+// GetExperiment returns Err if set, and the stored experiment otherwise.
+func (r *Experiments) GetExperiment(ctx context.Context, id string) (*domain.Experiment, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Store.GetExperiment(ctx, id)
+}
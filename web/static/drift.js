@@ -1,10 +1,19 @@
 (function () {
   'use strict';
 
-  // Render fan chart if stats are present on the results page.
+  const money = (v) => '$' + v.toLocaleString(undefined, {maximumFractionDigits: 0});
+  const pct = (q) => 'p' + Math.round(q * 1000) / 10;
+
+  // Render the per-day fan chart when the run recorded bands; older runs only
+  // have terminal percentiles and fall back to a bar chart of those.
   function renderFanChart() {
     const canvas = document.getElementById('fanChart');
-    if (!canvas || !window.DRIFT_STATS) return;
+    if (!canvas) return;
+    if (window.DRIFT_BANDS && window.DRIFT_BANDS.Days) {
+      renderBands(canvas, window.DRIFT_BANDS);
+      return;
+    }
+    if (!window.DRIFT_STATS) return;
 
     const stats = window.DRIFT_STATS;
     // Build a simple bar chart of percentile terminal values as a proxy
@@ -56,5 +65,138 @@
     });
   }
 
+  // renderBands draws the sample paths as faint spaghetti lines, then each
+  // quantile as a line filled down to the one below it. Fill opacity peaks in
+  // the central band so the chart reads as a fan around the median.
+  function renderBands(canvas, bands) {
+    const years = bands.Days.map((d) => d / 252);
+    const points = (vals) => vals.map((y, j) => ({x: years[j], y: y}));
+    const datasets = [];
+
+    (bands.Sample || []).forEach((vals) => {
+      datasets.push({
+        data: points(vals),
+        borderColor: 'rgba(148,163,184,0.25)',
+        borderWidth: 1,
+        pointRadius: 0,
+        fill: false,
+        spaghetti: true,
+      });
+    });
+
+    const first = datasets.length;
+    bands.Quantiles.forEach((q, i) => {
+      const ds = {
+        label: pct(q),
+        data: points(bands.Values[i]),
+        borderColor: 'rgba(99,102,241,' + (Math.abs(q - 0.5) < 1e-9 ? '1)' : '0.6)'),
+        borderWidth: Math.abs(q - 0.5) < 1e-9 ? 2 : 1,
+        pointRadius: 0,
+        fill: false,
+      };
+      if (i > 0) {
+        const mid = (bands.Quantiles[i - 1] + q) / 2;
+        const alpha = 0.08 + 0.32 * (1 - 2 * Math.abs(mid - 0.5));
+        ds.fill = {target: first + i - 1};
+        ds.backgroundColor = 'rgba(99,102,241,' + alpha.toFixed(2) + ')';
+      }
+      datasets.push(ds);
+    });
+
+    new Chart(canvas, {
+      type: 'line',
+      data: {datasets: datasets},
+      options: {
+        responsive: true,
+        maintainAspectRatio: false,
+        animation: false,
+        interaction: {mode: 'index', intersect: false},
+        plugins: {
+          legend: {
+            labels: {color: '#64748b', filter: (item, data) => !data.datasets[item.datasetIndex].spaghetti},
+          },
+          tooltip: {
+            filter: (item) => !item.dataset.spaghetti,
+            callbacks: {
+              title: (items) => items.length ? 'Year ' + items[0].parsed.x.toFixed(1) : '',
+              label: (ctx) => ctx.dataset.label + ': ' + money(ctx.parsed.y),
+            },
+          },
+        },
+        scales: {
+          x: {
+            type: 'linear',
+            title: {display: true, text: 'Years', color: '#64748b'},
+            ticks: {color: '#64748b'},
+            grid: {display: false},
+          },
+          y: {
+            ticks: {color: '#64748b', callback: money},
+            grid: {color: '#2a2d3a'},
+          },
+        },
+      },
+    });
+  }
+
+  // watchRun follows an in-flight run over its event stream, updating the
+  // progress bar and ETA, and reloads the page once the run finishes. Without
+  // EventSource, or when the stream cannot be opened, it falls back to polling.
+  function watchRun() {
+    const el = document.getElementById('runProgress');
+    if (!el) return;
+    const poll = () => setTimeout(() => location.reload(), 2000);
+    if (!window.EventSource) {
+      poll();
+      return;
+    }
+    const es = new EventSource('/runs/' + encodeURIComponent(el.dataset.runId) + '/events');
+    const finish = () => {
+      es.close();
+      location.reload();
+    };
+    es.addEventListener('progress', (e) => showProgress(el, JSON.parse(e.data)));
+    es.addEventListener('complete', finish);
+    es.addEventListener('failed', finish);
+    es.addEventListener('cancelled', finish);
+    es.onerror = () => {
+      if (es.readyState === EventSource.CLOSED) poll();
+    };
+  }
+
+  function showProgress(el, p) {
+    const bar = el.querySelector('progress');
+    bar.max = p.total;
+    bar.value = p.done;
+    const status = el.querySelector('[data-field=status]');
+    status.textContent = p.status;
+    status.className = 'status-' + p.status;
+    el.querySelector('[data-field=count]').textContent =
+      p.done.toLocaleString() + ' / ' + p.total.toLocaleString() + ' paths';
+    let eta = '';
+    if (p.done > 0 && p.done < p.total) {
+      eta = '— about ' + duration(p.elapsed_ms * (p.total - p.done) / p.done) + ' remaining';
+    }
+    el.querySelector('[data-field=eta]').textContent = eta;
+  }
+
+  function duration(ms) {
+    const s = Math.max(1, Math.round(ms / 1000));
+    if (s < 60) return s + 's';
+    const m = Math.floor(s / 60);
+    if (m < 60) return m + 'm ' + (s % 60) + 's';
+    return Math.floor(m / 60) + 'h ' + (m % 60) + 'm';
+  }
+
+  // A 422 carries a fragment explaining what was wrong with the submitted
+  // input; htmx does not swap error responses unless told to.
+  document.addEventListener('htmx:beforeSwap', (e) => {
+    if (e.detail.xhr.status === 422) {
+      e.detail.shouldSwap = true;
+      e.detail.isError = false;
+    }
+  });
+
   document.addEventListener('DOMContentLoaded', renderFanChart);
+  document.addEventListener('DOMContentLoaded', watchRun);
 })();